@@ -22,13 +22,13 @@ var (
 	}
 	BuildCommand = Command{
 		Name:        "build",
-		Args:        []string{"build", "<app-name>"},
-		Description: "Build a Velo project",
+		Args:        []string{"build", "--platform", "<android|ios>", "--env", "<environment>", "--release", "--format", "<apk|aab>", "--dev-server"},
+		Description: "Build a Velo project example: velo build --platform android --env staging --release --format aab",
 	}
 	DevCommand = Command{
 		Name:        "dev",
-		Args:        []string{"dev", "<app-name>"},
-		Description: "Run a Velo project in development mode",
+		Args:        []string{"dev", "--platform", "<android|ios|web>", "--device", "<device-id>", "--env", "<environment>", "--port", "<port>", "--host", "<host>", "--timeout", "<duration>", "--verbose", "--bundled", "--lan", "--https", "--strict-port", "--log-level", "<level>"},
+		Description: "Run the dev server and the debug shell on devices example: velo dev --platform android --device emulator-5554",
 	}
 	HelpCommand = Command{
 		Name:        "help",
@@ -40,26 +40,61 @@ var (
 		Args:        []string{"doctor"},
 		Description: "Check the health of a Velo project",
 	}
+	AndroidCommand = Command{
+		Name:        "android",
+		Args:        []string{"android", "<project|keystore|sign|verify>"},
+		Description: "Android tooling example: velo android sign --in app.apk --keystore release.keystore",
+	}
+	IOSCommand = Command{
+		Name:        "ios",
+		Args:        []string{"ios", "<project|profiles>"},
+		Description: "iOS tooling example: velo ios profiles inspect app.mobileprovision",
+	}
+	AssetsCommand = Command{
+		Name:        "assets",
+		Args:        []string{"assets", "<icons|splash>"},
+		Description: "Generate app icons and splash screens for every platform example: velo assets icons --source icon.png",
+	}
+	InspectCommand = Command{
+		Name:        "inspect",
+		Args:        []string{"inspect", "<apk|aab>"},
+		Description: "Show the manifest, signatures and size breakdown of an APK or app bundle example: velo inspect app-release.apk",
+	}
 	VersionCommand = Command{
 		Name:        "version",
-		Args:        []string{"-v", "--version"},
-		Description: "Show the version of a Velo project",
+		Args:        []string{"version", "[bump <major|minor|patch|build>]"},
+		Description: "Show the Velo version or bump the app version on every platform example: velo version bump minor --tag",
+	}
+	LogsCommand = Command{
+		Name:        "logs",
+		Args:        []string{"logs", "android", "--device", "<device-id>", "--env", "<environment>", "--level", "<level>", "--json"},
+		Description: "Stream the log of the app from a device example: velo logs android --level warn",
 	}
 )
 
+// commandAliases maps the comma separated names of each command to it
+var commandAliases = map[string]Command{
+	"init, -i, --init":       InitCommand,
+	"show,  --show":          ShowCommand,
+	"build,  --build":        BuildCommand,
+	"dev,  --dev":            DevCommand,
+	"help, -h, --help":       HelpCommand,
+	"doctor, --doctor":       DoctorCommand,
+	"android, --android":     AndroidCommand,
+	"ios, --ios":             IOSCommand,
+	"assets":                 AssetsCommand,
+	"inspect":                InspectCommand,
+	"version, -v, --version": VersionCommand,
+	"logs":                   LogsCommand,
+}
+
+// GetCommand returns the command one of whose names is exactly name
 func GetCommand(name string) Command {
-	commands := map[string]Command{
-		"init, -i, --init":       InitCommand,
-		"show,  --show":          ShowCommand,
-		"build,  --build":        BuildCommand,
-		"dev,  --dev":            DevCommand,
-		"help, -h, --help":       HelpCommand,
-		"doctor, --doctor":       DoctorCommand,
-		"version, -v, --version": VersionCommand,
-	}
-	for key, command := range commands {
-		if strings.Contains(key, name) {
-			return command
+	for key, command := range commandAliases {
+		for _, alias := range strings.Split(key, ",") {
+			if strings.TrimSpace(alias) == name {
+				return command
+			}
 		}
 	}
 	return Command{}
@@ -73,7 +108,12 @@ func AllCommands() []Command {
 		DevCommand,
 		HelpCommand,
 		DoctorCommand,
+		AndroidCommand,
+		IOSCommand,
+		AssetsCommand,
+		InspectCommand,
 		VersionCommand,
+		LogsCommand,
 	}
 }
 
@@ -89,5 +129,10 @@ var Commands = []Command{
 	DevCommand,
 	HelpCommand,
 	DoctorCommand,
+	AndroidCommand,
+	IOSCommand,
+	AssetsCommand,
+	InspectCommand,
 	VersionCommand,
+	LogsCommand,
 }
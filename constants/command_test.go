@@ -0,0 +1,42 @@
+package constants
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetCommandAliasesAreUnique(t *testing.T) {
+	for key, want := range commandAliases {
+		for _, alias := range strings.Split(key, ",") {
+			alias = strings.TrimSpace(alias)
+			if alias == "" {
+				continue
+			}
+			var matches []string
+			for other, command := range commandAliases {
+				for _, name := range strings.Split(other, ",") {
+					if strings.TrimSpace(name) == alias {
+						matches = append(matches, command.Name)
+					}
+				}
+			}
+			if len(matches) != 1 {
+				t.Errorf("alias %q names %d commands: %v", alias, len(matches), matches)
+			}
+			// Repeated lookups guard against the map iteration order
+			for i := 0; i < 20; i++ {
+				if got := GetCommand(alias); got.Name != want.Name {
+					t.Fatalf("GetCommand(%q) = %q, want %q", alias, got.Name, want.Name)
+				}
+			}
+		}
+	}
+}
+
+func TestGetCommandMatchesWholeNames(t *testing.T) {
+	for _, name := range []string{"-", "i", "os", "--", "in", "ver", ""} {
+		if got := GetCommand(name); got.Name != "" {
+			t.Errorf("GetCommand(%q) = %q, want no command", name, got.Name)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package apksign
+
+import (
+	"encoding/binary"
+	"path"
+	"strings"
+)
+
+const (
+	// DefaultAlignment is the alignment of uncompressed entries required by
+	// Android to mmap resources directly from the APK
+	DefaultAlignment = 4
+	// PageAlignment is used for uncompressed native libraries so they can be
+	// loaded without extraction
+	PageAlignment = 4096
+
+	// alignmentExtraID is the extra field used by apksigner to record alignment
+	alignmentExtraID  = 0xd935
+	alignmentExtraLen = 6
+)
+
+// Align rewrites the APK so the data of every uncompressed entry starts at a
+// multiple of alignment bytes, like zipalign -p. Any existing signature is
+// dropped, so alignment must happen before signing.
+func Align(apk []byte, alignment int) ([]byte, error) {
+	a, err := readArchive(apk)
+	if err != nil {
+		return nil, err
+	}
+	return a.bytes(aligner(alignment)), nil
+}
+
+// aligner returns the alignment function used when writing an archive
+func aligner(alignment int) func(e *entry) int {
+	return func(e *entry) int {
+		if e.method != 0 {
+			return 0
+		}
+		if strings.HasPrefix(e.name, "lib/") && path.Ext(e.name) == ".so" {
+			return PageAlignment
+		}
+		return alignment
+	}
+}
+
+// stripAlignmentExtra removes a previous alignment field from a local extra
+// field. Extra data that cannot be parsed, such as zipalign zero padding, is
+// dropped as well since its only purpose is alignment.
+func stripAlignmentExtra(extra []byte) []byte {
+	var out []byte
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		if 4+size > len(extra) {
+			break
+		}
+		if id != alignmentExtraID && id != 0 {
+			out = append(out, extra[:4+size]...)
+		}
+		extra = extra[4+size:]
+	}
+	return out
+}
+
+// alignmentExtra appends an alignment field to extra so that the entry data,
+// which starts right after the extra field at dataOffset+len(result), lands
+// on a multiple of alignment
+func alignmentExtra(extra []byte, dataOffset, alignment int) []byte {
+	offset := dataOffset + len(extra) + alignmentExtraLen
+	padding := (alignment - offset%alignment) % alignment
+
+	field := make([]byte, alignmentExtraLen+padding)
+	binary.LittleEndian.PutUint16(field[0:], alignmentExtraID)
+	binary.LittleEndian.PutUint16(field[2:], uint16(2+padding))
+	binary.LittleEndian.PutUint16(field[4:], uint16(alignment))
+	return append(append([]byte(nil), extra...), field...)
+}
@@ -0,0 +1,320 @@
+// Package apksign aligns and signs Android APKs without the Android build-tools.
+//
+// It implements zipalign, JAR signing (v1) and the APK Signature Schemes v2
+// and v3, and can verify all three.
+package apksign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Signer is the key and certificate chain used to sign an APK
+type Signer struct {
+	PrivateKey   crypto.Signer
+	Certificates []*x509.Certificate
+}
+
+// Options selects the signature schemes to apply
+type Options struct {
+	// MinSDK is the minSdkVersion of the APK. JAR signing is only needed
+	// below Android 7.0 (API 24) and is skipped otherwise unless V1 is forced.
+	MinSDK int
+	V1     bool
+	V2     bool
+	V3     bool
+}
+
+// DefaultOptions returns the schemes apksigner applies for an APK with the
+// given minSdkVersion
+func DefaultOptions(minSDK int) Options {
+	return Options{
+		MinSDK: minSDK,
+		V1:     minSDK < 24,
+		V2:     true,
+		V3:     true,
+	}
+}
+
+// Sign aligns the APK and signs it with the selected schemes. Existing
+// signatures are replaced.
+func Sign(apk []byte, signer *Signer, opts Options) ([]byte, error) {
+	if signer == nil || signer.PrivateKey == nil || len(signer.Certificates) == 0 {
+		return nil, errors.New("a private key and certificate are required to sign")
+	}
+	if !opts.V1 && !opts.V2 && !opts.V3 {
+		return nil, errors.New("no signature scheme selected")
+	}
+
+	a, err := readArchive(apk)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.V1 {
+		var schemes string
+		switch {
+		case opts.V2 && opts.V3:
+			schemes = "2, 3"
+		case opts.V2:
+			schemes = "2"
+		case opts.V3:
+			schemes = "3"
+		}
+		if err := signV1(a, signer, schemes); err != nil {
+			return nil, err
+		}
+	} else {
+		a.remove(isSignatureFile)
+	}
+
+	unsigned := a.bytes(aligner(DefaultAlignment))
+	if !opts.V2 && !opts.V3 {
+		return unsigned, nil
+	}
+
+	layout, err := findLayout(unsigned)
+	if err != nil {
+		return nil, err
+	}
+	alg, err := algorithmFor(signer.PrivateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	digest := contentDigest(alg.hash,
+		unsigned[:layout.cdOffset],
+		unsigned[layout.cdOffset:layout.eocdOffset],
+		unsigned[layout.eocdOffset:],
+	)
+
+	pairs := map[uint32][]byte{}
+	if opts.V2 {
+		if pairs[v2BlockID], err = signerBlock(2, signer, alg, digest, opts.MinSDK, opts.V3); err != nil {
+			return nil, err
+		}
+	}
+	if opts.V3 {
+		if pairs[v3BlockID], err = signerBlock(3, signer, alg, digest, max(opts.MinSDK, minSDKForV3), false); err != nil {
+			return nil, err
+		}
+	}
+	block := signingBlock(pairs, v2BlockID, v3BlockID)
+
+	// Insert the signing block before the central directory and move the
+	// central directory offset in the EOCD record accordingly
+	out := make([]byte, 0, len(unsigned)+len(block))
+	out = append(out, unsigned[:layout.cdOffset]...)
+	out = append(out, block...)
+	out = append(out, unsigned[layout.cdOffset:]...)
+	eocd := int(layout.eocdOffset) + len(block)
+	binary.LittleEndian.PutUint32(out[eocd+16:], uint32(layout.cdOffset)+uint32(len(block)))
+	return out, nil
+}
+
+// SignFile signs the APK at in and writes the result to out
+func SignFile(in, out string, signer *Signer, opts Options) error {
+	apk, err := os.ReadFile(in)
+	if err != nil {
+		return err
+	}
+	signed, err := Sign(apk, signer, opts)
+	if err != nil {
+		return fmt.Errorf("failed to sign %s: %w", in, err)
+	}
+	return os.WriteFile(out, signed, 0644)
+}
+
+// SchemeResult is the verification outcome of one signature scheme
+type SchemeResult struct {
+	Present      bool
+	Verified     bool
+	Error        error
+	Certificates []*x509.Certificate
+}
+
+// Result holds the verification results of all schemes
+type Result struct {
+	V1 SchemeResult
+	V2 SchemeResult
+	V3 SchemeResult
+}
+
+// Verified reports whether at least one scheme is present and none failed
+func (r *Result) Verified() bool {
+	signed := false
+	for _, s := range []SchemeResult{r.V1, r.V2, r.V3} {
+		if s.Present && !s.Verified {
+			return false
+		}
+		signed = signed || s.Verified
+	}
+	return signed
+}
+
+// Verify checks every signature scheme present in the APK
+func Verify(apk []byte) (*Result, error) {
+	a, err := readArchive(apk)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := findLayout(apk)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{}
+
+	if cert, err := verifyV1(a); cert != nil || err != nil {
+		result.V1 = SchemeResult{Present: true, Verified: err == nil, Error: err}
+		if cert != nil {
+			result.V1.Certificates = []*x509.Certificate{cert}
+		}
+	}
+
+	if len(a.signingBlock) == 0 {
+		return result, nil
+	}
+	pairs, err := parseSigningBlock(a.signingBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	// Digests are computed as if the signing block was absent
+	eocd := bytes.Clone(apk[layout.eocdOffset:])
+	binary.LittleEndian.PutUint32(eocd[16:], uint32(layout.signingBlock))
+	sections := [][]byte{apk[:layout.signingBlock], apk[layout.cdOffset:layout.eocdOffset], eocd}
+
+	if value, ok := pairs[v2BlockID]; ok {
+		result.V2 = verifyScheme(2, value, sections)
+	}
+	if value, ok := pairs[v3BlockID]; ok {
+		result.V3 = verifyScheme(3, value, sections)
+	}
+	if result.V2.Present && !result.V3.Present && result.V2.Error == nil && v2RequiresV3(pairs[v2BlockID]) {
+		result.V2.Verified = false
+		result.V2.Error = errors.New("v2: APK was signed with v3 but the v3 signature was stripped")
+	}
+	return result, nil
+}
+
+// VerifyFile verifies the APK at path
+func VerifyFile(path string) (*Result, error) {
+	apk, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Verify(apk)
+}
+
+// verifyScheme verifies the signers of a v2 or v3 block
+func verifyScheme(version int, value []byte, sections [][]byte) SchemeResult {
+	result := SchemeResult{Present: true}
+	fail := func(format string, args ...any) SchemeResult {
+		result.Error = fmt.Errorf("v%d: %s", version, fmt.Sprintf(format, args...))
+		return result
+	}
+
+	signers := (&reader{data: value}).elements()
+	if len(signers) == 0 {
+		return fail("no signers")
+	}
+
+	for _, signer := range signers {
+		r := &reader{data: signer}
+		signedData := r.bytes()
+		if version == 3 {
+			r.uint32() // minSdkVersion
+			r.uint32() // maxSdkVersion
+		}
+		signatures := r.elements()
+		publicKeyBytes := r.bytes()
+		if r.err != nil {
+			return fail("%v", r.err)
+		}
+
+		publicKey, err := x509.ParsePKIXPublicKey(publicKeyBytes)
+		if err != nil {
+			return fail("invalid public key: %v", err)
+		}
+
+		// Verify the strongest supported signature over the signed data
+		var alg algorithm
+		var signature []byte
+		for _, s := range signatures {
+			sr := &reader{data: s}
+			id := sr.uint32()
+			sig := sr.bytes()
+			if candidate, ok := algorithms[id]; ok && sr.err == nil && (signature == nil || candidate.hash > alg.hash) {
+				alg, signature = candidate, sig
+			}
+		}
+		if signature == nil {
+			return fail("no supported signatures")
+		}
+		if err := alg.verify(publicKey, signedData, signature); err != nil {
+			return fail("signature does not verify: %v", err)
+		}
+
+		sd := &reader{data: signedData}
+		digests := sd.elements()
+		certs := sd.elements()
+		if sd.err != nil || len(certs) == 0 {
+			return fail("malformed signed data")
+		}
+
+		var digest []byte
+		for _, d := range digests {
+			dr := &reader{data: d}
+			if dr.uint32() == alg.id {
+				digest = dr.bytes()
+			}
+		}
+		if digest == nil {
+			return fail("no digest for signature algorithm %#04x", alg.id)
+		}
+		if !bytes.Equal(digest, contentDigest(alg.hash, sections...)) {
+			return fail("APK contents do not match the signed digest")
+		}
+
+		cert, err := x509.ParseCertificate(certs[0])
+		if err != nil {
+			return fail("invalid certificate: %v", err)
+		}
+		certKey, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+		if err != nil || !bytes.Equal(certKey, publicKeyBytes) {
+			return fail("public key does not match the certificate")
+		}
+		result.Certificates = append(result.Certificates, cert)
+	}
+
+	result.Verified = true
+	return result
+}
+
+// v2RequiresV3 reports whether the v2 signer carries the stripping protection
+// attribute announcing a v3 signature
+func v2RequiresV3(value []byte) bool {
+	for _, signer := range (&reader{data: value}).elements() {
+		sd := &reader{data: (&reader{data: signer}).bytes()}
+		sd.elements() // digests
+		sd.elements() // certificates
+		for _, attr := range sd.elements() {
+			ar := &reader{data: attr}
+			if ar.uint32() == strippingProtectionAttrID && ar.uint32() >= 3 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CertificateDigest returns the SHA-256 digest of a signer certificate
+func CertificateDigest(cert *x509.Certificate) []byte {
+	sum := sha256.Sum256(cert.Raw)
+	return sum[:]
+}
@@ -0,0 +1,254 @@
+package apksign
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testAPK returns an unsigned zip laid out like an APK, with deflated and
+// stored entries and a native library
+func testAPK(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	files := []struct {
+		name   string
+		method uint16
+		data   string
+	}{
+		{"AndroidManifest.xml", zip.Deflate, "<manifest package=\"com.acme.app\"/>"},
+		{"classes.dex", zip.Deflate, strings.Repeat("dex\n", 500)},
+		{"res/raw/a.txt", zip.Store, "odd"},
+		{"resources.arsc", zip.Store, strings.Repeat("arsc", 300)},
+		{"lib/arm64-v8a/libapp.so", zip.Store, strings.Repeat("\x7fELF", 64)},
+	}
+	for _, f := range files {
+		fw, err := w.CreateHeader(&zip.FileHeader{Name: f.name, Method: f.method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testSigner returns a signer with a self-signed certificate for key
+func testSigner(t *testing.T, key crypto.Signer) *Signer {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Signer{PrivateKey: key, Certificates: []*x509.Certificate{cert}}
+}
+
+func testSigners(t *testing.T) map[string]*Signer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]*Signer{"RSA": testSigner(t, rsaKey), "EC": testSigner(t, ecKey)}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	apk := testAPK(t)
+	for name, signer := range testSigners(t) {
+		t.Run(name, func(t *testing.T) {
+			signed, err := Sign(apk, signer, DefaultOptions(21))
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := Verify(signed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for scheme, r := range map[string]SchemeResult{"v1": result.V1, "v2": result.V2, "v3": result.V3} {
+				if !r.Present || !r.Verified {
+					t.Errorf("%s: present %t, verified %t, %v", scheme, r.Present, r.Verified, r.Error)
+				}
+				if len(r.Certificates) == 0 || !r.Certificates[0].Equal(signer.Certificates[0]) {
+					t.Errorf("%s: signer certificate not reported", scheme)
+				}
+			}
+			if !result.Verified() {
+				t.Error("Verified() = false")
+			}
+
+			// Signing the signed APK again replaces the signatures
+			again, err := Sign(signed, signer, DefaultOptions(21))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result, err := Verify(again); err != nil || !result.Verified() {
+				t.Errorf("re-signed APK does not verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestSignSkipsV1FromAPI24(t *testing.T) {
+	signer := testSigners(t)["EC"]
+	signed, err := Sign(testAPK(t), signer, DefaultOptions(24))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Verify(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.V1.Present || !result.V2.Verified || !result.V3.Verified {
+		t.Errorf("v1 %t, v2 %t, v3 %t", result.V1.Present, result.V2.Verified, result.V3.Verified)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	apk := testAPK(t)
+	signer := testSigners(t)["EC"]
+	signed, err := Sign(apk, signer, DefaultOptions(21))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Change one byte of the stored resources.arsc without touching the
+	// zip structure
+	offset := bytes.Index(signed, []byte("arscarscarsc"))
+	if offset < 0 {
+		t.Fatal("resources.arsc data not found")
+	}
+	tampered := bytes.Clone(signed)
+	tampered[offset] = 'A'
+	result, err := Verify(tampered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for scheme, r := range map[string]SchemeResult{"v1": result.V1, "v2": result.V2, "v3": result.V3} {
+		if r.Verified || r.Error == nil {
+			t.Errorf("%s verifies a tampered APK", scheme)
+		}
+	}
+	if result.Verified() {
+		t.Error("Verified() = true for a tampered APK")
+	}
+
+	// Dropping the v3 signature is caught by the stripping protection of v2
+	stripped := withoutBlock(t, signed, v3BlockID)
+	result, err = Verify(stripped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.V3.Present || result.V2.Verified || result.Verified() {
+		t.Errorf("v3 present %t, v2 verified %t after stripping v3", result.V3.Present, result.V2.Verified)
+	}
+}
+
+// withoutBlock removes a pair from the APK Signing Block of apk
+func withoutBlock(t *testing.T, apk []byte, id uint32) []byte {
+	t.Helper()
+	layout, err := findLayout(apk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pairs, err := parseSigningBlock(apk[layout.signingBlock:layout.cdOffset])
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(pairs, id)
+	block := signingBlock(pairs, v2BlockID, v3BlockID)
+
+	out := append(bytes.Clone(apk[:layout.signingBlock]), block...)
+	out = append(out, apk[layout.cdOffset:]...)
+	eocd := len(out) - len(apk[layout.eocdOffset:])
+	binary.LittleEndian.PutUint32(out[eocd+16:], uint32(layout.signingBlock)+uint32(len(block)))
+	return out
+}
+
+func TestAlign(t *testing.T) {
+	aligned, err := Align(testAPK(t), DefaultAlignment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(aligned), int64(len(aligned)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range r.File {
+		if f.Method != zip.Store {
+			continue
+		}
+		offset, err := f.DataOffset()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := int64(DefaultAlignment)
+		if strings.HasSuffix(f.Name, ".so") {
+			want = PageAlignment
+		}
+		if offset%want != 0 {
+			t.Errorf("%s starts at %d, not aligned to %d", f.Name, offset, want)
+		}
+		// The content survives the move, Open checks the CRC
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.ReadAll(rc); err != nil {
+			t.Errorf("%s: %v", f.Name, err)
+		}
+		rc.Close()
+	}
+}
+
+// The chunked digest of the APK Signature Scheme, computed by hand for an
+// input shorter than one chunk
+func TestContentDigestKnownVector(t *testing.T) {
+	data := []byte("abc")
+	chunk := sha256.Sum256(append([]byte{0xa5, 3, 0, 0, 0}, data...))
+	want := sha256.Sum256(append([]byte{0x5a, 1, 0, 0, 0}, chunk[:]...))
+	if got := contentDigest(crypto.SHA256, data); !bytes.Equal(got, want[:]) {
+		t.Errorf("contentDigest = %x, want %x", got, want)
+	}
+
+	// Sections are chunked separately, 1 MiB and one byte make two chunks
+	large := make([]byte, chunkSize+1)
+	first := sha256.Sum256(append([]byte{0xa5, 0, 0, 0x10, 0}, large[:chunkSize]...))
+	second := sha256.Sum256([]byte{0xa5, 1, 0, 0, 0, 0})
+	top := append([]byte{0x5a, 2, 0, 0, 0}, first[:]...)
+	want = sha256.Sum256(append(top, second[:]...))
+	if got := contentDigest(crypto.SHA256, large); !bytes.Equal(got, want[:]) {
+		t.Errorf("contentDigest of two chunks = %x, want %x", got, want)
+	}
+}
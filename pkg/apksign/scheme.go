@@ -0,0 +1,286 @@
+package apksign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Block IDs inside the APK Signing Block
+const (
+	v2BlockID = 0x7109871a
+	v3BlockID = 0xf05368c0
+
+	// strippingProtectionAttrID marks a v2 signature as part of a v3 signed
+	// APK so the v3 block cannot be removed to downgrade verification
+	strippingProtectionAttrID = 0xbeeff00d
+
+	// minSDKForV3 is Android 9, the first release that verifies v3 signatures
+	minSDKForV3 = 28
+
+	chunkSize = 1 << 20
+)
+
+// algorithm is a signature algorithm of the APK Signature Scheme v2/v3
+type algorithm struct {
+	id   uint32
+	hash crypto.Hash
+}
+
+var (
+	rsaPKCS1SHA256 = algorithm{0x0103, crypto.SHA256}
+	rsaPKCS1SHA512 = algorithm{0x0104, crypto.SHA512}
+	ecdsaSHA256    = algorithm{0x0201, crypto.SHA256}
+	ecdsaSHA512    = algorithm{0x0202, crypto.SHA512}
+
+	algorithms = map[uint32]algorithm{
+		rsaPKCS1SHA256.id: rsaPKCS1SHA256,
+		rsaPKCS1SHA512.id: rsaPKCS1SHA512,
+		ecdsaSHA256.id:    ecdsaSHA256,
+		ecdsaSHA512.id:    ecdsaSHA512,
+	}
+)
+
+// algorithmFor picks the algorithm apksigner would use for the key
+func algorithmFor(key crypto.PublicKey) (algorithm, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() <= 3072 {
+			return rsaPKCS1SHA256, nil
+		}
+		return rsaPKCS1SHA512, nil
+	case *ecdsa.PublicKey:
+		if k.Curve.Params().BitSize <= 256 {
+			return ecdsaSHA256, nil
+		}
+		return ecdsaSHA512, nil
+	}
+	return algorithm{}, fmt.Errorf("unsupported key type %T", key)
+}
+
+// sign signs message with the key using the algorithm's digest
+func (alg algorithm) sign(key crypto.Signer, message []byte) ([]byte, error) {
+	h := alg.hash.New()
+	h.Write(message)
+	return key.Sign(rand.Reader, h.Sum(nil), alg.hash)
+}
+
+// verify checks a signature over message
+func (alg algorithm) verify(key crypto.PublicKey, message, signature []byte) error {
+	h := alg.hash.New()
+	h.Write(message)
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, alg.hash, digest, signature)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, digest, signature) {
+			return errors.New("ECDSA signature verification failed")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported key type %T", key)
+}
+
+// contentDigest computes the chunked digest of the zip entries, the central
+// directory and the end of central directory record. eocd must already point
+// the central directory offset at the start of the signing block.
+func contentDigest(hash crypto.Hash, sections ...[]byte) []byte {
+	var chunks int
+	for _, section := range sections {
+		chunks += (len(section) + chunkSize - 1) / chunkSize
+	}
+
+	top := make([]byte, 5, 5+chunks*hash.Size())
+	top[0] = 0x5a
+	binary.LittleEndian.PutUint32(top[1:], uint32(chunks))
+
+	prefix := make([]byte, 5)
+	prefix[0] = 0xa5
+	for _, section := range sections {
+		for len(section) > 0 {
+			chunk := section[:min(chunkSize, len(section))]
+			section = section[len(chunk):]
+
+			binary.LittleEndian.PutUint32(prefix[1:], uint32(len(chunk)))
+			h := hash.New()
+			h.Write(prefix)
+			h.Write(chunk)
+			top = h.Sum(top)
+		}
+	}
+
+	h := hash.New()
+	h.Write(top)
+	return h.Sum(nil)
+}
+
+// lengthPrefixed prepends the little-endian uint32 length of data
+func lengthPrefixed(data ...[]byte) []byte {
+	var size int
+	for _, d := range data {
+		size += len(d)
+	}
+	out := binary.LittleEndian.AppendUint32(make([]byte, 0, 4+size), uint32(size))
+	for _, d := range data {
+		out = append(out, d...)
+	}
+	return out
+}
+
+// sequence encodes elements as a length-prefixed sequence of length-prefixed elements
+func sequence(elements ...[]byte) []byte {
+	var body []byte
+	for _, e := range elements {
+		body = append(body, lengthPrefixed(e)...)
+	}
+	return lengthPrefixed(body)
+}
+
+func uint32Bytes(v uint32) []byte {
+	return binary.LittleEndian.AppendUint32(nil, v)
+}
+
+// signerBlock builds the v2 or v3 signer for the given content digest.
+// For v2, withV3 adds the stripping protection attribute.
+func signerBlock(version int, signer *Signer, alg algorithm, digest []byte, minSDK int, withV3 bool) ([]byte, error) {
+	certs := make([][]byte, len(signer.Certificates))
+	for i, cert := range signer.Certificates {
+		certs[i] = cert.Raw
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(signer.PrivateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	digests := sequence(append(uint32Bytes(alg.id), lengthPrefixed(digest)...))
+
+	var signedData []byte
+	switch version {
+	case 2:
+		var attrs [][]byte
+		if withV3 {
+			attrs = append(attrs, append(uint32Bytes(strippingProtectionAttrID), uint32Bytes(3)...))
+		}
+		signedData = append(signedData, digests...)
+		signedData = append(signedData, sequence(certs...)...)
+		signedData = append(signedData, sequence(attrs...)...)
+	case 3:
+		signedData = append(signedData, digests...)
+		signedData = append(signedData, sequence(certs...)...)
+		signedData = append(signedData, uint32Bytes(uint32(minSDK))...)
+		signedData = append(signedData, uint32Bytes(math.MaxInt32)...)
+		signedData = append(signedData, sequence()...)
+	}
+
+	signature, err := alg.sign(signer.PrivateKey, signedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign v%d signed data: %w", version, err)
+	}
+	signatures := sequence(append(uint32Bytes(alg.id), lengthPrefixed(signature)...))
+
+	block := lengthPrefixed(signedData)
+	if version == 3 {
+		block = append(block, uint32Bytes(uint32(minSDK))...)
+		block = append(block, uint32Bytes(math.MaxInt32)...)
+	}
+	block = append(block, signatures...)
+	block = append(block, lengthPrefixed(publicKey)...)
+
+	// The block value is a sequence of signers, of which we have one
+	return sequence(block), nil
+}
+
+// signingBlock assembles the APK Signing Block from ID-value pairs
+func signingBlock(pairs map[uint32][]byte, order ...uint32) []byte {
+	var body []byte
+	for _, id := range order {
+		value, ok := pairs[id]
+		if !ok {
+			continue
+		}
+		body = binary.LittleEndian.AppendUint64(body, uint64(4+len(value)))
+		body = binary.LittleEndian.AppendUint32(body, id)
+		body = append(body, value...)
+	}
+
+	size := uint64(len(body) + 8 + len(signingBlockMagic))
+	block := binary.LittleEndian.AppendUint64(nil, size)
+	block = append(block, body...)
+	block = binary.LittleEndian.AppendUint64(block, size)
+	return append(block, signingBlockMagic...)
+}
+
+// parseSigningBlock returns the ID-value pairs of an APK Signing Block
+func parseSigningBlock(block []byte) (map[uint32][]byte, error) {
+	if len(block) < 32 {
+		return nil, errors.New("APK Signing Block is too short")
+	}
+	pairs := map[uint32][]byte{}
+	body := block[8 : len(block)-24]
+	for len(body) > 0 {
+		if len(body) < 12 {
+			return nil, errors.New("truncated APK Signing Block pair")
+		}
+		size := binary.LittleEndian.Uint64(body)
+		if size < 4 || size > uint64(len(body)-8) {
+			return nil, errors.New("invalid APK Signing Block pair length")
+		}
+		id := binary.LittleEndian.Uint32(body[8:])
+		pairs[id] = body[12 : 8+size]
+		body = body[8+size:]
+	}
+	return pairs, nil
+}
+
+// reader consumes the little-endian, length-prefixed encoding of signer blocks
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) uint32() uint32 {
+	if r.err != nil || len(r.data) < 4 {
+		r.fail()
+		return 0
+	}
+	v := binary.LittleEndian.Uint32(r.data)
+	r.data = r.data[4:]
+	return v
+}
+
+func (r *reader) bytes() []byte {
+	size := r.uint32()
+	if r.err != nil || uint64(size) > uint64(len(r.data)) {
+		r.fail()
+		return nil
+	}
+	v := r.data[:size]
+	r.data = r.data[size:]
+	return v
+}
+
+// elements splits a length-prefixed sequence into its elements
+func (r *reader) elements() [][]byte {
+	seq := &reader{data: r.bytes()}
+	var out [][]byte
+	for r.err == nil && len(seq.data) > 0 {
+		out = append(out, seq.bytes())
+		if seq.err != nil {
+			r.fail()
+		}
+	}
+	return out
+}
+
+func (r *reader) fail() {
+	if r.err == nil {
+		r.err = errors.New("malformed signer block")
+	}
+}
@@ -0,0 +1,365 @@
+package apksign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	manifestName  = "META-INF/MANIFEST.MF"
+	signatureName = "META-INF/CERT"
+	createdBy     = "1.0 (Velo)"
+	maxLineLength = 72
+)
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECDSAWithSHA  = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+)
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      contentInfo
+	Certificates     asn1.RawValue `asn1:"tag:0,optional"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type signerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     issuerAndSerial
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+}
+
+type issuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+// isSignatureFile reports whether name is part of a JAR signature and must be
+// excluded from the manifest and replaced when re-signing
+func isSignatureFile(name string) bool {
+	if !strings.HasPrefix(name, "META-INF/") || strings.Count(name, "/") != 1 {
+		return false
+	}
+	if name == manifestName {
+		return true
+	}
+	base := strings.ToUpper(path.Base(name))
+	switch path.Ext(base) {
+	case ".SF", ".RSA", ".DSA", ".EC":
+		return true
+	}
+	return strings.HasPrefix(base, "SIG-")
+}
+
+// manifestSection writes "key: value" lines wrapped at 72 bytes
+func manifestSection(buf *bytes.Buffer, attrs ...[2]string) {
+	for _, attr := range attrs {
+		line := attr[0] + ": " + attr[1]
+		for first := true; len(line) > 0; first = false {
+			limit := maxLineLength
+			if !first {
+				buf.WriteByte(' ')
+				limit--
+			}
+			n := min(limit, len(line))
+			buf.WriteString(line[:n])
+			buf.WriteString("\r\n")
+			line = line[n:]
+		}
+	}
+	buf.WriteString("\r\n")
+}
+
+func digestBase64(data []byte) string {
+	sum := sha256.Sum256(data)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// signV1 adds a JAR signature (MANIFEST.MF, CERT.SF and CERT.RSA/CERT.EC) to
+// the archive. The signature file declares the v2/v3 schemes so Android
+// rejects APKs whose newer signatures were stripped.
+func signV1(a *archive, signer *Signer, schemes string) error {
+	a.remove(isSignatureFile)
+
+	names := make([]string, 0, len(a.entries))
+	for _, e := range a.entries {
+		if !strings.HasSuffix(e.name, "/") {
+			names = append(names, e.name)
+		}
+	}
+	sort.Strings(names)
+
+	var manifest bytes.Buffer
+	manifestSection(&manifest, [2]string{"Manifest-Version", "1.0"}, [2]string{"Created-By", createdBy})
+
+	var sections []byte
+	var sectionDigests bytes.Buffer
+	for _, name := range names {
+		content, err := a.find(name).open()
+		if err != nil {
+			return err
+		}
+		var section bytes.Buffer
+		manifestSection(&section, [2]string{"Name", name}, [2]string{"SHA-256-Digest", digestBase64(content)})
+		sections = append(sections, section.Bytes()...)
+		manifestSection(&sectionDigests, [2]string{"Name", name}, [2]string{"SHA-256-Digest", digestBase64(section.Bytes())})
+	}
+	manifest.Write(sections)
+
+	var sf bytes.Buffer
+	header := [][2]string{
+		{"Signature-Version", "1.0"},
+		{"Created-By", createdBy},
+		{"SHA-256-Digest-Manifest", digestBase64(manifest.Bytes())},
+	}
+	if schemes != "" {
+		header = append(header, [2]string{"X-Android-APK-Signed", schemes})
+	}
+	manifestSection(&sf, header...)
+	sf.Write(sectionDigests.Bytes())
+
+	block, ext, err := pkcs7Sign(signer, sf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	if err := a.add(manifestName, manifest.Bytes()); err != nil {
+		return err
+	}
+	if err := a.add(signatureName+".SF", sf.Bytes()); err != nil {
+		return err
+	}
+	return a.add(signatureName+ext, block)
+}
+
+// pkcs7Sign creates a detached PKCS#7 SignedData over content and returns it
+// with the signature block file extension for the key type
+func pkcs7Sign(signer *Signer, content []byte) ([]byte, string, error) {
+	cert := signer.Certificates[0]
+
+	var (
+		encryption pkix.AlgorithmIdentifier
+		ext        string
+	)
+	switch signer.PrivateKey.Public().(type) {
+	case *rsa.PublicKey:
+		encryption = pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue}
+		ext = ".RSA"
+	case *ecdsa.PublicKey:
+		encryption = pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA}
+		ext = ".EC"
+	default:
+		return nil, "", fmt.Errorf("unsupported key type %T", signer.PrivateKey.Public())
+	}
+
+	digest := sha256.Sum256(content)
+	signature, err := signer.PrivateKey.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to sign signature file: %w", err)
+	}
+
+	var certs []byte
+	for _, c := range signer.Certificates {
+		certs = append(certs, c.Raw...)
+	}
+	sha256Alg := pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue}
+
+	sd, err := asn1.Marshal(signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{sha256Alg},
+		ContentInfo:      contentInfo{ContentType: oidData},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certs},
+		SignerInfos: []signerInfo{{
+			Version:                   1,
+			IssuerAndSerialNumber:     issuerAndSerial{Issuer: asn1.RawValue{FullBytes: cert.RawIssuer}, SerialNumber: cert.SerialNumber},
+			DigestAlgorithm:           sha256Alg,
+			DigestEncryptionAlgorithm: encryption,
+			EncryptedDigest:           signature,
+		}},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	block, err := asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sd},
+	})
+	return block, ext, err
+}
+
+// verifyV1 checks the JAR signature of the archive and returns the signer
+// certificate. It returns nil without error when there is no JAR signature.
+func verifyV1(a *archive) (*x509.Certificate, error) {
+	var sfEntry, blockEntry *entry
+	for _, e := range a.entries {
+		if !isSignatureFile(e.name) || e.name == manifestName {
+			continue
+		}
+		if strings.EqualFold(path.Ext(e.name), ".SF") {
+			sfEntry = e
+		}
+	}
+	if sfEntry == nil {
+		return nil, nil
+	}
+	base := strings.TrimSuffix(sfEntry.name, path.Ext(sfEntry.name))
+	for _, ext := range []string{".RSA", ".EC", ".DSA"} {
+		if blockEntry = a.find(base + ext); blockEntry != nil {
+			break
+		}
+	}
+	manifestEntry := a.find(manifestName)
+	if blockEntry == nil || manifestEntry == nil {
+		return nil, errors.New("v1: incomplete JAR signature")
+	}
+
+	sf, err := sfEntry.open()
+	if err != nil {
+		return nil, err
+	}
+	block, err := blockEntry.open()
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := manifestEntry.open()
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := pkcs7Verify(block, sf)
+	if err != nil {
+		return nil, fmt.Errorf("v1: %w", err)
+	}
+
+	sfAttrs := parseManifest(sf)
+	if len(sfAttrs) == 0 || sfAttrs[0]["SHA-256-Digest-Manifest"] != digestBase64(manifest) {
+		return nil, errors.New("v1: manifest digest does not match the signature file")
+	}
+
+	for _, section := range parseManifest(manifest)[1:] {
+		name := section["Name"]
+		expected, ok := section["SHA-256-Digest"]
+		if !ok {
+			return nil, fmt.Errorf("v1: no SHA-256 digest for %s", name)
+		}
+		e := a.find(name)
+		if e == nil {
+			return nil, fmt.Errorf("v1: %s is listed in the manifest but missing", name)
+		}
+		content, err := e.open()
+		if err != nil {
+			return nil, err
+		}
+		if digestBase64(content) != expected {
+			return nil, fmt.Errorf("v1: digest mismatch for %s", name)
+		}
+	}
+	return cert, nil
+}
+
+// pkcs7Verify verifies a detached PKCS#7 signature without signed attributes
+func pkcs7Verify(block, content []byte) (*x509.Certificate, error) {
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(block, &ci); err != nil || !ci.ContentType.Equal(oidSignedData) {
+		return nil, errors.New("signature block is not PKCS#7 signed data")
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("invalid signed data: %w", err)
+	}
+	if len(sd.SignerInfos) == 0 {
+		return nil, errors.New("signature block has no signers")
+	}
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	si := sd.SignerInfos[0]
+	var cert *x509.Certificate
+	for _, c := range certs {
+		if c.SerialNumber.Cmp(si.IssuerAndSerialNumber.SerialNumber) == 0 && bytes.Equal(c.RawIssuer, si.IssuerAndSerialNumber.Issuer.FullBytes) {
+			cert = c
+		}
+	}
+	if cert == nil {
+		return nil, errors.New("signer certificate not found")
+	}
+
+	hash := crypto.SHA256
+	if !si.DigestAlgorithm.Algorithm.Equal(oidSHA256) {
+		hash = crypto.SHA1
+	}
+	h := hash.New()
+	h.Write(content)
+	digest := h.Sum(nil)
+
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, hash, digest, si.EncryptedDigest); err != nil {
+			return nil, fmt.Errorf("signature verification failed: %w", err)
+		}
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest, si.EncryptedDigest) {
+			return nil, errors.New("signature verification failed")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", cert.PublicKey)
+	}
+	return cert, nil
+}
+
+// parseManifest splits a manifest or signature file into sections of attributes
+func parseManifest(data []byte) []map[string]string {
+	var sections []map[string]string
+	current := map[string]string{}
+	var lastKey string
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for _, line := range lines {
+		switch {
+		case line == "":
+			if len(current) > 0 {
+				sections = append(sections, current)
+				current = map[string]string{}
+			}
+		case strings.HasPrefix(line, " "):
+			current[lastKey] += line[1:]
+		default:
+			key, value, _ := strings.Cut(line, ": ")
+			current[key] = value
+			lastKey = key
+		}
+	}
+	if len(current) > 0 {
+		sections = append(sections, current)
+	}
+	return sections
+}
@@ -0,0 +1,306 @@
+package apksign
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+)
+
+const (
+	localHeaderSignature = 0x04034b50
+	centralDirSignature  = 0x02014b50
+	eocdSignature        = 0x06054b50
+	localHeaderLen       = 30
+	centralDirLen        = 46
+	eocdLen              = 22
+
+	signingBlockMagic = "APK Sig Block 42"
+)
+
+var errNotZip = errors.New("not a valid zip archive")
+
+// entry is a zip entry kept in its raw, still compressed form so archives can
+// be rewritten without touching the entry data
+type entry struct {
+	name           string
+	method         uint16
+	compressedSize uint32
+	localHeader    []byte // fixed part of the local file header
+	localExtra     []byte
+	body           []byte // compressed data followed by the optional data descriptor
+	centralDir     []byte // fixed part of the central directory record
+	centralExtra   []byte
+	comment        []byte
+}
+
+// archive is a zip file as a list of raw entries
+type archive struct {
+	entries []*entry
+	comment []byte
+	// signingBlock is the APK Signing Block found between the entries and the
+	// central directory, if any
+	signingBlock []byte
+}
+
+// zipLayout holds the offsets of the sections of a zip file
+type zipLayout struct {
+	cdOffset     int64
+	cdSize       int64
+	eocdOffset   int64
+	entryCount   int
+	signingBlock int64 // offset of the APK Signing Block, equal to cdOffset when absent
+}
+
+// findLayout locates the end of central directory record and the optional
+// APK Signing Block
+func findLayout(data []byte) (*zipLayout, error) {
+	if len(data) < eocdLen {
+		return nil, errNotZip
+	}
+
+	eocd := -1
+	minOffset := max(0, len(data)-eocdLen-0xffff)
+	for i := len(data) - eocdLen; i >= minOffset; i-- {
+		if binary.LittleEndian.Uint32(data[i:]) != eocdSignature {
+			continue
+		}
+		commentLen := int(binary.LittleEndian.Uint16(data[i+20:]))
+		if i+eocdLen+commentLen == len(data) {
+			eocd = i
+			break
+		}
+	}
+	if eocd < 0 {
+		return nil, errNotZip
+	}
+
+	layout := &zipLayout{
+		entryCount: int(binary.LittleEndian.Uint16(data[eocd+10:])),
+		cdSize:     int64(binary.LittleEndian.Uint32(data[eocd+12:])),
+		cdOffset:   int64(binary.LittleEndian.Uint32(data[eocd+16:])),
+		eocdOffset: int64(eocd),
+	}
+	if layout.cdOffset == 0xffffffff || layout.entryCount == 0xffff {
+		return nil, fmt.Errorf("zip64 archives are not supported")
+	}
+	if layout.cdOffset+layout.cdSize != layout.eocdOffset {
+		return nil, fmt.Errorf("%w: central directory does not end at the end of central directory record", errNotZip)
+	}
+
+	layout.signingBlock = layout.cdOffset
+	if layout.cdOffset >= 32 && string(data[layout.cdOffset-16:layout.cdOffset]) == signingBlockMagic {
+		size := int64(binary.LittleEndian.Uint64(data[layout.cdOffset-24:]))
+		start := layout.cdOffset - size - 8
+		if start < 0 || int64(binary.LittleEndian.Uint64(data[start:])) != size {
+			return nil, fmt.Errorf("invalid APK Signing Block")
+		}
+		layout.signingBlock = start
+	}
+	return layout, nil
+}
+
+// readArchive parses data into raw entries
+func readArchive(data []byte) (*archive, error) {
+	layout, err := findLayout(data)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &archive{
+		comment:      bytes.Clone(data[layout.eocdOffset+eocdLen:]),
+		signingBlock: data[layout.signingBlock:layout.cdOffset],
+	}
+
+	offsets := make(map[*entry]int64, layout.entryCount)
+	pos := layout.cdOffset
+	for i := 0; i < layout.entryCount; i++ {
+		if pos+centralDirLen > layout.eocdOffset || binary.LittleEndian.Uint32(data[pos:]) != centralDirSignature {
+			return nil, fmt.Errorf("%w: bad central directory record %d", errNotZip, i)
+		}
+		record := data[pos : pos+centralDirLen]
+		nameLen := int64(binary.LittleEndian.Uint16(record[28:]))
+		extraLen := int64(binary.LittleEndian.Uint16(record[30:]))
+		commentLen := int64(binary.LittleEndian.Uint16(record[32:]))
+		end := pos + centralDirLen + nameLen + extraLen + commentLen
+		if end > layout.eocdOffset {
+			return nil, fmt.Errorf("%w: truncated central directory", errNotZip)
+		}
+
+		e := &entry{
+			name:           string(data[pos+centralDirLen : pos+centralDirLen+nameLen]),
+			method:         binary.LittleEndian.Uint16(record[10:]),
+			compressedSize: binary.LittleEndian.Uint32(record[20:]),
+			centralDir:     bytes.Clone(record),
+			centralExtra:   bytes.Clone(data[pos+centralDirLen+nameLen : pos+centralDirLen+nameLen+extraLen]),
+			comment:        bytes.Clone(data[pos+centralDirLen+nameLen+extraLen : end]),
+		}
+		offsets[e] = int64(binary.LittleEndian.Uint32(record[42:]))
+		a.entries = append(a.entries, e)
+		pos = end
+	}
+
+	// Local records run until the next record, the signing block or the central directory
+	sorted := append([]*entry(nil), a.entries...)
+	sort.Slice(sorted, func(i, j int) bool { return offsets[sorted[i]] < offsets[sorted[j]] })
+	for i, e := range sorted {
+		start := offsets[e]
+		end := layout.signingBlock
+		if i+1 < len(sorted) {
+			end = offsets[sorted[i+1]]
+		}
+		if start+localHeaderLen > end || binary.LittleEndian.Uint32(data[start:]) != localHeaderSignature {
+			return nil, fmt.Errorf("%w: bad local header for %s", errNotZip, e.name)
+		}
+		header := data[start : start+localHeaderLen]
+		nameLen := int64(binary.LittleEndian.Uint16(header[26:]))
+		extraLen := int64(binary.LittleEndian.Uint16(header[28:]))
+		dataStart := start + localHeaderLen + nameLen + extraLen
+		if dataStart+int64(e.compressedSize) > end {
+			return nil, fmt.Errorf("%w: truncated data for %s", errNotZip, e.name)
+		}
+		e.localHeader = bytes.Clone(header)
+		e.localExtra = bytes.Clone(data[start+localHeaderLen+nameLen : dataStart])
+		e.body = data[dataStart:end]
+	}
+	a.entries = sorted
+
+	return a, nil
+}
+
+// open returns the uncompressed contents of the entry
+func (e *entry) open() ([]byte, error) {
+	compressed := e.body[:e.compressedSize]
+	switch e.method {
+	case 0:
+		return compressed, nil
+	case 8:
+		return io.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+	default:
+		return nil, fmt.Errorf("unsupported compression method %d for %s", e.method, e.name)
+	}
+}
+
+// find returns the entry with the given name
+func (a *archive) find(name string) *entry {
+	for _, e := range a.entries {
+		if e.name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// remove drops all entries matching the predicate
+func (a *archive) remove(match func(name string) bool) {
+	kept := a.entries[:0]
+	for _, e := range a.entries {
+		if !match(e.name) {
+			kept = append(kept, e)
+		}
+	}
+	a.entries = kept
+}
+
+// add appends a deflated file to the archive
+func (a *archive) add(name string, content []byte) error {
+	var compressed bytes.Buffer
+	w, err := flate.NewWriter(&compressed, flate.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(content); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	crc := crc32.ChecksumIEEE(content)
+	// A fixed timestamp of 1981-01-01 00:00 keeps signed output reproducible
+	const dosDate = 1<<9 | 1<<5 | 1
+
+	local := make([]byte, localHeaderLen)
+	binary.LittleEndian.PutUint32(local[0:], localHeaderSignature)
+	binary.LittleEndian.PutUint16(local[4:], 20)
+	binary.LittleEndian.PutUint16(local[8:], 8)
+	binary.LittleEndian.PutUint16(local[12:], dosDate)
+	binary.LittleEndian.PutUint32(local[14:], crc)
+	binary.LittleEndian.PutUint32(local[18:], uint32(compressed.Len()))
+	binary.LittleEndian.PutUint32(local[22:], uint32(len(content)))
+	binary.LittleEndian.PutUint16(local[26:], uint16(len(name)))
+
+	central := make([]byte, centralDirLen)
+	binary.LittleEndian.PutUint32(central[0:], centralDirSignature)
+	binary.LittleEndian.PutUint16(central[4:], 20)
+	binary.LittleEndian.PutUint16(central[6:], 20)
+	binary.LittleEndian.PutUint16(central[10:], 8)
+	binary.LittleEndian.PutUint16(central[14:], dosDate)
+	binary.LittleEndian.PutUint32(central[16:], crc)
+	binary.LittleEndian.PutUint32(central[20:], uint32(compressed.Len()))
+	binary.LittleEndian.PutUint32(central[24:], uint32(len(content)))
+	binary.LittleEndian.PutUint16(central[28:], uint16(len(name)))
+
+	a.entries = append(a.entries, &entry{
+		name:           name,
+		method:         8,
+		compressedSize: uint32(compressed.Len()),
+		localHeader:    local,
+		body:           compressed.Bytes(),
+		centralDir:     central,
+	})
+	return nil
+}
+
+// bytes serializes the archive without a signing block. The alignment
+// function returns the required data alignment of each entry, or 0.
+func (a *archive) bytes(alignment func(e *entry) int) []byte {
+	var out bytes.Buffer
+	offsets := make([]uint32, len(a.entries))
+
+	for i, e := range a.entries {
+		offsets[i] = uint32(out.Len())
+		extra := stripAlignmentExtra(e.localExtra)
+		if align := alignment(e); align > 0 {
+			extra = alignmentExtra(extra, out.Len()+localHeaderLen+len(e.name), align)
+		}
+
+		header := bytes.Clone(e.localHeader)
+		binary.LittleEndian.PutUint16(header[26:], uint16(len(e.name)))
+		binary.LittleEndian.PutUint16(header[28:], uint16(len(extra)))
+		out.Write(header)
+		out.WriteString(e.name)
+		out.Write(extra)
+		out.Write(e.body)
+	}
+
+	cdOffset := out.Len()
+	for i, e := range a.entries {
+		record := bytes.Clone(e.centralDir)
+		binary.LittleEndian.PutUint16(record[28:], uint16(len(e.name)))
+		binary.LittleEndian.PutUint16(record[30:], uint16(len(e.centralExtra)))
+		binary.LittleEndian.PutUint16(record[32:], uint16(len(e.comment)))
+		binary.LittleEndian.PutUint32(record[42:], offsets[i])
+		out.Write(record)
+		out.WriteString(e.name)
+		out.Write(e.centralExtra)
+		out.Write(e.comment)
+	}
+	cdSize := out.Len() - cdOffset
+
+	eocd := make([]byte, eocdLen)
+	binary.LittleEndian.PutUint32(eocd[0:], eocdSignature)
+	binary.LittleEndian.PutUint16(eocd[8:], uint16(len(a.entries)))
+	binary.LittleEndian.PutUint16(eocd[10:], uint16(len(a.entries)))
+	binary.LittleEndian.PutUint32(eocd[12:], uint32(cdSize))
+	binary.LittleEndian.PutUint32(eocd[16:], uint32(cdOffset))
+	binary.LittleEndian.PutUint16(eocd[20:], uint16(len(a.comment)))
+	out.Write(eocd)
+	out.Write(a.comment)
+
+	return out.Bytes()
+}
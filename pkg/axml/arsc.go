@@ -0,0 +1,224 @@
+package axml
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Flags of resource table types and entries
+const (
+	typeFlagSparse   = 0x01
+	typeFlagOffset16 = 0x02
+
+	entryFlagComplex = 0x0001
+	entryFlagCompact = 0x0008
+
+	noEntry16 = 0xffff
+)
+
+// Table is a decoded resources.arsc resource table. Only simple values are
+// kept; styles, arrays and plurals are skipped.
+type Table struct {
+	strings stringPool
+	values  map[uint32][]tableValue
+	names   map[uint32]string
+}
+
+// tableValue is one configuration of a resource
+type tableValue struct {
+	dataType uint8
+	data     uint32
+	// defaultConfig is set for values without qualifiers
+	defaultConfig bool
+}
+
+// ParseTable decodes a resources.arsc resource table
+func ParseTable(data []byte) (*Table, error) {
+	root, err := readChunk(data)
+	if err != nil {
+		return nil, err
+	}
+	if root.typ != chunkTable {
+		return nil, fmt.Errorf("not a resource table (chunk type %#04x)", root.typ)
+	}
+	children, err := chunks(root.body())
+	if err != nil {
+		return nil, err
+	}
+
+	table := &Table{values: map[uint32][]tableValue{}, names: map[uint32]string{}}
+	for _, c := range children {
+		switch c.typ {
+		case chunkStringPool:
+			if table.strings, err = parseStringPool(c); err != nil {
+				return nil, err
+			}
+		case chunkPackage:
+			if err := table.parsePackage(c); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return table, nil
+}
+
+// parsePackage decodes the types of a package chunk
+func (t *Table) parsePackage(c chunk) error {
+	if c.headerSize < 284 {
+		return errors.New("truncated package header")
+	}
+	header := c.data[8:]
+	id := binary.LittleEndian.Uint32(header)
+	typeStringsOffset := binary.LittleEndian.Uint32(header[260:])
+	keyStringsOffset := binary.LittleEndian.Uint32(header[268:])
+
+	pool := func(offset uint32) (stringPool, error) {
+		if uint64(offset) >= uint64(len(c.data)) {
+			return nil, errors.New("string pool offset out of range")
+		}
+		pc, err := readChunk(c.data[offset:])
+		if err != nil {
+			return nil, err
+		}
+		return parseStringPool(pc)
+	}
+	typeStrings, err := pool(typeStringsOffset)
+	if err != nil {
+		return fmt.Errorf("invalid type strings: %w", err)
+	}
+	keyStrings, err := pool(keyStringsOffset)
+	if err != nil {
+		return fmt.Errorf("invalid key strings: %w", err)
+	}
+
+	children, err := chunks(c.body())
+	if err != nil {
+		return err
+	}
+	for _, tc := range children {
+		if tc.typ == chunkType {
+			if err := t.parseType(tc, id, typeStrings, keyStrings); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseType decodes the entries of one type in one configuration
+func (t *Table) parseType(c chunk, packageID uint32, typeStrings, keyStrings stringPool) error {
+	if c.headerSize < 24 {
+		return errors.New("truncated type header")
+	}
+	header := c.data[8:]
+	typeID := uint32(header[0])
+	flags := header[1]
+	entryCount := binary.LittleEndian.Uint32(header[4:])
+	entriesStart := binary.LittleEndian.Uint32(header[8:])
+	config := c.data[20:c.headerSize]
+	typeName := typeStrings.get(typeID - 1)
+
+	if uint64(entriesStart) > uint64(len(c.data)) {
+		return errors.New("type entries out of range")
+	}
+	entries := c.data[entriesStart:]
+	offsets := c.body()
+
+	add := func(index, offset uint32) {
+		if uint64(offset)+8 > uint64(len(entries)) {
+			return
+		}
+		id := packageID<<24 | typeID<<16 | index
+		entry := entries[offset:]
+		size := binary.LittleEndian.Uint16(entry)
+		entryFlags := binary.LittleEndian.Uint16(entry[2:])
+
+		var key uint32
+		value := tableValue{defaultConfig: isDefaultConfig(config)}
+		switch {
+		case entryFlags&entryFlagCompact != 0:
+			// Compact entries store the key in the size field and the value inline
+			key = uint32(size)
+			value.dataType = uint8(entryFlags >> 8)
+			value.data = binary.LittleEndian.Uint32(entry[4:])
+		case entryFlags&entryFlagComplex != 0:
+			key = binary.LittleEndian.Uint32(entry[4:])
+			t.names[id] = typeName + "/" + keyStrings.get(key)
+			return
+		default:
+			key = binary.LittleEndian.Uint32(entry[4:])
+			if uint64(size)+8 > uint64(len(entry)) {
+				return
+			}
+			v := entry[size:]
+			value.dataType = v[3]
+			value.data = binary.LittleEndian.Uint32(v[4:])
+		}
+
+		t.names[id] = typeName + "/" + keyStrings.get(key)
+		t.values[id] = append(t.values[id], value)
+	}
+
+	switch {
+	case flags&typeFlagSparse != 0:
+		for i := uint32(0); i < entryCount && int(i)*4+4 <= len(offsets); i++ {
+			index := uint32(binary.LittleEndian.Uint16(offsets[i*4:]))
+			add(index, uint32(binary.LittleEndian.Uint16(offsets[i*4+2:]))*4)
+		}
+	case flags&typeFlagOffset16 != 0:
+		for i := uint32(0); i < entryCount && int(i)*2+2 <= len(offsets); i++ {
+			if offset := binary.LittleEndian.Uint16(offsets[i*2:]); offset != noEntry16 {
+				add(i, uint32(offset)*4)
+			}
+		}
+	default:
+		for i := uint32(0); i < entryCount && int(i)*4+4 <= len(offsets); i++ {
+			if offset := binary.LittleEndian.Uint32(offsets[i*4:]); offset != noIndex {
+				add(i, offset)
+			}
+		}
+	}
+	return nil
+}
+
+// isDefaultConfig reports whether a ResTable_config has no qualifiers. The
+// first field is the size of the struct itself.
+func isDefaultConfig(config []byte) bool {
+	if len(config) < 4 {
+		return true
+	}
+	for _, b := range config[4:] {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup returns the value of a resource, preferring the default configuration
+func (t *Table) lookup(id uint32) (tableValue, bool) {
+	values := t.values[id]
+	if len(values) == 0 {
+		return tableValue{}, false
+	}
+	for _, v := range values {
+		if v.defaultConfig {
+			return v, true
+		}
+	}
+	return values[0], true
+}
+
+// Resolve returns the value of a resource as text
+func (t *Table) Resolve(id uint32) (string, bool) {
+	if _, ok := t.lookup(id); !ok {
+		return "", false
+	}
+	return formatValue(typeReference, id, t.strings, t), true
+}
+
+// Name returns the type/name of a resource, e.g. string/app_name
+func (t *Table) Name(id uint32) string {
+	return t.names[id]
+}
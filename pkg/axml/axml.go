@@ -0,0 +1,238 @@
+// Package axml decodes the compiled XML and resource table formats found in
+// Android artifacts: binary XML (AndroidManifest.xml inside an APK), the
+// resources.arsc table and the protobuf XML used by app bundles.
+package axml
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// AndroidNamespace is the namespace URI of the android: attribute prefix
+const AndroidNamespace = "http://schemas.android.com/apk/res/android"
+
+// Chunk types of the binary resource formats
+const (
+	chunkStringPool   = 0x0001
+	chunkTable        = 0x0002
+	chunkXML          = 0x0003
+	chunkStartNS      = 0x0100
+	chunkEndNS        = 0x0101
+	chunkStartElement = 0x0102
+	chunkEndElement   = 0x0103
+	chunkCData        = 0x0104
+	chunkResourceMap  = 0x0180
+	chunkPackage      = 0x0200
+	chunkType         = 0x0201
+	chunkTypeSpec     = 0x0202
+)
+
+// noIndex marks an absent string reference
+const noIndex = 0xffffffff
+
+// Node is an element of a decoded XML document
+type Node struct {
+	Name     string
+	Attrs    []Attr
+	Children []*Node
+}
+
+// Attr is an attribute with its value rendered as text
+type Attr struct {
+	Namespace string
+	Name      string
+	Value     string
+}
+
+// Attr returns the value of the attribute with the given name, preferring the
+// android: namespace
+func (n *Node) Attr(name string) string {
+	value := ""
+	for _, a := range n.Attrs {
+		if a.Name != name {
+			continue
+		}
+		if a.Namespace == AndroidNamespace {
+			return a.Value
+		}
+		value = a.Value
+	}
+	return value
+}
+
+// Elements returns the direct children with the given name
+func (n *Node) Elements(name string) []*Node {
+	var out []*Node
+	for _, child := range n.Children {
+		if child.Name == name {
+			out = append(out, child)
+		}
+	}
+	return out
+}
+
+// chunk is the common header of every binary resource chunk
+type chunk struct {
+	typ        uint16
+	headerSize uint32
+	data       []byte // the whole chunk, header included
+}
+
+// readChunk reads the chunk at the start of data
+func readChunk(data []byte) (chunk, error) {
+	if len(data) < 8 {
+		return chunk{}, errors.New("truncated chunk header")
+	}
+	c := chunk{
+		typ:        binary.LittleEndian.Uint16(data),
+		headerSize: uint32(binary.LittleEndian.Uint16(data[2:])),
+	}
+	size := binary.LittleEndian.Uint32(data[4:])
+	if size < 8 || c.headerSize < 8 || c.headerSize > size || uint64(size) > uint64(len(data)) {
+		return chunk{}, fmt.Errorf("invalid chunk size %d for type %#04x", size, c.typ)
+	}
+	c.data = data[:size]
+	return c, nil
+}
+
+// chunks splits data into consecutive chunks
+func chunks(data []byte) ([]chunk, error) {
+	var out []chunk
+	for len(data) > 0 {
+		c, err := readChunk(data)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+		data = data[len(c.data):]
+	}
+	return out, nil
+}
+
+// body returns the chunk data after its header
+func (c chunk) body() []byte {
+	return c.data[c.headerSize:]
+}
+
+// Parse decodes a binary XML document. References are resolved against table
+// when it is not nil.
+func Parse(data []byte, table *Table) (*Node, error) {
+	root, err := readChunk(data)
+	if err != nil {
+		return nil, err
+	}
+	if root.typ != chunkXML {
+		return nil, fmt.Errorf("not a binary XML document (chunk type %#04x)", root.typ)
+	}
+	children, err := chunks(root.body())
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		pool       stringPool
+		resources  []uint32
+		namespaces = map[string]string{}
+		document   = &Node{}
+		stack      = []*Node{document}
+	)
+
+	for _, c := range children {
+		switch c.typ {
+		case chunkStringPool:
+			if pool, err = parseStringPool(c); err != nil {
+				return nil, err
+			}
+		case chunkResourceMap:
+			body := c.body()
+			for i := 0; i+4 <= len(body); i += 4 {
+				resources = append(resources, binary.LittleEndian.Uint32(body[i:]))
+			}
+		case chunkStartNS:
+			ext := c.body()
+			if len(ext) < 8 {
+				return nil, errors.New("truncated namespace chunk")
+			}
+			namespaces[pool.get(binary.LittleEndian.Uint32(ext[4:]))] = pool.get(binary.LittleEndian.Uint32(ext))
+		case chunkStartElement:
+			node, err := parseElement(c, pool, resources, table)
+			if err != nil {
+				return nil, err
+			}
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, node)
+			stack = append(stack, node)
+		case chunkEndElement:
+			if len(stack) == 1 {
+				return nil, errors.New("unbalanced end element")
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	if len(document.Children) == 0 {
+		return nil, errors.New("document has no root element")
+	}
+	return document.Children[0], nil
+}
+
+// parseElement decodes a start element chunk
+func parseElement(c chunk, pool stringPool, resources []uint32, table *Table) (*Node, error) {
+	ext := c.body()
+	if len(ext) < 20 {
+		return nil, errors.New("truncated element chunk")
+	}
+	node := &Node{Name: pool.get(binary.LittleEndian.Uint32(ext[4:]))}
+
+	start := int(binary.LittleEndian.Uint16(ext[8:]))
+	size := int(binary.LittleEndian.Uint16(ext[10:]))
+	count := int(binary.LittleEndian.Uint16(ext[12:]))
+	if size < 20 || start+count*size > len(ext) {
+		return nil, fmt.Errorf("invalid attributes in element %s", node.Name)
+	}
+
+	for i := 0; i < count; i++ {
+		attr := ext[start+i*size:]
+		nameIndex := binary.LittleEndian.Uint32(attr[4:])
+		name := pool.get(nameIndex)
+		if name == "" && int(nameIndex) < len(resources) {
+			// Obfuscated or stripped names still carry the attribute resource ID
+			name = attributeNames[resources[nameIndex]]
+		}
+
+		value := ""
+		if raw := binary.LittleEndian.Uint32(attr[8:]); raw != noIndex {
+			value = pool.get(raw)
+		} else {
+			value = formatValue(attr[15], binary.LittleEndian.Uint32(attr[16:]), pool, table)
+		}
+
+		node.Attrs = append(node.Attrs, Attr{
+			Namespace: pool.get(binary.LittleEndian.Uint32(attr)),
+			Name:      name,
+			Value:     value,
+		})
+	}
+	return node, nil
+}
+
+// attributeNames maps the framework attribute IDs velo reads to their names
+var attributeNames = map[uint32]string{
+	0x01010001: "label",
+	0x01010002: "icon",
+	0x01010003: "name",
+	0x01010010: "exported",
+	0x01010026: "mimeType",
+	0x01010027: "scheme",
+	0x01010028: "host",
+	0x01010029: "port",
+	0x0101002a: "path",
+	0x0101002b: "pathPrefix",
+	0x0101002c: "pathPattern",
+	0x0101020c: "minSdkVersion",
+	0x0101021b: "versionCode",
+	0x0101021c: "versionName",
+	0x01010270: "targetSdkVersion",
+	0x01010271: "maxSdkVersion",
+}
@@ -0,0 +1,110 @@
+package axml
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The fixtures are encoded by hand after ResourceTypes.h, the way aapt writes
+// them: testdata/AndroidManifest.xml has a UTF-16 string pool, a resource map
+// for the android: attributes and a label referring to 0x7f010000, which
+// testdata/resources.arsc defines as string/app_name, "Acme" by default and
+// "Acme DE" for German.
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParse(t *testing.T) {
+	manifest, err := Parse(readFixture(t, "AndroidManifest.xml"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Name != "manifest" {
+		t.Fatalf("root element is %q", manifest.Name)
+	}
+	for name, want := range map[string]string{
+		"package":     "com.acme.app",
+		"versionCode": "7",
+		"versionName": "1.2.0",
+	} {
+		if got := manifest.Attr(name); got != want {
+			t.Errorf("manifest %s = %q, want %q", name, got, want)
+		}
+	}
+	for _, attr := range manifest.Attrs {
+		if attr.Name == "versionCode" && attr.Namespace != AndroidNamespace {
+			t.Errorf("versionCode is in namespace %q", attr.Namespace)
+		}
+	}
+
+	sdk := manifest.Elements("uses-sdk")
+	if len(sdk) != 1 || sdk[0].Attr("minSdkVersion") != "24" || sdk[0].Attr("targetSdkVersion") != "34" {
+		t.Errorf("uses-sdk = %+v", sdk)
+	}
+
+	apps := manifest.Elements("application")
+	if len(apps) != 1 {
+		t.Fatalf("%d application elements", len(apps))
+	}
+	// Without a resource table references stay ids
+	if got := apps[0].Attr("label"); got != "@0x7f010000" {
+		t.Errorf("label = %q", got)
+	}
+	activities := apps[0].Elements("activity")
+	if len(activities) != 1 {
+		t.Fatalf("%d activity elements", len(activities))
+	}
+	if got := activities[0].Attr("name"); got != ".MainActivity" {
+		t.Errorf("activity name = %q", got)
+	}
+	if got := activities[0].Attr("exported"); got != "true" {
+		t.Errorf("activity exported = %q", got)
+	}
+}
+
+func TestParseWithTable(t *testing.T) {
+	table, err := ParseTable(readFixture(t, "resources.arsc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := table.Name(0x7f010000); got != "string/app_name" {
+		t.Errorf("Name = %q", got)
+	}
+	// The default configuration wins over the German one
+	if got, ok := table.Resolve(0x7f010000); !ok || got != "Acme" {
+		t.Errorf("Resolve = %q, %t", got, ok)
+	}
+	if _, ok := table.Resolve(0x7f010001); ok {
+		t.Error("Resolve of an unknown id succeeded")
+	}
+
+	manifest, err := Parse(readFixture(t, "AndroidManifest.xml"), table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := manifest.Elements("application")[0].Attr("label"); got != "Acme" {
+		t.Errorf("label = %q", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	manifest := readFixture(t, "AndroidManifest.xml")
+	if _, err := Parse(readFixture(t, "resources.arsc"), nil); err == nil {
+		t.Error("Parse of a resource table succeeded")
+	}
+	if _, err := Parse([]byte("<manifest/>"), nil); err == nil {
+		t.Error("Parse of text XML succeeded")
+	}
+	if _, err := Parse(manifest[:len(manifest)/2], nil); err == nil {
+		t.Error("Parse of a truncated file succeeded")
+	}
+	if _, err := ParseTable(manifest); err == nil {
+		t.Error("ParseTable of a manifest succeeded")
+	}
+}
@@ -0,0 +1,221 @@
+package axml
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+)
+
+// Field numbers of the aapt2 Resources.proto messages used for XML
+const (
+	xmlNodeElement = 1
+
+	xmlElementName      = 3
+	xmlElementAttribute = 4
+	xmlElementChild     = 5
+
+	xmlAttrNamespaceURI = 1
+	xmlAttrName         = 2
+	xmlAttrValue        = 3
+	xmlAttrResourceID   = 5
+	xmlAttrCompiledItem = 6
+
+	itemRef  = 1
+	itemStr  = 2
+	itemPrim = 7
+
+	refName  = 3
+	strValue = 1
+
+	primFloat    = 3
+	primIntDec   = 6
+	primIntHex   = 7
+	primBoolean  = 8
+	primColorMin = 9
+	primColorMax = 12
+)
+
+// ParseProto decodes an XmlNode in the protobuf format aapt2 writes into app
+// bundles, e.g. base/manifest/AndroidManifest.xml
+func ParseProto(data []byte) (*Node, error) {
+	fields, err := protoFields(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		if f.num == xmlNodeElement {
+			return parseProtoElement(f.bytes)
+		}
+	}
+	return nil, errors.New("document has no root element")
+}
+
+// parseProtoElement decodes an XmlElement message
+func parseProtoElement(data []byte) (*Node, error) {
+	fields, err := protoFields(data)
+	if err != nil {
+		return nil, err
+	}
+
+	node := &Node{}
+	for _, f := range fields {
+		switch f.num {
+		case xmlElementName:
+			node.Name = string(f.bytes)
+		case xmlElementAttribute:
+			attr, err := parseProtoAttr(f.bytes)
+			if err != nil {
+				return nil, err
+			}
+			node.Attrs = append(node.Attrs, attr)
+		case xmlElementChild:
+			childFields, err := protoFields(f.bytes)
+			if err != nil {
+				return nil, err
+			}
+			for _, cf := range childFields {
+				if cf.num != xmlNodeElement {
+					continue // text nodes
+				}
+				child, err := parseProtoElement(cf.bytes)
+				if err != nil {
+					return nil, err
+				}
+				node.Children = append(node.Children, child)
+			}
+		}
+	}
+	return node, nil
+}
+
+// parseProtoAttr decodes an XmlAttribute message. The source text of the
+// value is used when present, the compiled item otherwise.
+func parseProtoAttr(data []byte) (Attr, error) {
+	fields, err := protoFields(data)
+	if err != nil {
+		return Attr{}, err
+	}
+
+	var (
+		attr       Attr
+		resourceID uint32
+		compiled   []byte
+	)
+	for _, f := range fields {
+		switch f.num {
+		case xmlAttrNamespaceURI:
+			attr.Namespace = string(f.bytes)
+		case xmlAttrName:
+			attr.Name = string(f.bytes)
+		case xmlAttrValue:
+			attr.Value = string(f.bytes)
+		case xmlAttrResourceID:
+			resourceID = uint32(f.varint)
+		case xmlAttrCompiledItem:
+			compiled = f.bytes
+		}
+	}
+
+	if attr.Name == "" {
+		attr.Name = attributeNames[resourceID]
+	}
+	if attr.Value == "" && compiled != nil {
+		attr.Value = formatProtoItem(compiled)
+	}
+	return attr, nil
+}
+
+// formatProtoItem renders a compiled Item message as text
+func formatProtoItem(data []byte) string {
+	fields, err := protoFields(data)
+	if err != nil {
+		return ""
+	}
+	for _, f := range fields {
+		inner, err := protoFields(f.bytes)
+		if err != nil {
+			continue
+		}
+		switch f.num {
+		case itemRef:
+			for _, rf := range inner {
+				if rf.num == refName {
+					return "@" + string(rf.bytes)
+				}
+			}
+		case itemStr:
+			for _, sf := range inner {
+				if sf.num == strValue {
+					return string(sf.bytes)
+				}
+			}
+		case itemPrim:
+			for _, pf := range inner {
+				switch {
+				case pf.num == primFloat:
+					return strconv.FormatFloat(float64(math.Float32frombits(uint32(pf.varint))), 'g', -1, 32)
+				case pf.num == primIntDec:
+					return strconv.Itoa(int(int32(pf.varint)))
+				case pf.num == primIntHex:
+					return "0x" + strconv.FormatUint(pf.varint, 16)
+				case pf.num == primBoolean:
+					return strconv.FormatBool(pf.varint != 0)
+				case pf.num >= primColorMin && pf.num <= primColorMax:
+					return "#" + strconv.FormatUint(pf.varint, 16)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// protoField is a decoded protobuf field. Varint and fixed width values are
+// stored in varint, length-delimited ones in bytes.
+type protoField struct {
+	num    int
+	varint uint64
+	bytes  []byte
+}
+
+// protoFields decodes the fields of a protobuf message
+func protoFields(data []byte) ([]protoField, error) {
+	var fields []protoField
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errors.New("invalid protobuf field key")
+		}
+		data = data[n:]
+
+		f := protoField{num: int(key >> 3)}
+		switch key & 0x7 {
+		case 0: // varint
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return nil, errors.New("invalid protobuf varint")
+			}
+			f.varint, data = v, data[n:]
+		case 1: // 64-bit
+			if len(data) < 8 {
+				return nil, errors.New("truncated protobuf fixed64")
+			}
+			f.varint, data = binary.LittleEndian.Uint64(data), data[8:]
+		case 2: // length-delimited
+			size, n := binary.Uvarint(data)
+			if n <= 0 || size > uint64(len(data)-n) {
+				return nil, errors.New("invalid protobuf length")
+			}
+			f.bytes, data = data[n:n+int(size)], data[n+int(size):]
+		case 5: // 32-bit
+			if len(data) < 4 {
+				return nil, errors.New("truncated protobuf fixed32")
+			}
+			f.varint, data = uint64(binary.LittleEndian.Uint32(data)), data[4:]
+		default:
+			return nil, errors.New("unsupported protobuf wire type")
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
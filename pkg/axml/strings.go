@@ -0,0 +1,112 @@
+package axml
+
+import (
+	"encoding/binary"
+	"errors"
+	"unicode/utf16"
+)
+
+// flagUTF8 marks a string pool encoded as UTF-8 rather than UTF-16
+const flagUTF8 = 1 << 8
+
+// stringPool is a decoded string pool chunk
+type stringPool []string
+
+// get returns the string at index, or "" for absent references
+func (p stringPool) get(index uint32) string {
+	if index == noIndex || int(index) >= len(p) {
+		return ""
+	}
+	return p[index]
+}
+
+// parseStringPool decodes a string pool chunk
+func parseStringPool(c chunk) (stringPool, error) {
+	if c.headerSize < 28 {
+		return nil, errors.New("truncated string pool header")
+	}
+	header := c.data[8:]
+	count := binary.LittleEndian.Uint32(header)
+	flags := binary.LittleEndian.Uint32(header[8:])
+	stringsStart := binary.LittleEndian.Uint32(header[12:])
+
+	offsets := c.body()
+	if uint64(count)*4 > uint64(len(offsets)) || uint64(stringsStart) > uint64(len(c.data)) {
+		return nil, errors.New("invalid string pool")
+	}
+	data := c.data[stringsStart:]
+
+	pool := make(stringPool, count)
+	for i := range pool {
+		offset := binary.LittleEndian.Uint32(offsets[i*4:])
+		if uint64(offset) >= uint64(len(data)) {
+			return nil, errors.New("string offset out of range")
+		}
+		var ok bool
+		if flags&flagUTF8 != 0 {
+			pool[i], ok = decodeUTF8(data[offset:])
+		} else {
+			pool[i], ok = decodeUTF16(data[offset:])
+		}
+		if !ok {
+			return nil, errors.New("truncated string in string pool")
+		}
+	}
+	return pool, nil
+}
+
+// decodeUTF8 reads a UTF-8 pool entry: the UTF-16 length, the byte length
+// and the bytes, where lengths above 0x7f take two bytes
+func decodeUTF8(data []byte) (string, bool) {
+	length := func() (int, bool) {
+		if len(data) < 1 {
+			return 0, false
+		}
+		n := int(data[0])
+		if n&0x80 == 0 {
+			data = data[1:]
+			return n, true
+		}
+		if len(data) < 2 {
+			return 0, false
+		}
+		n = (n&0x7f)<<8 | int(data[1])
+		data = data[2:]
+		return n, true
+	}
+
+	if _, ok := length(); !ok {
+		return "", false
+	}
+	n, ok := length()
+	if !ok || n > len(data) {
+		return "", false
+	}
+	return string(data[:n]), true
+}
+
+// decodeUTF16 reads a UTF-16 pool entry: the length in code units, taking
+// two units above 0x7fff, followed by the units
+func decodeUTF16(data []byte) (string, bool) {
+	if len(data) < 2 {
+		return "", false
+	}
+	n := int(binary.LittleEndian.Uint16(data))
+	data = data[2:]
+	if n&0x8000 != 0 {
+		if len(data) < 2 {
+			return "", false
+		}
+		n = (n&0x7fff)<<16 | int(binary.LittleEndian.Uint16(data))
+		data = data[2:]
+	}
+	if n*2 > len(data) {
+		return "", false
+	}
+
+	units := make([]uint16, n)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(data[i*2:])
+	}
+	return string(utf16.Decode(units)), true
+}
@@ -0,0 +1,94 @@
+package axml
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Data types of a Res_value
+const (
+	typeNull      = 0x00
+	typeReference = 0x01
+	typeAttribute = 0x02
+	typeString    = 0x03
+	typeFloat     = 0x04
+	typeDimension = 0x05
+	typeFraction  = 0x06
+	typeIntDec    = 0x10
+	typeIntHex    = 0x11
+	typeBoolean   = 0x12
+	typeColorMin  = 0x1c
+	typeColorMax  = 0x1f
+)
+
+var (
+	dimensionUnits = []string{"px", "dp", "sp", "pt", "in", "mm"}
+	fractionUnits  = []string{"%", "%p"}
+
+	// complexMultipliers scale the mantissa of a complex value by its radix
+	complexMultipliers = []float64{
+		1.0 / (1 << 8),
+		1.0 / (1 << 15),
+		1.0 / (1 << 23),
+		1.0 / (1 << 31),
+	}
+)
+
+// maxReferenceDepth bounds the resolution of references to references
+const maxReferenceDepth = 8
+
+// formatValue renders a typed value as text, resolving references through
+// table when possible
+func formatValue(dataType uint8, data uint32, pool stringPool, table *Table) string {
+	return formatValueDepth(dataType, data, pool, table, 0)
+}
+
+func formatValueDepth(dataType uint8, data uint32, pool stringPool, table *Table, depth int) string {
+	switch {
+	case dataType == typeNull:
+		return ""
+	case dataType == typeReference:
+		if table != nil && depth < maxReferenceDepth {
+			if v, ok := table.lookup(data); ok {
+				return formatValueDepth(v.dataType, v.data, table.strings, table, depth+1)
+			}
+		}
+		if data == 0 {
+			return "@null"
+		}
+		return fmt.Sprintf("@0x%08x", data)
+	case dataType == typeAttribute:
+		return fmt.Sprintf("?0x%08x", data)
+	case dataType == typeString:
+		return pool.get(data)
+	case dataType == typeFloat:
+		return strconv.FormatFloat(float64(math.Float32frombits(data)), 'g', -1, 32)
+	case dataType == typeDimension:
+		return formatComplex(data, dimensionUnits)
+	case dataType == typeFraction:
+		return formatComplex(data, fractionUnits)
+	case dataType == typeIntDec:
+		return strconv.Itoa(int(int32(data)))
+	case dataType == typeIntHex:
+		return fmt.Sprintf("0x%x", data)
+	case dataType == typeBoolean:
+		return strconv.FormatBool(data != 0)
+	case dataType >= typeColorMin && dataType <= typeColorMax:
+		return fmt.Sprintf("#%08x", data)
+	}
+	return fmt.Sprintf("0x%08x (type %#02x)", data, dataType)
+}
+
+// formatComplex renders a dimension or fraction value
+func formatComplex(data uint32, units []string) string {
+	value := float64(int32(data&0xffffff00)) * complexMultipliers[(data>>4)&0x3]
+	unit := ""
+	if u := int(data & 0xf); u < len(units) {
+		unit = units[u]
+	}
+	if unit == "%" || unit == "%p" {
+		value *= 100
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64) + unit
+}
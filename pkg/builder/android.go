@@ -1,11 +1,15 @@
 package builder
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 
+	"github.com/velogo-dev/velo/pkg/config"
 	"github.com/velogo-dev/velo/pkg/utils"
 )
 
@@ -14,10 +18,32 @@ type Android struct {
 	RootDir     string
 	ShellDir    string
 	GradlewPath string
+	Config      *config.Config
+	// Release selects the signed release variant instead of debug
+	Release bool
+	// Format selects the artifact type, FormatAPK (default) or FormatAAB
+	Format string
+	// Environment is the name of the build environment, the default one when
+	// empty
+	Environment string
+	// DevServerURL is loaded by debug builds instead of the bundled web app
+	// when set
+	DevServerURL string
+	// DevCA is the PEM certificate of the CA of velo dev --https, trusted
+	// by debug builds when set
+	DevCA []byte
+	// Changed lists the files GenerateProject rewrote or removed
+	Changed []string
 }
 
+// Android artifact formats
+const (
+	FormatAPK = "apk"
+	FormatAAB = "aab"
+)
+
 // NewAndroid creates a new Android builder
-func NewAndroid(rootDir string) *Android {
+func NewAndroid(rootDir string, cfg *config.Config) *Android {
 	shellDir := filepath.Join(rootDir, "mobile-shell", "android")
 	var gradlewPath string
 
@@ -31,7 +57,29 @@ func NewAndroid(rootDir string) *Android {
 		RootDir:     rootDir,
 		ShellDir:    shellDir,
 		GradlewPath: gradlewPath,
+		Config:      cfg,
+		Format:      FormatAPK,
+	}
+}
+
+// Variant returns the build variant selected by the Release flag
+func (a *Android) Variant() string {
+	if a.Release {
+		return "release"
 	}
+	return "debug"
+}
+
+// task returns the Gradle task producing the selected variant and format
+func (a *Android) task() string {
+	task := "assemble"
+	if a.Format == FormatAAB {
+		task = "bundle"
+	}
+	if a.Release {
+		return task + "Release"
+	}
+	return task + "Debug"
 }
 
 // Build builds the Android app
@@ -42,55 +90,128 @@ func (a *Android) Build() error {
 		return fmt.Errorf("Android build tools not found. Make sure the Android project is set up correctly: %w", err)
 	}
 
-	return utils.RunCmdWithDir(a.ShellDir, a.GradlewPath, "assembleDebug")
+	if a.Format != FormatAPK && a.Format != FormatAAB {
+		return fmt.Errorf("unsupported Android format %q, expected %s or %s", a.Format, FormatAPK, FormatAAB)
+	}
+
+	if err := checkDevServer(a.Release, a.DevServerURL); err != nil {
+		return err
+	}
+	if err := a.GenerateProject(); err != nil {
+		return err
+	}
+
+	if !a.Release {
+		return utils.RunCmdWithDir(a.ShellDir, a.GradlewPath, a.task())
+	}
+
+	// Resolve credentials first so a misconfiguration fails before Gradle runs
+	signing, err := ResolveSigning(a.RootDir, a.Config.Android.Signing)
+	if err != nil {
+		return err
+	}
+	// The signing config of build.gradle would sign with the keystore from
+	// the environment, Gradle builds the unsigned artifact Velo signs instead
+	unset := []string{EnvKeystore, EnvKeystorePassword, EnvKeyAlias, EnvKeyPassword}
+	if err := utils.RunCmdWithoutEnv(a.ShellDir, unset, a.GradlewPath, a.task()); err != nil {
+		return err
+	}
+	_, err = a.signArtifact(signing)
+	return err
 }
 
-// InstallApp installs the app on the device
-func (a *Android) InstallApp(deviceID string) error {
-	fmt.Println("Installing Android app on device/emulator...")
+// FindArtifact locates the APK or AAB of the current variant, the one signed
+// by Velo when there is one
+func (a *Android) FindArtifact() (string, error) {
+	path, err := a.gradleOutput()
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(SignedName(path)); err == nil {
+		return SignedName(path), nil
+	}
+	return path, nil
+}
 
-	// Try both potential APK locations (old and new AGP paths)
-	apkPaths := []string{
-		filepath.Join(a.ShellDir, "app", "build", "outputs", "apk", "debug", "app-debug.apk"),
-		filepath.Join(a.ShellDir, "app", "build", "intermediates", "apk", "debug", "app-debug.apk"),
+// gradleOutput locates the APK or AAB produced by Gradle for the current
+// variant.
+//
+// For APKs it reads the output-metadata.json written by the Android Gradle
+// plugin and falls back to searching the variant output directory.
+func (a *Android) gradleOutput() (string, error) {
+	var (
+		path string
+		err  error
+	)
+	if a.Format == FormatAAB {
+		path, err = findFile(filepath.Join(a.ShellDir, "app", "build", "outputs", "bundle", a.Variant()), ".aab")
+	} else {
+		outputDir := filepath.Join(a.ShellDir, "app", "build", "outputs", "apk", a.Variant())
+		if path, err = readOutputMetadata(outputDir); err != nil {
+			path, err = findFile(outputDir, ".apk")
+		}
 	}
+	return path, err
+}
 
-	var apkPath string
-	var found bool
+// findFile returns the first file with the given extension below dir. Files
+// signed by Velo are skipped.
+func findFile(dir, ext string) (string, error) {
+	var found string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(path) == ext && !strings.HasSuffix(path, signedSuffix+ext) {
+			found = path
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to search for %s in %s: %w", ext, dir, err)
+	}
+	if found == "" {
+		return "", fmt.Errorf("no %s file found in %s", ext, dir)
+	}
+	return found, nil
+}
 
-	for _, path := range apkPaths {
+// readOutputMetadata returns the first output file listed in output-metadata.json
+func readOutputMetadata(outputDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, "output-metadata.json"))
+	if err != nil {
+		return "", err
+	}
+
+	var metadata struct {
+		Elements []struct {
+			OutputFile string `json:"outputFile"`
+		} `json:"elements"`
+	}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return "", err
+	}
+	for _, element := range metadata.Elements {
+		path := filepath.Join(outputDir, element.OutputFile)
 		if _, err := os.Stat(path); err == nil {
-			apkPath = path
-			found = true
-			break
+			return path, nil
 		}
 	}
+	return "", fmt.Errorf("no output listed in %s", outputDir)
+}
 
-	if !found {
-		// Let's look for any APK file
-		searchPath := filepath.Join(a.ShellDir, "app", "build")
-		fmt.Println("Searching for APK in:", searchPath)
-
-		err := filepath.Walk(searchPath, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if !info.IsDir() && filepath.Ext(path) == ".apk" {
-				apkPath = path
-				found = true
-				fmt.Println("Found APK at:", path)
-				return filepath.SkipAll
-			}
-			return nil
-		})
+// InstallApp installs the app on the device
+func (a *Android) InstallApp(deviceID string) error {
+	fmt.Println("Installing Android app on device/emulator...")
 
-		if err != nil {
-			fmt.Printf("Error searching for APK: %v\n", err)
-		}
+	if a.Format == FormatAAB {
+		return fmt.Errorf("app bundles cannot be installed directly, build with --format %s", FormatAPK)
 	}
 
-	if !found {
-		fmt.Println("No APK file found. Skipping installation for now.")
+	apkPath, err := a.FindArtifact()
+	if err != nil {
+		fmt.Printf("%v. Skipping installation for now.\n", err)
 		return nil // Return nil to avoid failing the entire process
 	}
 
@@ -109,18 +230,20 @@ func (a *Android) InstallApp(deviceID string) error {
 func (a *Android) LaunchApp(deviceID string) error {
 	fmt.Println("Launching Android app...")
 
+	component := a.component()
+
 	var launchArgs []string
 	if deviceID != "" {
 		// For specific device, the deviceID must come before the command
-		launchArgs = []string{"-s", deviceID, "shell", "am", "start", "-n", "com.example.golangmobile/.MainActivity"}
+		launchArgs = []string{"-s", deviceID, "shell", "am", "start", "-n", component}
 	} else {
-		launchArgs = []string{"shell", "am", "start", "-n", "com.example.golangmobile/.MainActivity"}
+		launchArgs = []string{"shell", "am", "start", "-n", component}
 	}
 
 	err := utils.RunCmd("adb", launchArgs...)
 	if err != nil {
 		fmt.Printf("Warning: Failed to launch app: %v\n", err)
-		fmt.Println("Development server is still running at http://localhost:3000")
+		fmt.Printf("Development server is still running at %s\n", a.Config.Dev.URL())
 		return nil // Return nil to avoid failing the entire process
 	}
 
@@ -141,3 +264,26 @@ func (a *Android) SetupPortForwarding(deviceID, port string) error {
 
 	return utils.RunCmd("adb", args...)
 }
+
+// Devices returns the serials of the devices and emulators adb lists as
+// online. Unauthorized and offline devices are left out.
+func (a *Android) Devices() ([]string, error) {
+	output, err := exec.Command("adb", "devices").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Android devices: %w", err)
+	}
+	return parseDevices(string(output)), nil
+}
+
+// parseDevices parses the output of `adb devices`, a header followed by
+// serial<TAB>state lines
+func parseDevices(output string) []string {
+	var devices []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == "device" {
+			devices = append(devices, fields[0])
+		}
+	}
+	return devices
+}
@@ -0,0 +1,95 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/velogo-dev/velo/pkg/utils"
+)
+
+const (
+	// devReloadHost is the host of the links telling a running debug build
+	// to load its web app again, e.g. velo-com.acme.app://reload
+	devReloadHost = "reload"
+	// pushedAssetsDir is the directory below the files directory of the
+	// Android app holding the assets pushed by velo dev --bundled
+	pushedAssetsDir = "velo-assets"
+	// pushedAssetsFolder is the folder below the Library directory of the iOS
+	// app holding the assets pushed by velo dev --bundled
+	pushedAssetsFolder = "VeloAssets"
+)
+
+// component returns the main activity of the app as package/class
+func (a *Android) component() string {
+	// The activity class lives in the source package, which can differ from
+	// the application ID
+	pkg := a.SourcePackage()
+	if pkg == "" {
+		pkg = a.Config.App.ID
+	}
+	return a.ApplicationID() + "/" + pkg + mainActivity
+}
+
+// PushAssets copies the web app in assetsDir to the running debug build on
+// the device and has it load them, without building and installing the app
+// again. Debug builds load pushed assets newer than their installation
+// instead of the bundled ones.
+func (a *Android) PushAssets(deviceID, assetsDir string) error {
+	adb := func(args ...string) error {
+		if deviceID != "" {
+			// The device must come before the command
+			args = append([]string{"-s", deviceID}, args...)
+		}
+		return utils.RunCmd("adb", args...)
+	}
+	pkg := a.ApplicationID()
+	// adb cannot write to the private files of the app, run-as copies the
+	// assets there from the shared temporary directory
+	staging := "/data/local/tmp/velo-assets-" + pkg
+	if err := adb("shell", "rm", "-rf", staging); err != nil {
+		return err
+	}
+	if err := adb("push", assetsDir, staging); err != nil {
+		return fmt.Errorf("failed to push the assets: %w", err)
+	}
+	target := "files/" + pushedAssetsDir
+	script := fmt.Sprintf("'mkdir -p files && rm -rf %s && cp -R %s %s'", target, staging, target)
+	if err := adb("shell", "run-as", pkg, "sh", "-c", script); err != nil {
+		return fmt.Errorf("failed to copy the assets into %s, is a debug build installed: %w", pkg, err)
+	}
+	// The activity receives the link of an explicit intent without an intent
+	// filter for it
+	return adb("shell", "am", "start", "-n", a.component(), "-d", DevLinkScheme(pkg)+"://"+devReloadHost)
+}
+
+// PushAssets copies the web app in assetsDir to the debug build installed on
+// the simulator, the booted one when deviceID is empty, and has it load them,
+// without building and installing the app again. Physical devices are not
+// supported.
+func (i *IOS) PushAssets(deviceID, assetsDir string) error {
+	if err := i.checkHost("Simulator asset pushes"); err != nil {
+		return err
+	}
+	if deviceID == "" {
+		deviceID = "booted"
+	}
+	output, err := exec.Command("xcrun", "simctl", "get_app_container", deviceID, i.BundleID(), "data").Output()
+	if err != nil {
+		return fmt.Errorf("no data container of %s on simulator %s: %w", i.BundleID(), deviceID, err)
+	}
+	target := filepath.Join(strings.TrimSpace(string(output)), "Library", pushedAssetsFolder)
+	if _, err := utils.SyncDir(assetsDir, target); err != nil {
+		return fmt.Errorf("failed to copy the assets: %w", err)
+	}
+	// The app compares the time of index.html with the installed one, which
+	// a sync leaves unchanged when its content is the same
+	now := time.Now()
+	if err := os.Chtimes(filepath.Join(target, "index.html"), now, now); err != nil {
+		return err
+	}
+	return i.Runner.Run("", "xcrun", "simctl", "openurl", deviceID, DevLinkScheme(i.BundleID())+"://"+devReloadHost)
+}
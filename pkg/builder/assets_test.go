@@ -0,0 +1,52 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/velogo-dev/velo/pkg/config"
+)
+
+// fakeADB puts an adb on PATH that logs its arguments, one call per line,
+// and returns the path of the log
+func fakeADB(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake adb is a shell script")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "adb.log")
+	script := "#!/bin/sh\necho \"$*\" >> " + log + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "adb"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return log
+}
+
+func TestAndroidPushAssets(t *testing.T) {
+	log := fakeADB(t)
+	cfg := config.Default()
+	cfg.App.ID = "com.acme.my_app"
+	android := NewAndroid(t.TempDir(), cfg)
+
+	if err := android.PushAssets("emulator-5554", "/project/mobile-shell/assets"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"-s emulator-5554 shell rm -rf /data/local/tmp/velo-assets-com.acme.my_app",
+		"-s emulator-5554 push /project/mobile-shell/assets /data/local/tmp/velo-assets-com.acme.my_app",
+		"-s emulator-5554 shell run-as com.acme.my_app sh -c 'mkdir -p files && rm -rf files/velo-assets && cp -R /data/local/tmp/velo-assets-com.acme.my_app files/velo-assets'",
+		"-s emulator-5554 shell am start -n com.acme.my_app/com.acme.my_app.MainActivity -d velo-com.acme.my-app://reload",
+	}
+	if got := strings.TrimSpace(string(data)); got != strings.Join(want, "\n") {
+		t.Errorf("adb calls =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,95 @@
+package builder
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// BundleInfo describes a validated Android App Bundle
+type BundleInfo struct {
+	Path    string
+	Size    int64
+	Modules []string
+	Entries int
+}
+
+// ValidateBundle checks that the .aab at bundlePath has the structure Google
+// Play expects: a BundleConfig.pb and a base module with a manifest, dex code
+// and compiled resources.
+func ValidateBundle(bundlePath string) (*BundleInfo, error) {
+	stat, err := os.Stat(bundlePath)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := zip.OpenReader(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid zip archive: %w", bundlePath, err)
+	}
+	defer reader.Close()
+
+	var (
+		hasConfig    bool
+		hasManifest  bool
+		hasDex       bool
+		hasResources bool
+		modules      = map[string]bool{}
+		order        []string
+	)
+
+	for _, file := range reader.File {
+		name := file.Name
+		if name == "BundleConfig.pb" {
+			hasConfig = true
+			continue
+		}
+
+		module, rest, ok := strings.Cut(name, "/")
+		if !ok || module == "META-INF" || module == "BUNDLE-METADATA" {
+			continue
+		}
+		if !modules[module] && (strings.HasPrefix(rest, "manifest/") || rest == "resources.pb") {
+			modules[module] = true
+			order = append(order, module)
+		}
+		if module != "base" {
+			continue
+		}
+
+		switch {
+		case rest == "manifest/AndroidManifest.xml":
+			hasManifest = true
+		case strings.HasPrefix(rest, "dex/") && path.Ext(rest) == ".dex":
+			hasDex = true
+		case rest == "resources.pb":
+			hasResources = true
+		}
+	}
+
+	var missing []string
+	if !hasConfig {
+		missing = append(missing, "BundleConfig.pb")
+	}
+	if !hasManifest {
+		missing = append(missing, "base/manifest/AndroidManifest.xml")
+	}
+	if !hasDex {
+		missing = append(missing, "base/dex/*.dex")
+	}
+	if !hasResources {
+		missing = append(missing, "base/resources.pb")
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("invalid app bundle %s, missing: %s", bundlePath, strings.Join(missing, ", "))
+	}
+
+	return &BundleInfo{
+		Path:    bundlePath,
+		Size:    stat.Size(),
+		Modules: order,
+		Entries: len(reader.File),
+	}, nil
+}
@@ -0,0 +1,336 @@
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/velogo-dev/velo/pkg/config"
+	"github.com/velogo-dev/velo/pkg/plist"
+)
+
+// EnvVar is a value of the build environment injected into the web and
+// native builds. Value is a string or a bool.
+type EnvVar struct {
+	Name  string
+	Value any
+}
+
+// String returns the value as written to environment variables
+func (v EnvVar) String() string {
+	if b, ok := v.Value.(bool); ok {
+		return strconv.FormatBool(b)
+	}
+	return fmt.Sprint(v.Value)
+}
+
+// EnvVars returns the values of the named environment: VELO_ENV, a
+// <NAME>_URL for every URL and a FEATURE_<NAME> for every feature flag.
+// Names defined by any environment are always present, with an empty URL or
+// a disabled flag, so native code referencing them builds for every
+// environment.
+func EnvVars(cfg *config.Config, name string) ([]EnvVar, error) {
+	env, err := cfg.Environment(name)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := map[string]bool{}
+	features := map[string]bool{}
+	for _, e := range cfg.Environments {
+		for key := range e.URLs {
+			urls[key] = true
+		}
+		for key := range e.Features {
+			features[key] = true
+		}
+	}
+
+	vars := []EnvVar{{"VELO_ENV", name}}
+	seen := map[string]string{"VELO_ENV": "VELO_ENV"}
+	add := func(key, varName string, value any) error {
+		if other, ok := seen[varName]; ok {
+			return fmt.Errorf("%s and %s of the environments both map to %s", other, key, varName)
+		}
+		seen[varName] = key
+		vars = append(vars, EnvVar{varName, value})
+		return nil
+	}
+	for _, key := range sortedKeys(urls) {
+		if err := add("urls."+key, envName(key)+"_URL", env.URLs[key]); err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range sortedKeys(features) {
+		if err := add("features."+key, "FEATURE_"+envName(key), env.Features[key]); err != nil {
+			return nil, err
+		}
+	}
+	return vars, nil
+}
+
+// envName converts a key such as newCheckout or new-checkout to NEW_CHECKOUT
+func envName(key string) string {
+	var b strings.Builder
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			b.WriteByte('_')
+			b.WriteRune(r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(unicode.ToUpper(r))
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// envPrefixes maps frontend dependencies to the prefix their build tool
+// requires for variables exposed to the browser, in order of precedence
+var envPrefixes = []struct {
+	dependency string
+	prefix     string
+}{
+	{"next", "NEXT_PUBLIC_"},
+	{"nuxt", "NUXT_PUBLIC_"},
+	{"expo", "EXPO_PUBLIC_"},
+	{"@sveltejs/kit", "PUBLIC_"},
+	{"react-scripts", "REACT_APP_"},
+	{"@vue/cli-service", "VUE_APP_"},
+	{"vite", "VITE_"},
+}
+
+// EnvPrefix returns the prefix of the environment variables the frontend's
+// build tool exposes to the app, VITE_ when it is not recognized
+func (f *Frontend) EnvPrefix() string {
+	deps := f.dependencies()
+	for _, candidate := range envPrefixes {
+		if deps[candidate.dependency] {
+			return candidate.prefix
+		}
+	}
+	return "VITE_"
+}
+
+// dependencies returns the names of the dependencies and dev dependencies in
+// the frontend's package.json
+func (f *Frontend) dependencies() map[string]bool {
+	deps := map[string]bool{}
+	data, err := os.ReadFile(filepath.Join(f.RootDir, "package.json"))
+	if err != nil {
+		return deps
+	}
+	var pkg struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return deps
+	}
+	for name := range pkg.Dependencies {
+		deps[name] = true
+	}
+	for name := range pkg.DevDependencies {
+		deps[name] = true
+	}
+	return deps
+}
+
+// SetEnvironment passes vars to the frontend build with the prefix of its
+// build tool, e.g. VITE_API_URL
+func (f *Frontend) SetEnvironment(vars []EnvVar) {
+	prefix := f.EnvPrefix()
+	f.Env = nil
+	for _, v := range vars {
+		f.Env = append(f.Env, prefix+v.Name+"="+v.String())
+	}
+}
+
+// environment returns the name and settings of the build environment
+func environment(cfg *config.Config, name string) (string, config.Environment, error) {
+	if name == "" {
+		name = config.DefaultEnvironment
+	}
+	env, err := cfg.Environment(name)
+	return name, env, err
+}
+
+// ApplicationID returns the application ID of the build environment
+func (a *Android) ApplicationID() string {
+	_, env, _ := environment(a.Config, a.Environment)
+	return a.Config.App.ID + env.AppIDSuffix
+}
+
+// VeloGradlePath returns the path of the Gradle script holding the values of
+// the build environment, applied from build.gradle
+func (a *Android) VeloGradlePath() string {
+	return filepath.Join(a.ShellDir, "app", "velo.gradle")
+}
+
+// RenderVeloGradle returns velo.gradle for the build environment. It adds
+// the application ID suffix, the app label, a BuildConfig field for every
+// value of the environment and the dev server of debug builds, and bundles
+// mobile-shell/assets as the app's assets.
+func (a *Android) RenderVeloGradle() ([]byte, error) {
+	name, env, err := environment(a.Config, a.Environment)
+	if err != nil {
+		return nil, err
+	}
+	vars, err := EnvVars(a.Config, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Generated by Velo for the %s environment, do not edit.\n", name)
+	b.WriteString("// Applied from build.gradle and rewritten by every velo build.\n")
+	b.WriteString("android {\n")
+	b.WriteString("    buildFeatures {\n        buildConfig true\n    }\n")
+	b.WriteString("    defaultConfig {\n")
+	if env.AppIDSuffix != "" {
+		fmt.Fprintf(&b, "        applicationIdSuffix %s\n", groovyString(env.AppIDSuffix))
+	}
+	fmt.Fprintf(&b, "        manifestPlaceholders.appLabel = %s\n", groovyString(a.Config.App.Name+env.NameSuffix))
+	for _, v := range vars {
+		if flag, ok := v.Value.(bool); ok {
+			fmt.Fprintf(&b, "        buildConfigField \"boolean\", %q, %q\n", v.Name, strconv.FormatBool(flag))
+		} else {
+			fmt.Fprintf(&b, "        buildConfigField \"String\", %q, %s\n", v.Name, groovyString(strconv.Quote(v.String())))
+		}
+	}
+	b.WriteString("    }\n")
+
+	// Only debug builds may load the dev server, release builds always ship
+	// the bundled web app copied to mobile-shell/assets
+	fmt.Fprintf(&b, `    buildTypes {
+        debug {
+            buildConfigField "String", "DEV_SERVER_URL", %s
+        }
+        release {
+            buildConfigField "String", "DEV_SERVER_URL", '""'
+        }
+    }
+    sourceSets {
+        main {
+            assets.srcDirs += ['../../assets']
+        }
+    }
+}
+`, groovyString(strconv.Quote(a.DevServerURL)))
+	return []byte(b.String()), nil
+}
+
+// checkDevServer fails release builds that would load the web app from a dev
+// server instead of the bundled assets
+func checkDevServer(release bool, url string) error {
+	if release && url != "" {
+		return fmt.Errorf("release builds load the bundled web app and cannot use the dev server at %s", url)
+	}
+	return nil
+}
+
+// groovyString quotes s as a single quoted Groovy string, which does not
+// interpolate $
+func groovyString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// BundleID returns the bundle identifier of the build environment
+func (i *IOS) BundleID() string {
+	_, env, _ := environment(i.Config, i.Environment)
+	return i.Config.App.ID + env.AppIDSuffix
+}
+
+// devLinkHost is the host of the dev links
+const devLinkHost = "dev"
+
+// DevLinkScheme returns the URL scheme of the dev links opened by the debug
+// builds of the app, e.g. velo-com.acme.app.staging. It is unique to the app
+// so that several Velo apps can be installed side by side. Characters a
+// scheme may not hold (RFC 3986), such as the _ of Android app IDs, become -.
+func DevLinkScheme(appID string) string {
+	return "velo-" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '+', r == '-', r == '.':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return '-'
+	}, appID)
+}
+
+// DevLink returns the link handing devServerURL to a debug build of the app,
+// which loads it instead of the dev server it was built with
+func DevLink(appID, devServerURL string) string {
+	return DevLinkScheme(appID) + "://" + devLinkHost + "?url=" + url.QueryEscape(devServerURL)
+}
+
+// XCConfigPath returns the path of the xcconfig file the target
+// configurations are based on
+func (i *IOS) XCConfigPath() string {
+	return filepath.Join(i.ShellDir, "Velo.xcconfig")
+}
+
+// EnvironmentPlistPath returns the path of the plist bundled with the values
+// of the build environment
+func (i *IOS) EnvironmentPlistPath() string {
+	return filepath.Join(i.ShellDir, "Environment.plist")
+}
+
+// RenderXCConfig returns Velo.xcconfig for the build environment. The target
+// appends VELO_APP_ID_SUFFIX to its bundle ID and Info.plist reads the
+// display name from VELO_APP_NAME, the dev server from VELO_DEV_SERVER_URL,
+// which is always empty for the Release configuration, and the scheme of the
+// dev links from VELO_DEV_LINK_SCHEME.
+func (i *IOS) RenderXCConfig() ([]byte, error) {
+	name, env, err := environment(i.Config, i.Environment)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Generated by Velo for the %s environment, do not edit.\n", name)
+	fmt.Fprintf(&b, "VELO_ENV = %s\n", name)
+	fmt.Fprintf(&b, "VELO_APP_ID_SUFFIX = %s\n", env.AppIDSuffix)
+	fmt.Fprintf(&b, "VELO_APP_NAME = %s\n", i.Config.App.Name+env.NameSuffix)
+	// xcconfig files treat // as the start of a comment
+	fmt.Fprintf(&b, "VELO_DEV_SERVER_URL = %s\n", strings.ReplaceAll(i.DevServerURL, "//", "/$()/"))
+	b.WriteString("VELO_DEV_SERVER_URL[config=Release] =\n")
+	fmt.Fprintf(&b, "VELO_DEV_LINK_SCHEME = %s\n", DevLinkScheme(i.BundleID()))
+	return []byte(b.String()), nil
+}
+
+// RenderEnvironmentPlist returns Environment.plist, mapping the names of
+// EnvVars to their values
+func (i *IOS) RenderEnvironmentPlist() ([]byte, error) {
+	name, _, err := environment(i.Config, i.Environment)
+	if err != nil {
+		return nil, err
+	}
+	vars, err := EnvVars(i.Config, name)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]any{}
+	for _, v := range vars {
+		values[v.Name] = v.Value
+	}
+	return plist.Marshal(values)
+}
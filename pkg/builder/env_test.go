@@ -0,0 +1,43 @@
+package builder
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+)
+
+// uriScheme is the scheme syntax of RFC 3986, section 3.1
+var uriScheme = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*$`)
+
+func TestDevLinkScheme(t *testing.T) {
+	tests := map[string]string{
+		"com.acme.app":         "velo-com.acme.app",
+		"com.Acme.App.staging": "velo-com.acme.app.staging",
+		"com.acme.my_app":      "velo-com.acme.my-app",
+		"com.acmé.app":         "velo-com.acm-.app",
+		"com.acme.app:dev":     "velo-com.acme.app-dev",
+	}
+	for appID, want := range tests {
+		got := DevLinkScheme(appID)
+		if got != want {
+			t.Errorf("DevLinkScheme(%q) = %q, want %q", appID, got, want)
+		}
+		if !uriScheme.MatchString(got) {
+			t.Errorf("DevLinkScheme(%q) = %q is not a valid URI scheme", appID, got)
+		}
+	}
+}
+
+func TestDevLink(t *testing.T) {
+	link := DevLink("com.acme.my_app", "http://192.168.1.20:3000/?a=1&b=2")
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("DevLink = %q: %v", link, err)
+	}
+	if u.Scheme != "velo-com.acme.my-app" || u.Host != devLinkHost {
+		t.Errorf("DevLink = %q", link)
+	}
+	if got := u.Query().Get("url"); got != "http://192.168.1.20:3000/?a=1&b=2" {
+		t.Errorf("DevLink url = %q", got)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 
+	"github.com/velogo-dev/velo/pkg/supervisor"
 	"github.com/velogo-dev/velo/pkg/utils"
 )
 
@@ -13,6 +15,8 @@ import (
 type Frontend struct {
 	RootDir   string
 	AssetsDir string
+	// Env holds extra KEY=value environment variables of the build
+	Env []string
 }
 
 // NewFrontend creates a new frontend builder
@@ -26,13 +30,55 @@ func NewFrontend(rootDir string) *Frontend {
 // Build builds the frontend for production
 func (f *Frontend) Build() error {
 	fmt.Println("Building frontend...")
-	return utils.RunCmdWithDir(f.RootDir, "npm", "run", "build")
+	return utils.RunCmdWithEnv(f.RootDir, f.Env, "npm", "run", "build")
 }
 
-// StartDevServer starts the development server
-func (f *Frontend) StartDevServer() error {
-	fmt.Println("Starting frontend dev server...")
-	return utils.RunCmdInBackground(f.RootDir, "npm", "run", "dev")
+// devServerFlags maps frontend dependencies to the flags making their dev
+// server listen on a port, in order of precedence. Vite moves to the next free
+// port when the configured one is taken unless --strictPort is given.
+// react-scripts only reads the PORT environment variable.
+var devServerFlags = []struct {
+	dependency string
+	flags      []string
+}{
+	{"next", []string{"--port"}},
+	{"nuxt", []string{"--port"}},
+	{"expo", []string{"--port"}},
+	{"@sveltejs/kit", []string{"--strictPort", "--port"}},
+	{"react-scripts", nil},
+	{"@vue/cli-service", []string{"--port"}},
+	{"vite", []string{"--strictPort", "--port"}},
+}
+
+// DevServerProcess returns `npm run dev` listening on port, to be run by a
+// supervisor.Supervisor. The process is critical and restarts when it stops
+// answering HTTP on url.
+func (f *Frontend) DevServerProcess(port int, url string) supervisor.Process {
+	args := []string{"run", "dev"}
+	deps := f.dependencies()
+	for _, candidate := range devServerFlags {
+		if deps[candidate.dependency] {
+			if candidate.flags != nil {
+				args = append(args, "--")
+				args = append(args, candidate.flags...)
+				args = append(args, strconv.Itoa(port))
+			}
+			break
+		}
+	}
+
+	// BROWSER=none keeps react-scripts from opening a browser tab
+	env := append([]string{"PORT=" + strconv.Itoa(port), "BROWSER=none"}, f.Env...)
+	return supervisor.Process{
+		Name:     "web",
+		Dir:      f.RootDir,
+		Command:  "npm",
+		Args:     args,
+		Env:      env,
+		Critical: true,
+		Restart:  true,
+		Health:   supervisor.HTTPCheck(url),
+	}
 }
 
 // CopyBuildToMobile copies the build output to mobile shell assets
@@ -54,6 +100,12 @@ func (f *Frontend) CopyBuildToMobile() error {
 	return utils.RunCmd("cp", "-r", src+"/.", f.AssetsDir)
 }
 
+// SyncBuildToMobile makes the mobile shell assets a copy of the build output,
+// removing files of earlier builds. It returns the number of files changed.
+func (f *Frontend) SyncBuildToMobile() (int, error) {
+	return utils.SyncDir(filepath.Join(f.RootDir, "dist"), f.AssetsDir)
+}
+
 // InstallDependencies installs all frontend dependencies
 func (f *Frontend) InstallDependencies() error {
 	fmt.Println("Installing frontend dependencies...")
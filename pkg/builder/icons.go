@@ -0,0 +1,344 @@
+package builder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/velogo-dev/velo/pkg/config"
+	"github.com/velogo-dev/velo/pkg/imaging"
+	"github.com/velogo-dev/velo/pkg/utils"
+	"github.com/velogo-dev/velo/pkg/xmltree"
+)
+
+// MinIconSize is the smallest accepted source icon, the size of the App
+// Store marketing icon
+const MinIconSize = 1024
+
+// androidDensities maps mipmap qualifiers to their scale over mdpi
+var androidDensities = []struct {
+	name  string
+	scale float64
+}{
+	{"mdpi", 1},
+	{"hdpi", 1.5},
+	{"xhdpi", 2},
+	{"xxhdpi", 3},
+	{"xxxhdpi", 4},
+}
+
+// iosIcon is an entry of AppIcon.appiconset/Contents.json
+type iosIcon struct {
+	idiom string
+	// size is in points
+	size  float64
+	scale int
+}
+
+var iosIcons = []iosIcon{
+	{"iphone", 20, 2}, {"iphone", 20, 3},
+	{"iphone", 29, 2}, {"iphone", 29, 3},
+	{"iphone", 40, 2}, {"iphone", 40, 3},
+	{"iphone", 60, 2}, {"iphone", 60, 3},
+	{"ipad", 20, 1}, {"ipad", 20, 2},
+	{"ipad", 29, 1}, {"ipad", 29, 2},
+	{"ipad", 40, 1}, {"ipad", 40, 2},
+	{"ipad", 76, 1}, {"ipad", 76, 2},
+	{"ipad", 83.5, 2},
+	{"ios-marketing", 1024, 1},
+}
+
+// Icons generates the app icons of every platform from one source image
+type Icons struct {
+	RootDir string
+	Config  *config.Config
+	Source  image.Image
+	// Background is the #RRGGBB color of the Android adaptive icon background
+	// layer and fills transparent areas of the iOS icons, which must be opaque
+	Background string
+}
+
+// Validate checks that the source can produce every icon
+func (ic *Icons) Validate() error {
+	bounds := ic.Source.Bounds()
+	if bounds.Dx() != bounds.Dy() {
+		return fmt.Errorf("the source icon must be square, got %dx%d", bounds.Dx(), bounds.Dy())
+	}
+	if bounds.Dx() < MinIconSize {
+		return fmt.Errorf("the source icon must be at least %dx%d, got %dx%d", MinIconSize, MinIconSize, bounds.Dx(), bounds.Dy())
+	}
+	if ic.Background == "" && !imaging.Opaque(ic.Source) {
+		return errors.New("the source icon has transparent pixels, which the App Store rejects for the iOS marketing icon; pass --background to fill them")
+	}
+	if ic.Background != "" {
+		if _, err := imaging.ParseHexColor(ic.Background); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Generate writes the icons of the Android and iOS shells and the web
+// frontend that exist in the project, and returns the written paths
+func (ic *Icons) Generate() ([]string, error) {
+	if err := ic.Validate(); err != nil {
+		return nil, err
+	}
+	background := "#FFFFFF"
+	if ic.Background != "" {
+		background = ic.Background
+	}
+	color, _ := imaging.ParseHexColor(background)
+	flat := imaging.Flatten(ic.Source, color)
+
+	var written []string
+	targets := []struct {
+		dir      string
+		generate func() ([]string, error)
+	}{
+		{NewAndroid(ic.RootDir, ic.Config).ShellDir, func() ([]string, error) { return ic.android(flat, background) }},
+		{NewIOS(ic.RootDir, ic.Config).ShellDir, func() ([]string, error) { return ic.ios(flat) }},
+		{filepath.Join(ic.RootDir, "frontend"), func() ([]string, error) { return ic.web(flat, background) }},
+	}
+	for _, target := range targets {
+		if _, err := os.Stat(target.dir); err != nil {
+			continue
+		}
+		paths, err := target.generate()
+		if err != nil {
+			return nil, err
+		}
+		written = append(written, paths...)
+	}
+	if len(written) == 0 {
+		return nil, fmt.Errorf("no Android shell, iOS shell or frontend found in %s", ic.RootDir)
+	}
+	return written, nil
+}
+
+// android writes the legacy and adaptive launcher icons of every density
+func (ic *Icons) android(flat image.Image, background string) ([]string, error) {
+	res := filepath.Join(NewAndroid(ic.RootDir, ic.Config).ShellDir, "app", "src", "main", "res")
+	var written []string
+	save := func(path string, img image.Image) error {
+		written = append(written, path)
+		return imaging.SavePNG(path, img)
+	}
+
+	for _, density := range androidDensities {
+		dir := filepath.Join(res, "mipmap-"+density.name)
+		size := int(math.Round(48 * density.scale))
+		legacy := imaging.Resize(flat, size, size)
+		if err := save(filepath.Join(dir, "ic_launcher.png"), legacy); err != nil {
+			return nil, err
+		}
+		if err := save(filepath.Join(dir, "ic_launcher_round.png"), imaging.CircleMask(legacy)); err != nil {
+			return nil, err
+		}
+
+		// Adaptive icon layers are 108dp, launchers only guarantee that the
+		// center 72dp are visible
+		canvas := int(math.Round(108 * density.scale))
+		foreground := imaging.Place(ic.Source, int(math.Round(72*density.scale)), canvas)
+		if err := save(filepath.Join(dir, "ic_launcher_foreground.png"), foreground); err != nil {
+			return nil, err
+		}
+	}
+
+	adaptive := `<?xml version="1.0" encoding="utf-8"?>
+<adaptive-icon xmlns:android="http://schemas.android.com/apk/res/android">
+    <background android:drawable="@color/ic_launcher_background" />
+    <foreground android:drawable="@mipmap/ic_launcher_foreground" />
+</adaptive-icon>
+`
+	files := map[string]string{
+		filepath.Join(res, "mipmap-anydpi-v26", "ic_launcher.xml"):       adaptive,
+		filepath.Join(res, "mipmap-anydpi-v26", "ic_launcher_round.xml"): adaptive,
+	}
+	for path, content := range files {
+		if err := writeFile(path, []byte(content)); err != nil {
+			return nil, err
+		}
+		written = append(written, path)
+	}
+
+	path, err := setColorResource(filepath.Join(res, "values"), "ic_launcher_background", background)
+	if err != nil {
+		return nil, err
+	}
+	written = append(written, path)
+	return written, nil
+}
+
+// setColorResource sets a color in values/colors.xml, or in the file of the
+// values directory that already defines it, so the resource is never
+// declared twice
+func setColorResource(valuesDir, name, value string) (string, error) {
+	path := filepath.Join(valuesDir, "colors.xml")
+	if files, err := filepath.Glob(filepath.Join(valuesDir, "*.xml")); err == nil {
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				continue
+			}
+			if doc, err := xmltree.Parse(data); err == nil && doc.Root.Find("color", "name", name) != nil {
+				path = file
+				break
+			}
+		}
+	}
+
+	doc := &xmltree.Document{Root: xmltree.NewElement("resources")}
+	if data, err := os.ReadFile(path); err == nil {
+		if doc, err = xmltree.Parse(data); err != nil {
+			return "", fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+		}
+	}
+	color := doc.Root.Find("color", "name", name)
+	if color == nil {
+		color = xmltree.NewElement("color", "name", name)
+		doc.Root.Append(color)
+	}
+	color.SetText(value)
+	return path, writeFile(path, doc.Encode())
+}
+
+// ios writes the AppIcon set of the shell's asset catalog
+func (ic *Icons) ios(flat image.Image) ([]string, error) {
+	catalog := filepath.Join(NewIOS(ic.RootDir, ic.Config).ShellDir, catalogDirectory)
+	set := filepath.Join(catalog, "AppIcon.appiconset")
+	var written []string
+
+	type entry struct {
+		Filename string `json:"filename"`
+		Idiom    string `json:"idiom"`
+		Scale    string `json:"scale"`
+		Size     string `json:"size"`
+	}
+	var images []entry
+	for _, icon := range iosIcons {
+		pixels := int(math.Round(icon.size * float64(icon.scale)))
+		name := "AppIcon-" + strconv.Itoa(pixels) + ".png"
+		path := filepath.Join(set, name)
+		if !contains(written, path) {
+			if err := imaging.SavePNG(path, imaging.Resize(flat, pixels, pixels)); err != nil {
+				return nil, err
+			}
+			written = append(written, path)
+		}
+		points := strconv.FormatFloat(icon.size, 'f', -1, 64)
+		images = append(images, entry{
+			Filename: name,
+			Idiom:    icon.idiom,
+			Scale:    strconv.Itoa(icon.scale) + "x",
+			Size:     points + "x" + points,
+		})
+	}
+
+	info := map[string]any{"author": "xcode", "version": 1}
+	contents, err := json.MarshalIndent(map[string]any{"images": images, "info": info}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(set, "Contents.json")
+	if err := writeFile(path, append(contents, '\n')); err != nil {
+		return nil, err
+	}
+	written = append(written, path)
+
+	if err := ensureCatalog(catalog); err != nil {
+		return nil, err
+	}
+	return written, nil
+}
+
+// web writes the favicon, the Apple touch icon and the PWA icons into the
+// public directory of the frontend and lists them in manifest.webmanifest
+func (ic *Icons) web(flat image.Image, background string) ([]string, error) {
+	public := filepath.Join(ic.RootDir, "frontend", "public")
+	var written []string
+	save := func(name string, img image.Image) error {
+		path := filepath.Join(public, filepath.FromSlash(name))
+		written = append(written, path)
+		return imaging.SavePNG(path, img)
+	}
+
+	favicon, err := imaging.EncodeICO(
+		imaging.Resize(ic.Source, 16, 16),
+		imaging.Resize(ic.Source, 32, 32),
+		imaging.Resize(ic.Source, 48, 48),
+	)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(public, "favicon.ico")
+	if err := writeFile(path, favicon); err != nil {
+		return nil, err
+	}
+	written = append(written, path)
+
+	if err := save("apple-touch-icon.png", imaging.Resize(flat, 180, 180)); err != nil {
+		return nil, err
+	}
+	if err := save("icons/icon-192.png", imaging.Resize(ic.Source, 192, 192)); err != nil {
+		return nil, err
+	}
+	if err := save("icons/icon-512.png", imaging.Resize(ic.Source, 512, 512)); err != nil {
+		return nil, err
+	}
+	// Maskable icons are cropped to a circle of 80% of their size
+	color, _ := imaging.ParseHexColor(background)
+	maskable := imaging.Flatten(imaging.Place(ic.Source, 410, 512), color)
+	if err := save("icons/icon-maskable-512.png", maskable); err != nil {
+		return nil, err
+	}
+
+	path = filepath.Join(public, "manifest.webmanifest")
+	manifest, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		manifest, err = json.Marshal(map[string]any{
+			"name":             ic.Config.App.Name,
+			"short_name":       ic.Config.App.Name,
+			"start_url":        ".",
+			"display":          "standalone",
+			"background_color": background,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	icons := []map[string]string{
+		{"src": "icons/icon-192.png", "sizes": "192x192", "type": "image/png"},
+		{"src": "icons/icon-512.png", "sizes": "512x512", "type": "image/png"},
+		{"src": "icons/icon-maskable-512.png", "sizes": "512x512", "type": "image/png", "purpose": "maskable"},
+	}
+	if manifest, err = utils.SetJSONValue(manifest, icons, "icons"); err != nil {
+		return nil, fmt.Errorf("failed to update manifest.webmanifest: %w", err)
+	}
+	if err := writeFile(path, manifest); err != nil {
+		return nil, err
+	}
+	return append(written, path), nil
+}
+
+// writeFile writes data to path, creating parent directories as needed
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/velogo-dev/velo/pkg/plist"
+)
+
+// iosOrientations maps orientation names to UIInterfaceOrientation values
+var iosOrientations = map[string][]string{
+	"portrait":             {"UIInterfaceOrientationPortrait"},
+	"portrait-upside-down": {"UIInterfaceOrientationPortraitUpsideDown"},
+	"landscape":            {"UIInterfaceOrientationLandscapeLeft", "UIInterfaceOrientationLandscapeRight"},
+	"landscape-left":       {"UIInterfaceOrientationLandscapeLeft"},
+	"landscape-right":      {"UIInterfaceOrientationLandscapeRight"},
+}
+
+// defaultOrientations are used when the config lists none
+var defaultOrientations = []string{"portrait", "landscape"}
+
+// InfoPlistPath returns the path of the shell's Info.plist
+func (i *IOS) InfoPlistPath() string {
+	return filepath.Join(i.ShellDir, "Info.plist")
+}
+
+// InfoPlist applies the project config to the keys of an existing Info.plist.
+// Keys Velo does not manage, such as the scene manifest or keys added by
+// hand, are kept as they are.
+func (i *IOS) InfoPlist(info map[string]any) (map[string]any, error) {
+	app := i.Config.App
+	if info == nil {
+		info = map[string]any{}
+	}
+
+	info["CFBundleIdentifier"] = "$(PRODUCT_BUNDLE_IDENTIFIER)"
+	info["CFBundleName"] = "$(PRODUCT_NAME)"
+	info["CFBundleExecutable"] = "$(EXECUTABLE_NAME)"
+	// The name with the suffix of the build environment, from Velo.xcconfig
+	info["CFBundleDisplayName"] = "$(VELO_APP_NAME)"
+	info["CFBundleShortVersionString"] = app.Version
+	info["CFBundleVersion"] = strconv.Itoa(app.Build)
+	info["CFBundleInfoDictionaryVersion"] = "6.0"
+	info["CFBundlePackageType"] = "APPL"
+	info["LSRequiresIPhoneOS"] = true
+	info["UILaunchStoryboardName"] = "LaunchScreen"
+	// Read by ViewController, which hides the launch screen once the web app
+	// is ready or after this many milliseconds
+	info["VeloSplashTimeout"] = splashTimeout(i.Config)
+	// The dev server loaded by debug builds, from Velo.xcconfig. The web app
+	// is loaded from the bundle when it is empty.
+	info["VeloDevServerURL"] = "$(VELO_DEV_SERVER_URL)"
+
+	// Usage descriptions of permissions that are no longer requested are
+	// removed, so the App Store review does not ask about them
+	for name, permission := range permissions {
+		key := permission.ios
+		if key == "" {
+			continue
+		}
+		if reason, ok := app.Permissions[name]; ok {
+			if reason == "" {
+				return nil, fmt.Errorf("app.permissions.%s needs a usage description for iOS", name)
+			}
+			info[key] = reason
+		} else {
+			delete(info, key)
+		}
+	}
+	for name := range app.Permissions {
+		if _, ok := permissions[name]; !ok && !isAndroidPermission(name) {
+			return nil, fmt.Errorf("unknown permission %q in app.permissions, expected one of %s", name, strings.Join(PermissionNames(), ", "))
+		}
+	}
+
+	orientations := app.Orientations
+	if len(orientations) == 0 {
+		orientations = defaultOrientations
+	}
+	var values []any
+	seen := map[string]bool{}
+	for _, name := range orientations {
+		mapped, ok := iosOrientations[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown orientation %q in app.orientations", name)
+		}
+		for _, value := range mapped {
+			if !seen[value] {
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+	}
+	info["UISupportedInterfaceOrientations"] = values
+
+	var urlTypes []any
+	if len(app.URLSchemes) > 0 {
+		schemes := make([]any, len(app.URLSchemes))
+		for n, scheme := range app.URLSchemes {
+			schemes[n] = scheme
+		}
+		urlTypes = append(urlTypes, map[string]any{
+			"CFBundleURLName":    app.ID,
+			"CFBundleURLSchemes": schemes,
+		})
+	}
+	// The dev links of velo dev --lan, from Velo.xcconfig. Only Debug builds
+	// act on them.
+	urlTypes = append(urlTypes, map[string]any{
+		"CFBundleURLName":    app.ID + ".velo-dev",
+		"CFBundleURLSchemes": []any{"$(VELO_DEV_LINK_SCHEME)"},
+	})
+	info["CFBundleURLTypes"] = urlTypes
+
+	if modes := i.Config.IOS.BackgroundModes; len(modes) > 0 {
+		values := make([]any, len(modes))
+		for n, mode := range modes {
+			values[n] = mode
+		}
+		info["UIBackgroundModes"] = values
+	} else {
+		delete(info, "UIBackgroundModes")
+	}
+
+	i.removeMissingStoryboards(info)
+
+	// The exceptions of the dev server only go into Info-Debug.plist, those
+	// written here by earlier versions are removed
+	removeDevServerATS(info)
+	return info, nil
+}
+
+// removeMissingStoryboards removes the main storyboard of the app and of its
+// scenes when the shell has no such storyboard. The shell creates its window
+// in SceneDelegate, and earlier templates named a Main storyboard they did
+// not ship, which stops the app on launch.
+func (i *IOS) removeMissingStoryboards(info map[string]any) {
+	missing := func(name any) bool {
+		value, ok := name.(string)
+		if !ok {
+			return false
+		}
+		_, err := os.Stat(filepath.Join(i.ShellDir, value+".storyboard"))
+		return errors.Is(err, os.ErrNotExist)
+	}
+	if missing(info["UIMainStoryboardFile"]) {
+		delete(info, "UIMainStoryboardFile")
+	}
+	manifest, _ := info["UIApplicationSceneManifest"].(map[string]any)
+	configurations, _ := manifest["UISceneConfigurations"].(map[string]any)
+	for _, role := range configurations {
+		scenes, _ := role.([]any)
+		for _, scene := range scenes {
+			if scene, ok := scene.(map[string]any); ok && missing(scene["UISceneStoryboardFile"]) {
+				delete(scene, "UISceneStoryboardFile")
+			}
+		}
+	}
+}
+
+// allowDevServer adds the App Transport Security exceptions of the dev
+// server, served over plain HTTP from localhost or the LAN. Other exceptions
+// added by hand are kept.
+func allowDevServer(info map[string]any) {
+	ats, _ := info["NSAppTransportSecurity"].(map[string]any)
+	if ats == nil {
+		ats = map[string]any{}
+	}
+	ats["NSAllowsLocalNetworking"] = true
+	domains, _ := ats["NSExceptionDomains"].(map[string]any)
+	if domains == nil {
+		domains = map[string]any{}
+	}
+	domains["localhost"] = map[string]any{"NSExceptionAllowsInsecureHTTPLoads": true}
+	ats["NSExceptionDomains"] = domains
+	info["NSAppTransportSecurity"] = ats
+}
+
+// removeDevServerATS removes the exceptions added by allowDevServer,
+// dropping the dictionaries left empty
+func removeDevServerATS(info map[string]any) {
+	ats, ok := info["NSAppTransportSecurity"].(map[string]any)
+	if !ok {
+		return
+	}
+	delete(ats, "NSAllowsLocalNetworking")
+	if domains, ok := ats["NSExceptionDomains"].(map[string]any); ok {
+		delete(domains, "localhost")
+		if len(domains) == 0 {
+			delete(ats, "NSExceptionDomains")
+		}
+	}
+	if len(ats) == 0 {
+		delete(info, "NSAppTransportSecurity")
+	}
+}
+
+// DebugInfoPlistPath returns the path of the Info.plist of Debug builds,
+// generated from Info.plist
+func (i *IOS) DebugInfoPlistPath() string {
+	return filepath.Join(i.ShellDir, "Info-Debug.plist")
+}
+
+// RenderInfoPlists returns the Info.plist of the shell with the project config
+// applied, and Info-Debug.plist, the same with the App Transport Security
+// exceptions of the dev server, so that release builds never carry them. An
+// existing Info.plist keeps its format, XML or binary.
+func (i *IOS) RenderInfoPlists() (release, debug []byte, err error) {
+	var info map[string]any
+	format := plist.XMLFormat
+
+	data, err := os.ReadFile(i.InfoPlistPath())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+	if err == nil {
+		value, err := plist.Unmarshal(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read Info.plist: %w", err)
+		}
+		var ok bool
+		if info, ok = value.(map[string]any); !ok {
+			return nil, nil, errors.New("Info.plist is not a dictionary")
+		}
+		format = plist.DetectFormat(data)
+	}
+
+	info, err = i.InfoPlist(info)
+	if err != nil {
+		return nil, nil, err
+	}
+	if release, err = plist.MarshalFormat(info, format); err != nil {
+		return nil, nil, err
+	}
+	allowDevServer(info)
+	if debug, err = plist.MarshalFormat(info, format); err != nil {
+		return nil, nil, err
+	}
+	return release, debug, nil
+}
@@ -0,0 +1,168 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/velogo-dev/velo/pkg/config"
+	"github.com/velogo-dev/velo/pkg/plist"
+)
+
+func TestDevServerATSIsDebugOnly(t *testing.T) {
+	ios := NewIOS(t.TempDir(), config.Default())
+	if err := os.MkdirAll(ios.ShellDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// An Info.plist written by earlier versions, with an exception added by hand
+	existing := map[string]any{
+		"NSAppTransportSecurity": map[string]any{
+			"NSAllowsLocalNetworking": true,
+			"NSExceptionDomains": map[string]any{
+				"localhost":       map[string]any{"NSExceptionAllowsInsecureHTTPLoads": true},
+				"api.example.com": map[string]any{"NSExceptionMinimumTLSVersion": "TLSv1.2"},
+			},
+		},
+	}
+	data, err := plist.Marshal(existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ios.InfoPlistPath(), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	release, debug, err := ios.RenderInfoPlists()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ats := decodeATS(t, release)
+	if _, ok := ats["NSAllowsLocalNetworking"]; ok {
+		t.Error("Info.plist allows local networking")
+	}
+	domains, _ := ats["NSExceptionDomains"].(map[string]any)
+	if _, ok := domains["localhost"]; ok {
+		t.Error("Info.plist has the localhost exception")
+	}
+	if _, ok := domains["api.example.com"]; !ok {
+		t.Error("Info.plist lost the exception added by hand")
+	}
+
+	ats = decodeATS(t, debug)
+	if ats["NSAllowsLocalNetworking"] != true {
+		t.Error("Info-Debug.plist does not allow local networking")
+	}
+	domains, _ = ats["NSExceptionDomains"].(map[string]any)
+	if _, ok := domains["localhost"]; !ok {
+		t.Error("Info-Debug.plist has no localhost exception")
+	}
+	if _, ok := domains["api.example.com"]; !ok {
+		t.Error("Info-Debug.plist lost the exception added by hand")
+	}
+}
+
+func TestDevServerATSRemovesEmptyDictionaries(t *testing.T) {
+	info := map[string]any{}
+	allowDevServer(info)
+	removeDevServerATS(info)
+	if _, ok := info["NSAppTransportSecurity"]; ok {
+		t.Errorf("NSAppTransportSecurity left behind: %v", info["NSAppTransportSecurity"])
+	}
+}
+
+func TestMissingStoryboardsAreRemoved(t *testing.T) {
+	ios := NewIOS(t.TempDir(), config.Default())
+	if err := os.MkdirAll(ios.ShellDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Shells created from earlier templates name a Main storyboard they do not
+	// ship, the launch screen is there
+	info := map[string]any{
+		"UIMainStoryboardFile": "Main",
+		"UIApplicationSceneManifest": map[string]any{
+			"UISceneConfigurations": map[string]any{
+				"UIWindowSceneSessionRoleApplication": []any{
+					map[string]any{
+						"UISceneDelegateClassName": "$(PRODUCT_MODULE_NAME).SceneDelegate",
+						"UISceneStoryboardFile":    "Main",
+					},
+					map[string]any{
+						"UISceneStoryboardFile": "LaunchScreen",
+					},
+				},
+			},
+		},
+	}
+	if err := os.WriteFile(filepath.Join(ios.ShellDir, "LaunchScreen.storyboard"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := ios.InfoPlist(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := info["UIMainStoryboardFile"]; ok {
+		t.Error("UIMainStoryboardFile names a missing storyboard")
+	}
+	manifest := info["UIApplicationSceneManifest"].(map[string]any)
+	scenes := manifest["UISceneConfigurations"].(map[string]any)["UIWindowSceneSessionRoleApplication"].([]any)
+	scene := scenes[0].(map[string]any)
+	if _, ok := scene["UISceneStoryboardFile"]; ok {
+		t.Error("UISceneStoryboardFile names a missing storyboard")
+	}
+	if scene["UISceneDelegateClassName"] != "$(PRODUCT_MODULE_NAME).SceneDelegate" {
+		t.Errorf("scene delegate = %v", scene["UISceneDelegateClassName"])
+	}
+	if name := scenes[1].(map[string]any)["UISceneStoryboardFile"]; name != "LaunchScreen" {
+		t.Errorf("storyboard of the shell = %v, want LaunchScreen", name)
+	}
+}
+
+// The template must not name storyboards it does not ship, SceneDelegate
+// creates the window holding the ViewController that dev links go to
+func TestTemplateNamesShippedStoryboards(t *testing.T) {
+	dir := filepath.Join("..", "..", "platform", "ios")
+	data, err := os.ReadFile(filepath.Join(dir, "Info.plist"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := plist.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := value.(map[string]any)
+	ios := &IOS{ShellDir: dir}
+	before, err := plist.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ios.removeMissingStoryboards(info)
+	after, err := plist.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(before) != string(after) {
+		t.Errorf("template Info.plist names missing storyboards:\n%s", before)
+	}
+
+	delegate, err := os.ReadFile(filepath.Join(dir, "SceneDelegate.swift"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(delegate), "rootViewController = ViewController()") {
+		t.Error("SceneDelegate does not create the ViewController")
+	}
+}
+
+func decodeATS(t *testing.T, data []byte) map[string]any {
+	t.Helper()
+	value, err := plist.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, _ := value.(map[string]any)
+	ats, _ := info["NSAppTransportSecurity"].(map[string]any)
+	return ats
+}
@@ -0,0 +1,244 @@
+package builder
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/velogo-dev/velo/pkg/apksign"
+	"github.com/velogo-dev/velo/pkg/axml"
+)
+
+// ArtifactInfo describes the contents of an APK or app bundle
+type ArtifactInfo struct {
+	Path   string
+	Format string
+	Size   int64
+
+	Package     string
+	Label       string
+	VersionCode string
+	VersionName string
+	MinSDK      string
+	TargetSDK   string
+	Permissions []string
+	Activities  []Activity
+
+	Signing *apksign.Result
+	Sizes   []DirSize
+}
+
+// Activity is an activity declared in the manifest
+type Activity struct {
+	Name          string
+	Exported      string
+	IntentFilters []IntentFilter
+}
+
+// IntentFilter is an intent filter of an activity
+type IntentFilter struct {
+	Actions    []string
+	Categories []string
+	Data       []string
+}
+
+// DirSize is the size of the entries under a top-level directory
+type DirSize struct {
+	Name         string
+	Files        int
+	Compressed   uint64
+	Uncompressed uint64
+}
+
+// Inspect decodes the manifest, signatures and layout of an APK or .aab
+func Inspect(artifactPath string) (*ArtifactInfo, error) {
+	data, err := os.ReadFile(artifactPath)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid zip archive: %w", artifactPath, err)
+	}
+
+	info := &ArtifactInfo{Path: artifactPath, Format: FormatAPK, Size: int64(len(data))}
+	if strings.EqualFold(path.Ext(artifactPath), ".aab") {
+		info.Format = FormatAAB
+	}
+
+	manifest, err := readManifest(reader, info.Format)
+	if err != nil {
+		return nil, err
+	}
+	info.applyManifest(manifest)
+
+	if info.Signing, err = apksign.Verify(data); err != nil {
+		return nil, fmt.Errorf("failed to read signatures: %w", err)
+	}
+	info.Sizes = dirSizes(reader.File, info.Format)
+	return info, nil
+}
+
+// readManifest decodes the binary manifest of an APK or the protobuf manifest
+// of the base module of an app bundle
+func readManifest(reader *zip.Reader, format string) (*axml.Node, error) {
+	if format == FormatAAB {
+		data, err := readZipFile(reader, "base/manifest/AndroidManifest.xml")
+		if err != nil {
+			return nil, err
+		}
+		return axml.ParseProto(data)
+	}
+
+	data, err := readZipFile(reader, "AndroidManifest.xml")
+	if err != nil {
+		return nil, err
+	}
+	// The resource table resolves references such as @string/app_name
+	var table *axml.Table
+	if arsc, err := readZipFile(reader, "resources.arsc"); err == nil {
+		if table, err = axml.ParseTable(arsc); err != nil {
+			fmt.Printf("Warning: failed to parse resources.arsc: %v\n", err)
+		}
+	}
+	return axml.Parse(data, table)
+}
+
+// readZipFile returns the contents of the named entry
+func readZipFile(reader *zip.Reader, name string) ([]byte, error) {
+	file, err := reader.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// applyManifest fills the app details from the decoded manifest
+func (info *ArtifactInfo) applyManifest(manifest *axml.Node) {
+	info.Package = manifest.Attr("package")
+	info.VersionCode = manifest.Attr("versionCode")
+	info.VersionName = manifest.Attr("versionName")
+
+	for _, usesSDK := range manifest.Elements("uses-sdk") {
+		info.MinSDK = usesSDK.Attr("minSdkVersion")
+		info.TargetSDK = usesSDK.Attr("targetSdkVersion")
+	}
+	if info.MinSDK == "" {
+		info.MinSDK = "1"
+	}
+	if info.TargetSDK == "" {
+		info.TargetSDK = info.MinSDK
+	}
+
+	for _, name := range []string{"uses-permission", "uses-permission-sdk-23"} {
+		for _, permission := range manifest.Elements(name) {
+			info.Permissions = append(info.Permissions, permission.Attr("name"))
+		}
+	}
+
+	for _, application := range manifest.Elements("application") {
+		info.Label = application.Attr("label")
+		for _, name := range []string{"activity", "activity-alias"} {
+			for _, activity := range application.Elements(name) {
+				info.Activities = append(info.Activities, readActivity(activity, info.Package))
+			}
+		}
+	}
+}
+
+// readActivity reads an activity and its intent filters
+func readActivity(node *axml.Node, pkg string) Activity {
+	activity := Activity{Name: node.Attr("name"), Exported: node.Attr("exported")}
+	if strings.HasPrefix(activity.Name, ".") {
+		activity.Name = pkg + activity.Name
+	}
+
+	for _, filter := range node.Elements("intent-filter") {
+		var f IntentFilter
+		for _, action := range filter.Elements("action") {
+			f.Actions = append(f.Actions, action.Attr("name"))
+		}
+		for _, category := range filter.Elements("category") {
+			f.Categories = append(f.Categories, category.Attr("name"))
+		}
+		for _, data := range filter.Elements("data") {
+			f.Data = append(f.Data, formatIntentData(data))
+		}
+		activity.IntentFilters = append(activity.IntentFilters, f)
+	}
+	return activity
+}
+
+// formatIntentData renders a <data> element as a URI pattern
+func formatIntentData(data *axml.Node) string {
+	var uri string
+	if scheme := data.Attr("scheme"); scheme != "" {
+		uri = scheme + "://"
+	}
+	uri += data.Attr("host")
+	if port := data.Attr("port"); port != "" {
+		uri += ":" + port
+	}
+	for _, attr := range []string{"path", "pathPrefix", "pathPattern"} {
+		if value := data.Attr(attr); value != "" {
+			uri += value
+			if attr == "pathPrefix" {
+				uri += "*"
+			}
+		}
+	}
+	if mimeType := data.Attr("mimeType"); mimeType != "" {
+		uri = strings.TrimSpace(uri + " " + mimeType)
+	}
+	return uri
+}
+
+// dirSizes groups the entries by top-level directory. Dex files at the root
+// of an APK are grouped as dex, and the module prefix of bundle entries is
+// skipped so APKs and bundles show the same groups.
+func dirSizes(files []*zip.File, format string) []DirSize {
+	groups := map[string]*DirSize{}
+	for _, file := range files {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		name := file.Name
+		if format == FormatAAB {
+			if module, rest, ok := strings.Cut(name, "/"); ok && module != "META-INF" && module != "BUNDLE-METADATA" {
+				name = rest
+			}
+		}
+
+		group, _, nested := strings.Cut(name, "/")
+		if !nested && path.Ext(name) == ".dex" {
+			group = "dex"
+		}
+
+		size, ok := groups[group]
+		if !ok {
+			size = &DirSize{Name: group}
+			groups[group] = size
+		}
+		size.Files++
+		size.Compressed += file.CompressedSize64
+		size.Uncompressed += file.UncompressedSize64
+	}
+
+	out := make([]DirSize, 0, len(groups))
+	for _, size := range groups {
+		out = append(out, *size)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Compressed != out[j].Compressed {
+			return out[i].Compressed > out[j].Compressed
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
@@ -1,91 +1,253 @@
 package builder
 
 import (
+	"bytes"
 	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 
+	"github.com/velogo-dev/velo/pkg/config"
+	"github.com/velogo-dev/velo/pkg/plist"
 	"github.com/velogo-dev/velo/pkg/utils"
+	"github.com/velogo-dev/velo/pkg/xcode"
 )
 
+// IOSProjectName is the name of the Xcode target, product and scheme
+const IOSProjectName = "GolangMobile"
+
 // IOS represents the iOS app builder
 type IOS struct {
 	RootDir          string
 	ShellDir         string
 	XcodeProjectPath string
 	BuildPath        string
+	Config           *config.Config
+	// Release builds archive the app and export a signed .ipa
+	Release bool
+	// Environment is the name of the build environment, the default one when
+	// empty
+	Environment string
+	// DevServerURL is loaded by debug builds instead of the bundled web app
+	// when set
+	DevServerURL string
+	Runner       utils.Runner
 }
 
 // NewIOS creates a new iOS builder
-func NewIOS(rootDir string) *IOS {
+func NewIOS(rootDir string, cfg *config.Config) *IOS {
 	shellDir := filepath.Join(rootDir, "mobile-shell", "ios")
 
 	return &IOS{
 		RootDir:          rootDir,
 		ShellDir:         shellDir,
-		XcodeProjectPath: filepath.Join(shellDir, "GolangMobile.xcodeproj"),
+		XcodeProjectPath: filepath.Join(shellDir, IOSProjectName+".xcodeproj"),
 		BuildPath:        filepath.Join(rootDir, "build"),
+		Config:           cfg,
+		Runner:           utils.CmdRunner{},
+	}
+}
+
+// Project describes the Xcode project of the shell from its files and the
+// project config
+func (i *IOS) Project() (*xcode.Project, error) {
+	project := &xcode.Project{
+		Name:             IOSProjectName,
+		BundleID:         i.Config.App.ID + "$(VELO_APP_ID_SUFFIX)",
+		DeploymentTarget: i.Config.IOS.DeploymentTarget,
+		InfoPlist:        "Info.plist",
+		// Debug builds get the App Transport Security exceptions of the dev
+		// server from their own Info.plist
+		DebugInfoPlist: filepath.Base(i.DebugInfoPlistPath()),
+		// Velo.xcconfig sets the bundle ID suffix and app name of the build
+		// environment, Environment.plist holds its values
+		BaseConfiguration: filepath.Base(i.XCConfigPath()),
+		Resources:         []string{filepath.Base(i.EnvironmentPlistPath())},
+		// The web build is copied to mobile-shell/assets and loaded from the
+		// assets directory of the bundle
+		Folders: []string{"../assets"},
+	}
+
+	entries, err := os.ReadDir(i.ShellDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read iOS shell: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		switch filepath.Ext(name) {
+		case ".swift":
+			project.Sources = append(project.Sources, name)
+		case ".storyboard", ".xcassets":
+			project.Resources = append(project.Resources, name)
+		}
+	}
+	if len(project.Sources) == 0 {
+		return nil, fmt.Errorf("no Swift sources found in %s", i.ShellDir)
+	}
+	sort.Strings(project.Sources)
+
+	// The app icon set is written by velo icons, the template has none
+	if info, err := os.Stat(filepath.Join(i.ShellDir, catalogDirectory, "AppIcon.appiconset")); err == nil && info.IsDir() {
+		project.AppIcon = "AppIcon"
+	}
+
+	if len(i.Config.IOS.Entitlements) > 0 {
+		project.Entitlements = IOSProjectName + ".entitlements"
+	}
+	return project, nil
+}
+
+// GenerateProject writes the Xcode project, its shared scheme, the Info.plist,
+// the entitlements file, the launch screen and the files of the build
+// environment. Files are only rewritten when their content changes.
+func (i *IOS) GenerateProject() error {
+	// The launch screen and asset catalog must exist to be listed as
+	// resources of the project
+	if err := i.GenerateSplash(); err != nil {
+		return err
+	}
+	project, err := i.Project()
+	if err != nil {
+		return err
+	}
+
+	infoPlist, debugInfoPlist, err := i.RenderInfoPlists()
+	if err != nil {
+		return err
+	}
+	xcconfig, err := i.RenderXCConfig()
+	if err != nil {
+		return err
+	}
+	environment, err := i.RenderEnvironmentPlist()
+	if err != nil {
+		return err
+	}
+
+	// Written in a fixed order, the project last so that Xcode picks up the
+	// files it lists
+	type file struct {
+		path    string
+		content []byte
+	}
+	files := []file{
+		{i.InfoPlistPath(), infoPlist},
+		{i.DebugInfoPlistPath(), debugInfoPlist},
+		{i.XCConfigPath(), xcconfig},
+		{i.EnvironmentPlistPath(), environment},
 	}
+	if project.Entitlements != "" {
+		entitlements, err := plist.Marshal(i.Config.IOS.Entitlements)
+		if err != nil {
+			return fmt.Errorf("invalid ios.entitlements: %w", err)
+		}
+		files = append(files, file{filepath.Join(i.ShellDir, project.Entitlements), entitlements})
+	}
+	files = append(files,
+		file{filepath.Join(i.XcodeProjectPath, filepath.FromSlash(project.SchemePath())), project.Scheme()},
+		file{filepath.Join(i.XcodeProjectPath, "project.pbxproj"), project.Generate()},
+	)
+
+	for _, f := range files {
+		if current, err := os.ReadFile(f.path); err == nil && bytes.Equal(current, f.content) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(f.path, f.content, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", filepath.Base(f.path), err)
+		}
+	}
+	return nil
 }
 
 // Build builds the iOS app
 func (i *IOS) Build() error {
 	fmt.Println("Building iOS app...")
 
-	if runtime.GOOS != "darwin" {
-		return fmt.Errorf("iOS builds are only supported on macOS")
+	if err := checkDevServer(i.Release, i.DevServerURL); err != nil {
+		return err
+	}
+	if err := i.GenerateProject(); err != nil {
+		return err
+	}
+	if err := i.checkHost("iOS builds"); err != nil {
+		return err
+	}
+
+	if i.Release {
+		if err := i.Archive(); err != nil {
+			return err
+		}
+		return i.Export()
+	}
+	// Simulator builds are not signed with the profile, a mismatch only
+	// matters for the release build
+	if err := i.CheckProfile(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 	}
 
-	return utils.RunCmd(
+	// Debug builds run on the simulator, InstallApp picks the app up from
+	// Debug-iphonesimulator
+	return i.Runner.Run("",
 		"xcodebuild",
 		"-project", i.XcodeProjectPath,
-		"-scheme", "GolangMobile",
+		"-scheme", IOSProjectName,
 		"-configuration", "Debug",
+		"-sdk", "iphonesimulator",
 		"-derivedDataPath", i.BuildPath,
 	)
 }
 
-// InstallApp installs the app on the simulator or device
-func (i *IOS) InstallApp(deviceID string) error {
-	fmt.Println("Installing iOS app on simulator/device...")
-
-	if runtime.GOOS != "darwin" {
-		return fmt.Errorf("iOS app installation is only supported on macOS")
+// checkHost fails on machines without Xcode. A custom Runner, such as a fake
+// checking the constructed commands, skips the check.
+func (i *IOS) checkHost(action string) error {
+	if _, ok := i.Runner.(utils.CmdRunner); ok && runtime.GOOS != "darwin" {
+		return fmt.Errorf("%s are only supported on macOS", action)
 	}
+	return nil
+}
 
-	args := []string{"simctl", "install"}
+// InstallApp installs the Debug build on the simulator, the booted one when
+// deviceID is empty
+func (i *IOS) InstallApp(deviceID string) error {
+	fmt.Println("Installing iOS app on the simulator...")
 
+	if err := i.checkHost("iOS app installations"); err != nil {
+		return err
+	}
 	if deviceID == "" {
-		args = append(args, "-simulator")
-	} else {
-		args = append(args, "-device", deviceID)
+		deviceID = "booted"
 	}
+	appPath := filepath.Join(i.BuildPath, "Build", "Products", "Debug-iphonesimulator", IOSProjectName+".app")
+	return i.Runner.Run("", "xcrun", "simctl", "install", deviceID, appPath)
+}
 
-	// Build path for the iOS app
-	appPath := filepath.Join(i.BuildPath, "Build", "Products", "Debug-iphonesimulator", "GolangMobile.app")
-	args = append(args, appPath)
-
-	return utils.RunCmd("xcrun", args...)
+// TrustRootCert adds a CA certificate to the trusted roots of the simulator,
+// the booted one when deviceID is empty. Physical devices install it from a
+// configuration profile instead.
+func (i *IOS) TrustRootCert(deviceID, certPath string) error {
+	if err := i.checkHost("Simulator root certificates"); err != nil {
+		return err
+	}
+	if deviceID == "" {
+		deviceID = "booted"
+	}
+	return i.Runner.Run("", "xcrun", "simctl", "keychain", deviceID, "add-root-cert", certPath)
 }
 
-// LaunchApp launches the app on the simulator or device
+// LaunchApp launches the app on the simulator, the booted one when deviceID
+// is empty
 func (i *IOS) LaunchApp(deviceID string) error {
 	fmt.Println("Launching iOS app...")
 
-	if runtime.GOOS != "darwin" {
-		return fmt.Errorf("iOS app launch is only supported on macOS")
+	if err := i.checkHost("iOS app launches"); err != nil {
+		return err
 	}
-
-	args := []string{"simctl", "launch"}
-
 	if deviceID == "" {
-		args = append(args, "-simulator")
-	} else {
-		args = append(args, "-device", deviceID)
+		deviceID = "booted"
 	}
-
-	// Bundle ID
-	args = append(args, "com.example.golangmobile")
-
-	return utils.RunCmd("xcrun", args...)
+	return i.Runner.Run("", "xcrun", "simctl", "launch", deviceID, i.BundleID())
 }
@@ -0,0 +1,182 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/velogo-dev/velo/pkg/config"
+	"github.com/velogo-dev/velo/pkg/plist"
+	"github.com/velogo-dev/velo/pkg/provisioning"
+)
+
+// iOS signing styles
+const (
+	SigningAutomatic = "automatic"
+	SigningManual    = "manual"
+)
+
+// ArchivePath returns the path of the .xcarchive produced by release builds
+func (i *IOS) ArchivePath() string {
+	return filepath.Join(i.BuildPath, "archive", IOSProjectName+".xcarchive")
+}
+
+// ExportPath returns the directory the .ipa is exported to
+func (i *IOS) ExportPath() string {
+	return filepath.Join(i.BuildPath, "export")
+}
+
+// ExportOptionsPath returns the path of the generated ExportOptions.plist
+func (i *IOS) ExportOptionsPath() string {
+	return filepath.Join(i.BuildPath, "ExportOptions.plist")
+}
+
+// validateSigning checks that the signing config is complete for a release
+func validateSigning(signing config.IOSSigning, bundleID string) error {
+	if signing.TeamID == "" {
+		return fmt.Errorf("ios.signing.teamId is required for release builds")
+	}
+	switch signing.Style {
+	case SigningAutomatic:
+	case SigningManual:
+		if signing.ProvisioningProfiles[bundleID] == "" {
+			return fmt.Errorf("manual signing requires a provisioning profile for %s in ios.signing.provisioningProfiles", bundleID)
+		}
+	default:
+		return fmt.Errorf("unsupported signing style %q, expected %s or %s", signing.Style, SigningAutomatic, SigningManual)
+	}
+	return nil
+}
+
+// CheckProfile verifies the provisioning profile configured for the bundle
+// ID, whatever the signing style, against the bundle ID, team, export method
+// and entitlements of the app, so signing problems are reported before
+// xcodebuild runs. Nothing is checked when no profile is configured.
+func (i *IOS) CheckProfile() error {
+	signing := i.Config.IOS.Signing
+	name := signing.ProvisioningProfiles[i.BundleID()]
+	if name == "" {
+		return nil
+	}
+	profile, err := provisioning.Find(name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Using provisioning profile %s (%s, expires %s)\n",
+		profile.Name, profile.Type(), profile.Expiration.Format("2006-01-02"))
+
+	return profile.Check(provisioning.Requirements{
+		BundleID:     i.BundleID(),
+		TeamID:       signing.TeamID,
+		ExportMethod: signing.ExportMethod,
+		Entitlements: i.Config.IOS.Entitlements,
+	}, time.Now())
+}
+
+// ArchiveArgs returns the xcodebuild arguments that archive the app
+func (i *IOS) ArchiveArgs() []string {
+	signing := i.Config.IOS.Signing
+	args := []string{
+		"-project", i.XcodeProjectPath,
+		"-scheme", IOSProjectName,
+		"-configuration", "Release",
+		"-destination", "generic/platform=iOS",
+		"-archivePath", i.ArchivePath(),
+		"-derivedDataPath", i.BuildPath,
+		"archive",
+		"DEVELOPMENT_TEAM=" + signing.TeamID,
+	}
+
+	if signing.Style == SigningManual {
+		args = append(args,
+			"CODE_SIGN_STYLE=Manual",
+			"PROVISIONING_PROFILE_SPECIFIER="+signing.ProvisioningProfiles[i.BundleID()],
+		)
+		if signing.Certificate != "" {
+			args = append(args, "CODE_SIGN_IDENTITY="+signing.Certificate)
+		}
+	} else {
+		// Let Xcode create and download the certificates and profiles it needs
+		args = append(args, "CODE_SIGN_STYLE=Automatic", "-allowProvisioningUpdates")
+	}
+	return args
+}
+
+// ExportArgs returns the xcodebuild arguments that export the .ipa
+func (i *IOS) ExportArgs() []string {
+	args := []string{
+		"-exportArchive",
+		"-archivePath", i.ArchivePath(),
+		"-exportPath", i.ExportPath(),
+		"-exportOptionsPlist", i.ExportOptionsPath(),
+	}
+	if i.Config.IOS.Signing.Style != SigningManual {
+		args = append(args, "-allowProvisioningUpdates")
+	}
+	return args
+}
+
+// ExportOptions returns the contents of the ExportOptions.plist passed to
+// xcodebuild -exportArchive
+func (i *IOS) ExportOptions() map[string]any {
+	signing := i.Config.IOS.Signing
+	options := map[string]any{
+		"method":       signing.ExportMethod,
+		"teamID":       signing.TeamID,
+		"signingStyle": signing.Style,
+		"destination":  "export",
+	}
+	if signing.ExportMethod == "app-store" {
+		options["uploadSymbols"] = true
+	}
+	if signing.Style == SigningManual {
+		options["provisioningProfiles"] = signing.ProvisioningProfiles
+		if signing.Certificate != "" {
+			options["signingCertificate"] = signing.Certificate
+		}
+	}
+	return options
+}
+
+// Archive builds the release .xcarchive
+func (i *IOS) Archive() error {
+	if err := validateSigning(i.Config.IOS.Signing, i.BundleID()); err != nil {
+		return err
+	}
+	if err := i.CheckProfile(); err != nil {
+		return err
+	}
+
+	fmt.Println("Archiving iOS app...")
+	// Start from a clean archive so a failed build cannot leave a stale one
+	if err := os.RemoveAll(i.ArchivePath()); err != nil {
+		return err
+	}
+	return i.Runner.Run("", "xcodebuild", i.ArchiveArgs()...)
+}
+
+// Export writes ExportOptions.plist and exports the archive to an .ipa
+func (i *IOS) Export() error {
+	data, err := plist.Marshal(i.ExportOptions())
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(i.BuildPath, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(i.ExportOptionsPath(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write ExportOptions.plist: %w", err)
+	}
+	if err := os.RemoveAll(i.ExportPath()); err != nil {
+		return err
+	}
+
+	fmt.Println("Exporting IPA...")
+	return i.Runner.Run("", "xcodebuild", i.ExportArgs()...)
+}
+
+// FindArtifact returns the path of the exported .ipa
+func (i *IOS) FindArtifact() (string, error) {
+	return findFile(i.ExportPath(), ".ipa")
+}
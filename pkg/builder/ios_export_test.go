@@ -0,0 +1,295 @@
+package builder
+
+import (
+	"encoding/asn1"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/velogo-dev/velo/pkg/config"
+	"github.com/velogo-dev/velo/pkg/plist"
+)
+
+const (
+	testTeam     = "ABCDE12345"
+	testBundleID = "com.acme.app"
+	testProfile  = "Acme Distribution"
+)
+
+// fakeRunner records the commands instead of running them
+type fakeRunner struct {
+	commands [][]string
+}
+
+func (r *fakeRunner) Run(dir, name string, args ...string) error {
+	r.commands = append(r.commands, append([]string{name}, args...))
+	return nil
+}
+
+func newTestIOS(t *testing.T, signing config.IOSSigning) (*IOS, *fakeRunner) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.App.ID = testBundleID
+	cfg.IOS.Signing = signing
+	cfg.IOS.Entitlements = nil
+	ios := NewIOS(t.TempDir(), cfg)
+	ios.Release = true
+	runner := &fakeRunner{}
+	ios.Runner = runner
+	return ios, runner
+}
+
+// installProfile writes a provisioning profile of the export method into a
+// temporary home, where provisioning.Find looks for installed profiles. The
+// CMS envelope carries no signature, which the decoder does not verify.
+func installProfile(t *testing.T, method string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	entitlements := map[string]any{"application-identifier": testTeam + "." + testBundleID}
+	profile := map[string]any{
+		"Name":           testProfile,
+		"UUID":           "11111111-2222-3333-4444-555555555555",
+		"TeamIdentifier": []any{testTeam},
+		"CreationDate":   time.Now().Add(-time.Hour),
+		"ExpirationDate": time.Now().AddDate(1, 0, 0),
+		"Entitlements":   entitlements,
+	}
+	switch method {
+	case "development":
+		entitlements["get-task-allow"] = true
+		profile["ProvisionedDevices"] = []any{"00008030-000000000000001E"}
+	case "ad-hoc":
+		profile["ProvisionedDevices"] = []any{"00008030-000000000000001E"}
+	case "enterprise":
+		profile["ProvisionsAllDevices"] = true
+	}
+	content, err := plist.Marshal(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(home, "Library", "MobileDevice", "Provisioning Profiles")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "acme.mobileprovision"), signedData(t, content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// signedData wraps content in a CMS SignedData envelope without signers
+func signedData(t *testing.T, content []byte) []byte {
+	t.Helper()
+	explicit := func(v any) asn1.RawValue {
+		der, err := asn1.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+	}
+	type encapContentInfo struct {
+		Type    asn1.ObjectIdentifier
+		Content asn1.RawValue
+	}
+	type signed struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		EncapContentInfo encapContentInfo
+		SignerInfos      asn1.RawValue
+	}
+	emptySet := asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true}
+	der, err := asn1.Marshal(struct {
+		Type    asn1.ObjectIdentifier
+		Content asn1.RawValue
+	}{
+		Type: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2},
+		Content: explicit(signed{
+			Version:          1,
+			DigestAlgorithms: emptySet,
+			EncapContentInfo: encapContentInfo{
+				Type:    asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1},
+				Content: explicit(content),
+			},
+			SignerInfos: emptySet,
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+var exportMethods = []string{"app-store", "ad-hoc", "enterprise", "development"}
+
+func TestArchiveAndExportAutomatic(t *testing.T) {
+	for _, method := range exportMethods {
+		t.Run(method, func(t *testing.T) {
+			ios, runner := newTestIOS(t, config.IOSSigning{
+				TeamID:       testTeam,
+				Style:        SigningAutomatic,
+				ExportMethod: method,
+			})
+			runArchiveAndExport(t, ios)
+
+			wantArchive := append(archiveBase(ios), "DEVELOPMENT_TEAM="+testTeam, "CODE_SIGN_STYLE=Automatic", "-allowProvisioningUpdates")
+			wantExport := append(exportBase(ios), "-allowProvisioningUpdates")
+			checkCommands(t, runner, wantArchive, wantExport)
+
+			want := map[string]any{
+				"method":       method,
+				"teamID":       testTeam,
+				"signingStyle": SigningAutomatic,
+				"destination":  "export",
+			}
+			if method == "app-store" {
+				want["uploadSymbols"] = true
+			}
+			checkExportOptions(t, ios, want)
+		})
+	}
+}
+
+func TestArchiveAndExportManual(t *testing.T) {
+	for _, method := range exportMethods {
+		t.Run(method, func(t *testing.T) {
+			installProfile(t, method)
+			ios, runner := newTestIOS(t, config.IOSSigning{
+				TeamID:               testTeam,
+				Style:                SigningManual,
+				ExportMethod:         method,
+				Certificate:          "Apple Distribution",
+				ProvisioningProfiles: map[string]string{testBundleID: testProfile},
+			})
+			runArchiveAndExport(t, ios)
+
+			wantArchive := append(archiveBase(ios),
+				"DEVELOPMENT_TEAM="+testTeam,
+				"CODE_SIGN_STYLE=Manual",
+				"PROVISIONING_PROFILE_SPECIFIER="+testProfile,
+				"CODE_SIGN_IDENTITY=Apple Distribution",
+			)
+			checkCommands(t, runner, wantArchive, exportBase(ios))
+
+			want := map[string]any{
+				"method":               method,
+				"teamID":               testTeam,
+				"signingStyle":         SigningManual,
+				"destination":          "export",
+				"provisioningProfiles": map[string]any{testBundleID: testProfile},
+				"signingCertificate":   "Apple Distribution",
+			}
+			if method == "app-store" {
+				want["uploadSymbols"] = true
+			}
+			checkExportOptions(t, ios, want)
+		})
+	}
+}
+
+func TestArchiveRejectsIncompleteSigning(t *testing.T) {
+	tests := map[string]config.IOSSigning{
+		"no team":        {Style: SigningAutomatic, ExportMethod: "app-store"},
+		"unknown style":  {TeamID: testTeam, Style: "magic", ExportMethod: "app-store"},
+		"manual without": {TeamID: testTeam, Style: SigningManual, ExportMethod: "app-store"},
+	}
+	for name, signing := range tests {
+		t.Run(name, func(t *testing.T) {
+			ios, runner := newTestIOS(t, signing)
+			if err := ios.Archive(); err == nil {
+				t.Fatal("Archive succeeded")
+			}
+			if len(runner.commands) > 0 {
+				t.Fatalf("xcodebuild ran: %v", runner.commands)
+			}
+		})
+	}
+}
+
+func runArchiveAndExport(t *testing.T, ios *IOS) {
+	t.Helper()
+	if err := ios.Archive(); err != nil {
+		t.Fatalf("Archive: %v", err)
+	}
+	if err := ios.Export(); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+}
+
+func archiveBase(ios *IOS) []string {
+	return []string{
+		"xcodebuild",
+		"-project", ios.XcodeProjectPath,
+		"-scheme", IOSProjectName,
+		"-configuration", "Release",
+		"-destination", "generic/platform=iOS",
+		"-archivePath", ios.ArchivePath(),
+		"-derivedDataPath", ios.BuildPath,
+		"archive",
+	}
+}
+
+func exportBase(ios *IOS) []string {
+	return []string{
+		"xcodebuild",
+		"-exportArchive",
+		"-archivePath", ios.ArchivePath(),
+		"-exportPath", ios.ExportPath(),
+		"-exportOptionsPlist", ios.ExportOptionsPath(),
+	}
+}
+
+func checkCommands(t *testing.T, runner *fakeRunner, want ...[]string) {
+	t.Helper()
+	if len(runner.commands) != len(want) {
+		t.Fatalf("ran %d commands, want %d: %v", len(runner.commands), len(want), runner.commands)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(runner.commands[i], want[i]) {
+			t.Errorf("command %d:\n got %s\nwant %s", i, strings.Join(runner.commands[i], " "), strings.Join(want[i], " "))
+		}
+	}
+}
+
+func checkExportOptions(t *testing.T, ios *IOS, want map[string]any) {
+	t.Helper()
+	data, err := os.ReadFile(ios.ExportOptionsPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := plist.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("ExportOptions.plist: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExportOptions.plist:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestArchiveChecksProfileForEverySigningStyle(t *testing.T) {
+	for _, style := range []string{SigningAutomatic, SigningManual} {
+		t.Run(style, func(t *testing.T) {
+			installProfile(t, "ad-hoc")
+			ios, runner := newTestIOS(t, config.IOSSigning{
+				TeamID:               testTeam,
+				Style:                style,
+				ExportMethod:         "app-store",
+				ProvisioningProfiles: map[string]string{testBundleID: testProfile},
+			})
+			err := ios.Archive()
+			if err == nil {
+				t.Fatal("Archive accepted an ad-hoc profile for an app-store export")
+			}
+			if !strings.Contains(err.Error(), "is an ad-hoc profile, but the export method is app-store") {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if len(runner.commands) > 0 {
+				t.Fatalf("xcodebuild ran: %v", runner.commands)
+			}
+		})
+	}
+}
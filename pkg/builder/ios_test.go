@@ -0,0 +1,69 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/velogo-dev/velo/pkg/config"
+)
+
+func TestSimulatorCommands(t *testing.T) {
+	ios, runner := newTestIOS(t, config.IOSSigning{})
+	ios.Release = false
+	if err := os.MkdirAll(ios.ShellDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(ios.ShellDir, "AppDelegate.swift"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ios.Build(); err != nil {
+		t.Fatal(err)
+	}
+	appPath := filepath.Join(ios.BuildPath, "Build", "Products", "Debug-iphonesimulator", IOSProjectName+".app")
+	for _, deviceID := range []string{"", "8A1F4C2E-5B7D-4E3A-9C6B-2D1E0F3A4B5C"} {
+		if err := ios.InstallApp(deviceID); err != nil {
+			t.Fatal(err)
+		}
+		if err := ios.LaunchApp(deviceID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkCommands(t, runner,
+		[]string{"xcodebuild", "-project", ios.XcodeProjectPath, "-scheme", IOSProjectName, "-configuration", "Debug", "-sdk", "iphonesimulator", "-derivedDataPath", ios.BuildPath},
+		[]string{"xcrun", "simctl", "install", "booted", appPath},
+		[]string{"xcrun", "simctl", "launch", "booted", testBundleID},
+		[]string{"xcrun", "simctl", "install", "8A1F4C2E-5B7D-4E3A-9C6B-2D1E0F3A4B5C", appPath},
+		[]string{"xcrun", "simctl", "launch", "8A1F4C2E-5B7D-4E3A-9C6B-2D1E0F3A4B5C", testBundleID},
+	)
+}
+
+func TestProjectAppIcon(t *testing.T) {
+	ios := NewIOS(t.TempDir(), config.Default())
+	if err := os.MkdirAll(filepath.Join(ios.ShellDir, catalogDirectory, "SplashBackground.colorset"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(ios.ShellDir, "AppDelegate.swift"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The catalog of the template holds the splash screen only
+	project, err := ios.Project()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.AppIcon != "" {
+		t.Errorf("AppIcon = %q without an app icon set", project.AppIcon)
+	}
+
+	if err := os.MkdirAll(filepath.Join(ios.ShellDir, catalogDirectory, "AppIcon.appiconset"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if project, err = ios.Project(); err != nil {
+		t.Fatal(err)
+	}
+	if project.AppIcon != "AppIcon" {
+		t.Errorf("AppIcon = %q, want AppIcon", project.AppIcon)
+	}
+}
@@ -0,0 +1,542 @@
+package builder
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/velogo-dev/velo/pkg/xmltree"
+)
+
+// Permissions the WebView shell always needs
+var basePermissions = []string{
+	"android.permission.INTERNET",
+	"android.permission.ACCESS_NETWORK_STATE",
+}
+
+const (
+	actionView        = "android.intent.action.VIEW"
+	categoryBrowsable = "android.intent.category.BROWSABLE"
+	categoryDefault   = "android.intent.category.DEFAULT"
+	mainActivity      = ".MainActivity"
+
+	splashScreenDependency = "androidx.core:core-splashscreen:1.0.1"
+)
+
+// ManifestPath returns the path of the shell's AndroidManifest.xml
+func (a *Android) ManifestPath() string {
+	return filepath.Join(a.ShellDir, "app", "src", "main", "AndroidManifest.xml")
+}
+
+// BuildGradlePath returns the path of the app module's build.gradle
+func (a *Android) BuildGradlePath() string {
+	return filepath.Join(a.ShellDir, "app", "build.gradle")
+}
+
+// StringsPath returns the path of the default string resources
+func (a *Android) StringsPath() string {
+	return filepath.Join(a.ShellDir, "app", "src", "main", "res", "values", "strings.xml")
+}
+
+// Permissions returns the manifest permissions requested by the app in
+// sorted order
+func (a *Android) Permissions() ([]string, error) {
+	wanted := map[string]bool{}
+	for _, name := range basePermissions {
+		wanted[name] = true
+	}
+	for name := range a.Config.App.Permissions {
+		if isAndroidPermission(name) {
+			wanted[name] = true
+			continue
+		}
+		permission, ok := permissions[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown permission %q in app.permissions, expected one of %s", name, strings.Join(PermissionNames(), ", "))
+		}
+		for _, p := range permission.android {
+			wanted[p] = true
+		}
+	}
+
+	out := make([]string, 0, len(wanted))
+	for name := range wanted {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// ScreenOrientation returns the android:screenOrientation of the main
+// activity, or "" when both portrait and landscape are supported
+func (a *Android) ScreenOrientation() (string, error) {
+	var portrait, landscape []string
+	for _, name := range a.Config.App.Orientations {
+		switch name {
+		case "portrait", "portrait-upside-down":
+			portrait = append(portrait, name)
+		case "landscape", "landscape-left", "landscape-right":
+			landscape = append(landscape, name)
+		default:
+			return "", fmt.Errorf("unknown orientation %q in app.orientations", name)
+		}
+	}
+
+	switch {
+	case len(portrait) > 0 && len(landscape) > 0, len(portrait)+len(landscape) == 0:
+		return "", nil
+	case len(portrait) > 0:
+		if len(portrait) > 1 || portrait[0] == "portrait-upside-down" {
+			return "sensorPortrait", nil
+		}
+		return "portrait", nil
+	}
+	if len(landscape) == 1 {
+		switch landscape[0] {
+		case "landscape-left":
+			return "landscape", nil
+		case "landscape-right":
+			return "reverseLandscape", nil
+		}
+	}
+	return "sensorLandscape", nil
+}
+
+// deepLinkFilters returns the intent filters for the configured URL schemes
+// and App Links
+func (a *Android) deepLinkFilters() ([]*xmltree.Element, error) {
+	var filters []*xmltree.Element
+	newFilter := func(attrs ...string) *xmltree.Element {
+		filter := xmltree.NewElement("intent-filter", attrs...)
+		filter.Append(
+			xmltree.NewElement("action", "android:name", actionView),
+			xmltree.NewElement("category", "android:name", categoryDefault),
+			xmltree.NewElement("category", "android:name", categoryBrowsable),
+		)
+		return filter
+	}
+
+	if schemes := a.Config.App.URLSchemes; len(schemes) > 0 {
+		filter := newFilter()
+		for _, scheme := range schemes {
+			filter.Append(xmltree.NewElement("data", "android:scheme", scheme))
+		}
+		filters = append(filters, filter)
+	}
+
+	for _, link := range a.Config.Android.DeepLinks {
+		u, err := url.Parse(link)
+		if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+			return nil, fmt.Errorf("invalid deep link %q in android.deepLinks, expected an http(s) URL", link)
+		}
+		// Only https links can be verified against assetlinks.json
+		var filter *xmltree.Element
+		if u.Scheme == "https" {
+			filter = newFilter("android:autoVerify", "true")
+		} else {
+			filter = newFilter()
+		}
+		data := xmltree.NewElement("data", "android:scheme", u.Scheme, "android:host", u.Host)
+		if u.Path != "" && u.Path != "/" {
+			data.SetAttr("android:pathPrefix", u.Path)
+		}
+		filter.Append(data)
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+// isDeepLinkFilter reports whether an intent filter handles browsable VIEW
+// intents. These filters are owned by Velo and replaced on every render.
+func isDeepLinkFilter(filter *xmltree.Element) bool {
+	return filter.Name == "intent-filter" &&
+		filter.Find("action", "android:name", actionView) != nil &&
+		filter.Find("category", "android:name", categoryBrowsable) != nil
+}
+
+// RenderManifest returns the shell's AndroidManifest.xml with the project
+// config applied. Elements and attributes Velo does not manage are kept.
+func (a *Android) RenderManifest() ([]byte, error) {
+	data, err := os.ReadFile(a.ManifestPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read AndroidManifest.xml: %w", err)
+	}
+	doc, err := xmltree.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AndroidManifest.xml: %w", err)
+	}
+	manifest := doc.Root
+
+	// The package is set by the Gradle namespace and applicationId
+	manifest.RemoveAttr("package")
+
+	// Permissions known to Velo that are no longer requested are removed,
+	// others added by hand are kept
+	wanted, err := a.Permissions()
+	if err != nil {
+		return nil, err
+	}
+	keep := map[string]bool{}
+	for _, name := range wanted {
+		keep[name] = true
+	}
+	managed := map[string]bool{}
+	for _, permission := range permissions {
+		for _, name := range permission.android {
+			managed[name] = true
+		}
+	}
+	manifest.Remove(func(e *xmltree.Element) bool {
+		name, _ := e.Attr("android:name")
+		return e.Name == "uses-permission" && managed[name] && !keep[name]
+	})
+	for _, name := range wanted {
+		if manifest.Find("uses-permission", "android:name", name) == nil {
+			manifest.InsertAfter("uses-permission", xmltree.NewElement("uses-permission", "android:name", name))
+		}
+	}
+
+	applications := manifest.Elements("application")
+	if len(applications) == 0 {
+		return nil, fmt.Errorf("AndroidManifest.xml has no <application> element")
+	}
+	application := applications[0]
+	// Plain HTTP is needed to reach the dev server, the placeholder is only
+	// true for debug builds
+	application.SetAttr("android:usesCleartextTraffic", "${usesCleartextTraffic}")
+	// The name with the suffix of the build environment, from velo.gradle
+	application.SetAttr("android:label", "${appLabel}")
+	// Launcher icons generated by velo assets icons
+	icon := filepath.Join(a.ShellDir, "app", "src", "main", "res", "mipmap-anydpi-v26", "ic_launcher.xml")
+	if _, err := os.Stat(icon); err == nil {
+		application.SetAttr("android:icon", "@mipmap/ic_launcher")
+		application.SetAttr("android:roundIcon", "@mipmap/ic_launcher_round")
+	}
+
+	activity := application.Find("activity", "android:name", mainActivity)
+	if activity == nil {
+		return nil, fmt.Errorf("AndroidManifest.xml has no %s activity", mainActivity)
+	}
+	// The splash screen theme generated by GenerateSplash
+	activity.SetAttr("android:theme", "@style/"+SplashTheme)
+	orientation, err := a.ScreenOrientation()
+	if err != nil {
+		return nil, err
+	}
+	if orientation != "" {
+		activity.SetAttr("android:screenOrientation", orientation)
+	} else {
+		activity.RemoveAttr("android:screenOrientation")
+	}
+
+	filters, err := a.deepLinkFilters()
+	if err != nil {
+		return nil, err
+	}
+	activity.Remove(isDeepLinkFilter)
+	activity.Append(filters...)
+
+	return doc.Encode(), nil
+}
+
+// gradleSetting matches a setting line of build.gradle, e.g. `minSdk 21`
+func gradleSetting(name string) *regexp.Regexp {
+	return regexp.MustCompile(`(?m)^([ \t]*)` + name + `\b.*$`)
+}
+
+// replaceFirst expands template for the first match of re only, so settings
+// repeated in product flavors keep their own values
+func replaceFirst(re *regexp.Regexp, content, template string) string {
+	match := re.FindStringSubmatchIndex(content)
+	if match == nil {
+		return content
+	}
+	expanded := re.ExpandString(nil, template, content, match)
+	return content[:match[0]] + string(expanded) + content[match[1]:]
+}
+
+// veloGradleApply applies velo.gradle to the app module when it exists
+const veloGradleApply = "if (file('velo.gradle').exists()) {\n    apply from: 'velo.gradle'\n}"
+
+// applyVeloGradle matches the unconditional apply of earlier shells
+var applyVeloGradle = regexp.MustCompile(`(?m)^[ \t]*apply\s+from:\s*['"]velo\.gradle['"][ \t]*\n?`)
+
+// RenderBuildGradle returns the app module's build.gradle with the app ID,
+// version and SDK levels from the project config. The rest of the file, such
+// as dependencies added by hand, is kept.
+func (a *Android) RenderBuildGradle() ([]byte, error) {
+	data, err := os.ReadFile(a.BuildGradlePath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read build.gradle: %w", err)
+	}
+	content := string(data)
+	cfg := a.Config
+
+	settings := [][2]string{
+		{"compileSdk", strconv.Itoa(cfg.Android.CompileSDK)},
+		{"applicationId", strconv.Quote(cfg.App.ID)},
+		{"minSdk", strconv.Itoa(cfg.Android.MinSDK)},
+		{"targetSdk", strconv.Itoa(cfg.Android.TargetSDK)},
+		{"versionCode", strconv.Itoa(cfg.App.Build)},
+		{"versionName", strconv.Quote(cfg.App.Version)},
+	}
+	// The namespace is the package of the Kotlin sources so that R and
+	// BuildConfig resolve, which may differ from the application ID
+	if pkg := a.SourcePackage(); pkg != "" {
+		settings = append(settings, [2]string{"namespace", "'" + pkg + "'"})
+	}
+	for _, setting := range settings {
+		re := gradleSetting(setting[0])
+		if !re.MatchString(content) {
+			return nil, fmt.Errorf("build.gradle has no %s setting", setting[0])
+		}
+		content = replaceFirst(re, content, "${1}"+setting[0]+" "+strings.ReplaceAll(setting[1], "$", "$$"))
+	}
+
+	// Older shells do not define the placeholder used by the manifest
+	if !strings.Contains(content, "manifestPlaceholders") {
+		content = replaceFirst(gradleSetting("versionName"), content,
+			"$0\n${1}manifestPlaceholders = [usesCleartextTraffic: \"false\", appLabel: \"@string/app_name\"]")
+		content = replaceFirst(regexp.MustCompile(`(?m)^([ \t]*)buildTypes\s*\{[ \t]*$`), content,
+			"$0\n${1}    debug {\n${1}        manifestPlaceholders.usesCleartextTraffic = \"true\"\n${1}    }")
+	}
+
+	// The shell keeps the splash screen up with the SplashScreen API
+	if !strings.Contains(content, "core-splashscreen") {
+		content = replaceFirst(regexp.MustCompile(`(?m)^([ \t]*)dependencies\s*\{[ \t]*$`), content,
+			"$0\n${1}    implementation '"+splashScreenDependency+"'")
+	}
+
+	// The manifest labels the app with the name from velo.gradle
+	if !strings.Contains(content, "appLabel:") {
+		content = replaceFirst(regexp.MustCompile(`(?m)^([ \t]*manifestPlaceholders\s*=\s*\[)`), content,
+			"${1}appLabel: \"@string/app_name\", ")
+	}
+
+	// Values of the build environment. velo.gradle is generated and ignored
+	// by git, so builds of a fresh checkout skip it.
+	if !strings.Contains(content, veloGradleApply) {
+		content = applyVeloGradle.ReplaceAllString(content, "")
+		content = strings.TrimRight(content, " \t\n") + "\n\n// Values of the build environment, generated by velo\n" + veloGradleApply + "\n"
+	}
+	return []byte(content), nil
+}
+
+// DebugManifestPath returns the path of the manifest merged into debug
+// builds only
+func (a *Android) DebugManifestPath() string {
+	return filepath.Join(a.ShellDir, "app", "src", "debug", "AndroidManifest.xml")
+}
+
+// Resources of debug builds trusting the CA of velo dev --https
+const (
+	devCAResource           = "velo_dev_ca"
+	networkSecurityResource = "velo_network_security_config"
+)
+
+// NetworkSecurityConfigPath returns the path of the network security config
+// of debug builds
+func (a *Android) NetworkSecurityConfigPath() string {
+	return filepath.Join(a.ShellDir, "app", "src", "debug", "res", "xml", networkSecurityResource+".xml")
+}
+
+// DevCAPath returns the path of the dev CA certificate in the raw resources
+// of debug builds
+func (a *Android) DevCAPath() string {
+	return filepath.Join(a.ShellDir, "app", "src", "debug", "res", "raw", devCAResource+".pem")
+}
+
+// RenderNetworkSecurityConfig returns the network security config of debug
+// builds when DevCA is set. It trusts the dev CA on top of the system CAs and
+// keeps cleartext traffic allowed, which the config overrides on Android 7
+// and later.
+func (a *Android) RenderNetworkSecurityConfig() []byte {
+	anchors := xmltree.NewElement("trust-anchors")
+	anchors.Append(
+		xmltree.NewElement("certificates", "src", "system"),
+		xmltree.NewElement("certificates", "src", "@raw/"+devCAResource),
+	)
+	base := xmltree.NewElement("base-config", "cleartextTrafficPermitted", "true")
+	base.Append(anchors)
+	config := xmltree.NewElement("network-security-config")
+	config.Append(base)
+
+	doc := &xmltree.Document{
+		Prolog: []xmltree.Node{xmltree.Comment(" Generated by Velo for velo dev --https, do not edit. ")},
+		Root:   config,
+	}
+	return doc.Encode()
+}
+
+// RenderDebugManifest returns the manifest merged into debug builds. It opens
+// the main activity for the dev links of velo dev --lan, which hand it the
+// URL of the dev server, so release builds never accept them. A single
+// activity instance receives the links of the running app. With DevCA set the
+// app uses the network security config trusting it.
+func (a *Android) RenderDebugManifest() ([]byte, error) {
+	filter := xmltree.NewElement("intent-filter")
+	filter.Append(
+		xmltree.NewElement("action", "android:name", actionView),
+		xmltree.NewElement("category", "android:name", categoryDefault),
+		xmltree.NewElement("category", "android:name", categoryBrowsable),
+		xmltree.NewElement("data", "android:scheme", DevLinkScheme(a.ApplicationID()), "android:host", devLinkHost),
+	)
+	activity := xmltree.NewElement("activity", "android:name", mainActivity, "android:launchMode", "singleTask")
+	activity.Append(filter)
+	application := xmltree.NewElement("application")
+	if len(a.DevCA) > 0 {
+		application.SetAttr("android:networkSecurityConfig", "@xml/"+networkSecurityResource)
+	}
+	application.Append(activity)
+	manifest := xmltree.NewElement("manifest", "xmlns:android", "http://schemas.android.com/apk/res/android")
+	manifest.Append(application)
+
+	doc := &xmltree.Document{
+		Prolog: []xmltree.Node{xmltree.Comment(" Generated by Velo for debug builds, do not edit. ")},
+		Root:   manifest,
+	}
+	return doc.Encode(), nil
+}
+
+// RenderStrings returns strings.xml with app_name set to the app name
+func (a *Android) RenderStrings() ([]byte, error) {
+	data, err := os.ReadFile(a.StringsPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read strings.xml: %w", err)
+	}
+	doc, err := xmltree.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse strings.xml: %w", err)
+	}
+
+	name := doc.Root.Find("string", "name", "app_name")
+	if name == nil {
+		name = xmltree.NewElement("string", "name", "app_name")
+		doc.Root.Append(name)
+	}
+	// Apostrophes must be escaped in Android string resources
+	name.SetText(strings.ReplaceAll(a.Config.App.Name, "'", `\'`))
+	return doc.Encode(), nil
+}
+
+// SourcePackage returns the package declared by the MainActivity source, or
+// "" when it cannot be found
+func (a *Android) SourcePackage() string {
+	var pkg string
+	root := filepath.Join(a.ShellDir, "app", "src", "main")
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if name := d.Name(); name != "MainActivity.kt" && name != "MainActivity.java" {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return nil
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if rest, ok := strings.CutPrefix(line, "package "); ok {
+				pkg = strings.TrimSuffix(strings.TrimSpace(rest), ";")
+				return filepath.SkipAll
+			}
+		}
+		return nil
+	})
+	return pkg
+}
+
+// generateDevCA writes the dev CA and the network security config trusting it
+// into the debug resources when DevCA is set, and removes them otherwise so
+// later debug builds stop trusting the CA
+func (a *Android) generateDevCA() error {
+	files := []struct {
+		path    string
+		content []byte
+	}{
+		{a.DevCAPath(), a.DevCA},
+		{a.NetworkSecurityConfigPath(), a.RenderNetworkSecurityConfig()},
+	}
+	for _, f := range files {
+		var err error
+		if len(a.DevCA) == 0 {
+			err = a.remove(f.path)
+		} else {
+			err = a.update(f.path, f.content)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// update writes a generated file of the shell when its content changed and
+// records it in Changed
+func (a *Android) update(path string, content []byte) error {
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, content) {
+		return nil
+	}
+	if err := writeFile(path, content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	a.Changed = append(a.Changed, path)
+	return nil
+}
+
+// remove deletes a generated file of the shell that is no longer wanted and
+// records it in Changed
+func (a *Android) remove(path string) error {
+	err := os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	a.Changed = append(a.Changed, path)
+	return nil
+}
+
+// GenerateProject renders the manifests, build.gradle, app name, splash
+// screen, velo.gradle and the dev CA of the shell from the project config.
+// Files are only rewritten when their content changes, in a fixed order so
+// that Changed lists them the same way on every run.
+func (a *Android) GenerateProject() error {
+	renderers := []struct {
+		path   string
+		render func() ([]byte, error)
+	}{
+		{a.ManifestPath(), a.RenderManifest},
+		{a.BuildGradlePath(), a.RenderBuildGradle},
+		{a.StringsPath(), a.RenderStrings},
+		{a.VeloGradlePath(), a.RenderVeloGradle},
+		{a.DebugManifestPath(), a.RenderDebugManifest},
+	}
+
+	for _, r := range renderers {
+		content, err := r.render()
+		if err != nil {
+			return err
+		}
+		if err := a.update(r.path, content); err != nil {
+			return err
+		}
+	}
+	if err := a.generateDevCA(); err != nil {
+		return err
+	}
+	return a.GenerateSplash()
+}
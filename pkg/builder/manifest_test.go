@@ -0,0 +1,108 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/velogo-dev/velo/pkg/config"
+)
+
+func TestRenderBuildGradleAppliesVeloGradleWhenItExists(t *testing.T) {
+	rootDir := t.TempDir()
+	copyTemplate(t, rootDir, "app/build.gradle")
+	android := NewAndroid(rootDir, config.Default())
+	template, err := os.ReadFile(android.BuildGradlePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A shell of an earlier version applies velo.gradle unconditionally and
+	// gets the app label from it only
+	old := strings.Replace(string(template), veloGradleApply, "apply from: 'velo.gradle'", 1)
+	old = strings.Replace(old, `, appLabel: "@string/app_name"`, "", 1)
+	if old == string(template) {
+		t.Fatal("the template does not apply velo.gradle as expected")
+	}
+
+	for name, content := range map[string]string{"template": string(template), "earlier shell": old} {
+		t.Run(name, func(t *testing.T) {
+			if err := os.WriteFile(android.BuildGradlePath(), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			rendered, err := android.RenderBuildGradle()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := string(rendered)
+			if n := strings.Count(got, "apply from: 'velo.gradle'"); n != 1 {
+				t.Errorf("velo.gradle is applied %d times:\n%s", n, got)
+			}
+			if !strings.Contains(got, veloGradleApply) {
+				t.Errorf("velo.gradle is applied without checking it exists:\n%s", got)
+			}
+			if !strings.Contains(got, `appLabel: "@string/app_name"`) {
+				t.Errorf("no default appLabel placeholder:\n%s", got)
+			}
+
+			// Rendering again changes nothing
+			if err := os.WriteFile(android.BuildGradlePath(), rendered, 0644); err != nil {
+				t.Fatal(err)
+			}
+			again, err := android.RenderBuildGradle()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != got {
+				t.Errorf("second render differs:\n%s", again)
+			}
+		})
+	}
+}
+
+func TestGenerateProjectChangedOrder(t *testing.T) {
+	var first []string
+	for run := 0; run < 5; run++ {
+		rootDir := t.TempDir()
+		copyTemplate(t, rootDir,
+			"app/build.gradle",
+			"app/src/main/AndroidManifest.xml",
+			"app/src/main/res/values/strings.xml",
+		)
+		cfg := config.Default()
+		cfg.App.ID = "com.acme.app"
+		cfg.App.Name = "Acme"
+		cfg.App.Version = "2.0.0"
+		android := NewAndroid(rootDir, cfg)
+		if err := android.GenerateProject(); err != nil {
+			t.Fatal(err)
+		}
+		var changed []string
+		for _, path := range android.Changed {
+			rel, err := filepath.Rel(rootDir, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			changed = append(changed, filepath.ToSlash(rel))
+		}
+		if run == 0 {
+			first = changed
+			continue
+		}
+		if strings.Join(changed, "\n") != strings.Join(first, "\n") {
+			t.Fatalf("Changed differs between runs:\n%s\nwant\n%s", strings.Join(changed, "\n"), strings.Join(first, "\n"))
+		}
+	}
+
+	// The main manifest of the template already matches the config
+	want := []string{
+		"mobile-shell/android/app/build.gradle",
+		"mobile-shell/android/app/src/main/res/values/strings.xml",
+		"mobile-shell/android/app/velo.gradle",
+		"mobile-shell/android/app/src/debug/AndroidManifest.xml",
+	}
+	if len(first) < len(want) || strings.Join(first[:len(want)], "\n") != strings.Join(want, "\n") {
+		t.Errorf("Changed starts with\n%s\nwant\n%s", strings.Join(first, "\n"), strings.Join(want, "\n"))
+	}
+}
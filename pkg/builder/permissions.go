@@ -0,0 +1,54 @@
+package builder
+
+import (
+	"sort"
+	"strings"
+)
+
+// permission describes how an entry of app.permissions is declared on each
+// platform
+type permission struct {
+	// ios is the Info.plist key holding the usage description. Permissions
+	// without a key need no declaration on iOS.
+	ios string
+	// android are the manifest permissions requested for it
+	android []string
+}
+
+// permissions are the names accepted in app.permissions. Android permissions
+// that are not listed can be requested by their full name, e.g.
+// android.permission.NFC.
+var permissions = map[string]permission{
+	"camera":          {ios: "NSCameraUsageDescription", android: []string{"android.permission.CAMERA"}},
+	"microphone":      {ios: "NSMicrophoneUsageDescription", android: []string{"android.permission.RECORD_AUDIO"}},
+	"location":        {ios: "NSLocationWhenInUseUsageDescription", android: []string{"android.permission.ACCESS_COARSE_LOCATION", "android.permission.ACCESS_FINE_LOCATION"}},
+	"location-always": {ios: "NSLocationAlwaysAndWhenInUseUsageDescription", android: []string{"android.permission.ACCESS_BACKGROUND_LOCATION"}},
+	"photos":          {ios: "NSPhotoLibraryUsageDescription", android: []string{"android.permission.READ_MEDIA_IMAGES"}},
+	"photos-add":      {ios: "NSPhotoLibraryAddUsageDescription"},
+	"contacts":        {ios: "NSContactsUsageDescription", android: []string{"android.permission.READ_CONTACTS"}},
+	"calendar":        {ios: "NSCalendarsUsageDescription", android: []string{"android.permission.READ_CALENDAR", "android.permission.WRITE_CALENDAR"}},
+	"bluetooth":       {ios: "NSBluetoothAlwaysUsageDescription", android: []string{"android.permission.BLUETOOTH_CONNECT", "android.permission.BLUETOOTH_SCAN"}},
+	"face-id":         {ios: "NSFaceIDUsageDescription", android: []string{"android.permission.USE_BIOMETRIC"}},
+	"motion":          {ios: "NSMotionUsageDescription", android: []string{"android.permission.ACTIVITY_RECOGNITION"}},
+	"speech":          {ios: "NSSpeechRecognitionUsageDescription", android: []string{"android.permission.RECORD_AUDIO"}},
+	"internet":        {android: []string{"android.permission.INTERNET"}},
+	"notifications":   {android: []string{"android.permission.POST_NOTIFICATIONS"}},
+	"vibrate":         {android: []string{"android.permission.VIBRATE"}},
+}
+
+// PermissionNames returns the permission names known to Velo in sorted order
+func PermissionNames() []string {
+	names := make([]string, 0, len(permissions))
+	for name := range permissions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isAndroidPermission reports whether name is a full Android permission name
+// rather than one of the names known to Velo
+func isAndroidPermission(name string) bool {
+	_, known := permissions[name]
+	return !known && strings.Contains(name, ".")
+}
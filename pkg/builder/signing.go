@@ -0,0 +1,183 @@
+package builder
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/velogo-dev/velo/pkg/apksign"
+	"github.com/velogo-dev/velo/pkg/config"
+	"github.com/velogo-dev/velo/pkg/keystore"
+	"github.com/velogo-dev/velo/pkg/utils"
+)
+
+// Environment variables holding the release signing credentials. They are also
+// read by the signing config in app/build.gradle for builds outside of Velo.
+const (
+	EnvKeystore         = "VELO_ANDROID_KEYSTORE"
+	EnvKeystorePassword = "VELO_ANDROID_KEYSTORE_PASSWORD"
+	EnvKeyAlias         = "VELO_ANDROID_KEY_ALIAS"
+	EnvKeyPassword      = "VELO_ANDROID_KEY_PASSWORD"
+)
+
+// SigningConfig holds the resolved credentials for a release build
+type SigningConfig struct {
+	StoreFile     string
+	StorePassword string
+	KeyAlias      string
+	KeyPassword   string
+}
+
+// ResolveSigning resolves the release signing credentials.
+//
+// Values come from the environment first and fall back to the properties file
+// configured in velo.json (keystore.properties by default).
+func ResolveSigning(rootDir string, cfg config.Signing) (*SigningConfig, error) {
+	props := map[string]string{}
+	propsPath := ""
+	if cfg.PropertiesFile != "" {
+		propsPath = filepath.Join(rootDir, cfg.PropertiesFile)
+		p, err := readProperties(propsPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read %s: %w", propsPath, err)
+		}
+		if err == nil {
+			props = p
+			if !utils.GitIsIgnored(rootDir, propsPath) {
+				fmt.Printf("Warning: %s is not gitignored, signing credentials may be committed\n", cfg.PropertiesFile)
+			}
+		}
+	}
+
+	lookup := func(env, key string) string {
+		if v := os.Getenv(env); v != "" {
+			return v
+		}
+		return props[key]
+	}
+
+	sc := &SigningConfig{
+		StoreFile:     lookup(EnvKeystore, "storeFile"),
+		StorePassword: lookup(EnvKeystorePassword, "storePassword"),
+		KeyAlias:      lookup(EnvKeyAlias, "keyAlias"),
+		KeyPassword:   lookup(EnvKeyPassword, "keyPassword"),
+	}
+	if sc.KeyPassword == "" {
+		// PKCS#12 keystores use the store password for the key as well
+		sc.KeyPassword = sc.StorePassword
+	}
+
+	var missing []string
+	if sc.StoreFile == "" {
+		missing = append(missing, EnvKeystore+" (storeFile)")
+	}
+	if sc.StorePassword == "" {
+		missing = append(missing, EnvKeystorePassword+" (storePassword)")
+	}
+	if sc.KeyAlias == "" {
+		missing = append(missing, EnvKeyAlias+" (keyAlias)")
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("release signing is not configured, missing: %s", strings.Join(missing, ", "))
+	}
+
+	// Relative keystore paths in the properties file are relative to that file
+	if !filepath.IsAbs(sc.StoreFile) {
+		base := rootDir
+		if os.Getenv(EnvKeystore) == "" && propsPath != "" {
+			base = filepath.Dir(propsPath)
+		}
+		sc.StoreFile = filepath.Join(base, sc.StoreFile)
+	}
+	if _, err := os.Stat(sc.StoreFile); err != nil {
+		return nil, fmt.Errorf("keystore not found: %w", err)
+	}
+
+	return sc, nil
+}
+
+// Signer loads the signing key and certificate chain from the keystore,
+// decrypting the key with the key password
+func (s *SigningConfig) Signer() (*apksign.Signer, error) {
+	entries, err := keystore.LoadWithKeyPassword(s.StoreFile, s.StorePassword, s.KeyPassword)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := keystore.Find(entries, s.KeyAlias)
+	if err != nil {
+		return nil, err
+	}
+	if entry.PrivateKey == nil {
+		return nil, fmt.Errorf("alias %q has no private key", s.KeyAlias)
+	}
+	return &apksign.Signer{PrivateKey: entry.PrivateKey, Certificates: entry.Certificates}, nil
+}
+
+// signArtifact signs the unsigned release artifact produced by Gradle. APKs
+// are aligned and signed with the schemes required by the app's minSdk, app
+// bundles get a JAR signature like jarsigner would produce.
+func (a *Android) signArtifact(signing *SigningConfig) (string, error) {
+	unsigned, err := a.gradleOutput()
+	if err != nil {
+		return "", err
+	}
+	signer, err := signing.Signer()
+	if err != nil {
+		return "", err
+	}
+
+	opts := apksign.DefaultOptions(a.Config.Android.MinSDK)
+	if a.Format == FormatAAB {
+		opts = apksign.Options{V1: true}
+	}
+
+	signed := SignedName(unsigned)
+	fmt.Printf("Signing %s...\n", filepath.Base(signed))
+	if err := apksign.SignFile(unsigned, signed, signer, opts); err != nil {
+		return "", err
+	}
+	return signed, nil
+}
+
+// signedSuffix marks artifacts signed by Velo whose Gradle output is not
+// named -unsigned, such as app bundles
+const signedSuffix = "-signed"
+
+// SignedName returns the path of the signed counterpart of an artifact built
+// by Gradle, which is never the artifact itself: app-release-unsigned.apk is
+// signed to app-release.apk and app-release.aab to app-release-signed.aab.
+func SignedName(path string) string {
+	dir, name := filepath.Split(path)
+	if strings.Contains(name, "-unsigned") {
+		return dir + strings.Replace(name, "-unsigned", "", 1)
+	}
+	ext := filepath.Ext(name)
+	return dir + strings.TrimSuffix(name, ext) + signedSuffix + ext
+}
+
+// readProperties parses a Java style .properties file
+func readProperties(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	props := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			key, value, _ = strings.Cut(line, ":")
+		}
+		props[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return props, scanner.Err()
+}
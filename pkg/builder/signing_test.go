@@ -0,0 +1,82 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/velogo-dev/velo/pkg/config"
+	"github.com/velogo-dev/velo/pkg/keystore"
+)
+
+func TestSignedName(t *testing.T) {
+	tests := map[string]string{
+		"app-release-unsigned.apk":             "app-release.apk",
+		"app-staging-release-unsigned.apk":     "app-staging-release.apk",
+		"app-release.aab":                      "app-release-signed.aab",
+		"app-release.apk":                      "app-release-signed.apk",
+		"out/release-unsigned/app-release.aab": "out/release-unsigned/app-release-signed.aab",
+	}
+	for path, want := range tests {
+		if got := SignedName(filepath.FromSlash(path)); got != filepath.FromSlash(want) {
+			t.Errorf("SignedName(%s) = %s, want %s", path, got, want)
+		}
+	}
+}
+
+func TestFindArtifactPrefersSignedOutput(t *testing.T) {
+	for _, format := range []string{FormatAPK, FormatAAB} {
+		t.Run(format, func(t *testing.T) {
+			android := NewAndroid(t.TempDir(), config.Default())
+			android.Format = format
+			android.Release = true
+
+			var dir, output string
+			if format == FormatAAB {
+				dir = filepath.Join(android.ShellDir, "app", "build", "outputs", "bundle", android.Variant())
+				output = filepath.Join(dir, "app-release.aab")
+			} else {
+				dir = filepath.Join(android.ShellDir, "app", "build", "outputs", "apk", android.Variant())
+				output = filepath.Join(dir, "app-release-unsigned.apk")
+			}
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			// With both artifacts present the signer still reads Gradle's
+			// output and never its own
+			for _, path := range []string{SignedName(output), output} {
+				if err := os.WriteFile(path, []byte("zip"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got, err := android.gradleOutput(); err != nil || got != output {
+				t.Errorf("gradleOutput() = %s, %v, want %s", got, err, output)
+			}
+			if got, err := android.FindArtifact(); err != nil || got != SignedName(output) {
+				t.Errorf("FindArtifact() = %s, %v, want %s", got, err, SignedName(output))
+			}
+		})
+	}
+}
+
+func TestSignerDecryptsWithKeyPassword(t *testing.T) {
+	entry, err := keystore.Generate(keystore.Options{Alias: "upload", DName: "CN=Test", ValidityDays: 1, KeyAlgorithm: keystore.EC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "release.keystore")
+	if err := keystore.Save(path, "store-secret", []keystore.Entry{*entry}); err != nil {
+		t.Fatal(err)
+	}
+
+	signing := &SigningConfig{StoreFile: path, StorePassword: "store-secret", KeyAlias: "upload", KeyPassword: "store-secret"}
+	if _, err := signing.Signer(); err != nil {
+		t.Fatal(err)
+	}
+	signing.KeyPassword = "other-secret"
+	if _, err := signing.Signer(); err == nil || !strings.Contains(err.Error(), "key password") {
+		t.Errorf("Signer() with the wrong key password: %v", err)
+	}
+}
@@ -0,0 +1,386 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/velogo-dev/velo/pkg/config"
+	"github.com/velogo-dev/velo/pkg/imaging"
+	"github.com/velogo-dev/velo/pkg/xmltree"
+)
+
+const (
+	// SplashTheme is the starting theme of the main activity
+	SplashTheme = "Theme.App.Starting"
+
+	// The SplashScreen API draws the icon on a 240dp canvas masked to a
+	// 160dp circle, the image is fitted in the square inside that circle
+	androidSplashCanvas = 240
+	androidSplashImage  = 112
+	// iosSplashImage is the largest side of the launch screen image in points
+	iosSplashImage = 160
+
+	splashImage      = "splash_image.png"
+	splashColor      = "SplashBackground"
+	splashImageSet   = "SplashImage"
+	defaultAppTheme  = "@style/Theme.MaterialComponents.Light.NoActionBar"
+	catalogDirectory = "Assets.xcassets"
+)
+
+// splashVariant is the look of the splash screen in light or dark mode
+type splashVariant struct {
+	background color.NRGBA
+	image      image.Image
+}
+
+// splashImageVariant is an image of the iOS SplashImage set, the dark one has
+// the Dark suffix
+type splashImageVariant struct {
+	suffix string
+	image  image.Image
+}
+
+// loadSplash returns the light variant of the configured splash screen and
+// the dark one, which is nil when no dark mode setting is given
+func loadSplash(rootDir string, splash config.Splash) (light, dark *splashVariant, err error) {
+	load := func(field, path string) (image.Image, error) {
+		if path == "" {
+			return nil, nil
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(rootDir, path)
+		}
+		img, err := imaging.Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid app.splash.%s: %w", field, err)
+		}
+		return img, nil
+	}
+	parse := func(field, value string) (color.NRGBA, error) {
+		c, err := imaging.ParseHexColor(value)
+		if err != nil {
+			return c, fmt.Errorf("invalid app.splash.%s: %w", field, err)
+		}
+		return c, nil
+	}
+
+	background := splash.Background
+	if background == "" {
+		background = "#FFFFFF"
+	}
+	light = &splashVariant{}
+	if light.background, err = parse("background", background); err != nil {
+		return nil, nil, err
+	}
+	if light.image, err = load("image", splash.Image); err != nil {
+		return nil, nil, err
+	}
+	if splash.DarkBackground == "" && splash.DarkImage == "" {
+		return light, nil, nil
+	}
+
+	dark = &splashVariant{background: light.background, image: light.image}
+	if splash.DarkBackground != "" {
+		if dark.background, err = parse("darkBackground", splash.DarkBackground); err != nil {
+			return nil, nil, err
+		}
+	}
+	if splash.DarkImage != "" {
+		if dark.image, err = load("darkImage", splash.DarkImage); err != nil {
+			return nil, nil, err
+		}
+	}
+	return light, dark, nil
+}
+
+// splashTimeout returns app.splash.timeout or its default
+func splashTimeout(cfg *config.Config) int {
+	if cfg.App.Splash.Timeout > 0 {
+		return cfg.App.Splash.Timeout
+	}
+	return config.Default().App.Splash.Timeout
+}
+
+// SplashPath returns the path of the splash screen resources generated by
+// Velo
+func (a *Android) SplashPath() string {
+	return filepath.Join(a.ShellDir, "app", "src", "main", "res", "values", "splash.xml")
+}
+
+// GenerateSplash writes the SplashScreen API theme, colors and images of
+// app.splash. The theme is applied to the main activity by RenderManifest.
+func (a *Android) GenerateSplash() error {
+	light, dark, err := loadSplash(a.RootDir, a.Config.App.Splash)
+	if err != nil {
+		return err
+	}
+	res := filepath.Join(a.ShellDir, "app", "src", "main", "res")
+
+	// The app theme the activity switches to once the splash is gone
+	appTheme := defaultAppTheme
+	if data, err := os.ReadFile(a.ManifestPath()); err == nil {
+		if doc, err := xmltree.Parse(data); err == nil {
+			for _, application := range doc.Root.Elements("application") {
+				if theme, ok := application.Attr("android:theme"); ok {
+					appTheme = theme
+				}
+			}
+		}
+	}
+
+	resources := xmltree.NewElement("resources")
+	background := xmltree.NewElement("color", "name", "splash_background")
+	background.SetText(imaging.HexColor(light.background))
+	timeout := xmltree.NewElement("integer", "name", "splash_timeout")
+	timeout.SetText(strconv.Itoa(splashTimeout(a.Config)))
+	style := xmltree.NewElement("style", "name", SplashTheme, "parent", "Theme.SplashScreen")
+	item := func(name, value string) {
+		e := xmltree.NewElement("item", "name", name)
+		e.SetText(value)
+		style.Append(e)
+	}
+	item("windowSplashScreenBackground", "@color/splash_background")
+	if light.image != nil {
+		item("windowSplashScreenAnimatedIcon", "@drawable/splash_image")
+	}
+	item("postSplashScreenTheme", appTheme)
+	resources.Append(background, timeout, style)
+	if err := a.update(a.SplashPath(), (&xmltree.Document{Root: resources}).Encode()); err != nil {
+		return err
+	}
+
+	night := filepath.Join(res, "values-night", "splash.xml")
+	if dark != nil {
+		resources := xmltree.NewElement("resources")
+		background := xmltree.NewElement("color", "name", "splash_background")
+		background.SetText(imaging.HexColor(dark.background))
+		resources.Append(background)
+		if err := a.update(night, (&xmltree.Document{Root: resources}).Encode()); err != nil {
+			return err
+		}
+	} else if err := a.remove(night); err != nil {
+		return err
+	}
+
+	// Images of a variant that is no longer configured are removed, the dark
+	// image is only written when it differs from the light one
+	var darkImage image.Image
+	if dark != nil && a.Config.App.Splash.DarkImage != "" {
+		darkImage = dark.image
+	}
+	for _, density := range androidDensities {
+		canvas := int(math.Round(androidSplashCanvas * density.scale))
+		size := int(math.Round(androidSplashImage * density.scale))
+		variants := map[string]image.Image{
+			"drawable-" + density.name:       light.image,
+			"drawable-night-" + density.name: darkImage,
+		}
+		for dir, img := range variants {
+			path := filepath.Join(res, dir, splashImage)
+			if img == nil {
+				if err := a.remove(path); err != nil {
+					return err
+				}
+				continue
+			}
+			data, err := imaging.EncodePNG(imaging.Place(img, size, canvas))
+			if err != nil {
+				return err
+			}
+			if err := a.update(path, data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// LaunchScreenPath returns the path of the launch screen storyboard
+func (i *IOS) LaunchScreenPath() string {
+	return filepath.Join(i.ShellDir, "LaunchScreen.storyboard")
+}
+
+// GenerateSplash writes the LaunchScreen storyboard of app.splash with its
+// background color and image in the shell's asset catalog. ViewController
+// keeps the launch screen on top of the web view until the web app is ready.
+func (i *IOS) GenerateSplash() error {
+	light, dark, err := loadSplash(i.RootDir, i.Config.App.Splash)
+	if err != nil {
+		return err
+	}
+	catalog := filepath.Join(i.ShellDir, catalogDirectory)
+	if err := ensureCatalog(catalog); err != nil {
+		return err
+	}
+
+	appearance := []map[string]string{{"appearance": "luminosity", "value": "dark"}}
+	components := func(c color.NRGBA) map[string]any {
+		return map[string]any{
+			"color-space": "srgb",
+			"components": map[string]string{
+				"red":   fmt.Sprintf("0x%02X", c.R),
+				"green": fmt.Sprintf("0x%02X", c.G),
+				"blue":  fmt.Sprintf("0x%02X", c.B),
+				"alpha": "1.000",
+			},
+		}
+	}
+	colors := []map[string]any{{"idiom": "universal", "color": components(light.background)}}
+	if dark != nil {
+		colors = append(colors, map[string]any{"idiom": "universal", "appearances": appearance, "color": components(dark.background)})
+	}
+	if err := writeCatalogContents(filepath.Join(catalog, splashColor+".colorset"), map[string]any{"colors": colors}); err != nil {
+		return err
+	}
+
+	set := filepath.Join(catalog, splashImageSet+".imageset")
+	var size image.Point
+	if light.image == nil {
+		if err := os.RemoveAll(set); err != nil {
+			return err
+		}
+	} else {
+		variants := []splashImageVariant{{"", light.image}}
+		if dark != nil && i.Config.App.Splash.DarkImage != "" {
+			variants = append(variants, splashImageVariant{"Dark", dark.image})
+		}
+
+		var images []map[string]any
+		keep := map[string]bool{"Contents.json": true}
+		for _, variant := range variants {
+			for scale := 1; scale <= 3; scale++ {
+				img := imaging.Fit(variant.image, iosSplashImage*scale)
+				if scale == 1 && variant.suffix == "" {
+					size = img.Bounds().Size()
+				}
+				name := fmt.Sprintf("%s%s@%dx.png", splashImageSet, variant.suffix, scale)
+				data, err := imaging.EncodePNG(img)
+				if err != nil {
+					return err
+				}
+				if err := updateFile(filepath.Join(set, name), data); err != nil {
+					return err
+				}
+				keep[name] = true
+
+				entry := map[string]any{"idiom": "universal", "filename": name, "scale": strconv.Itoa(scale) + "x"}
+				if variant.suffix != "" {
+					entry["appearances"] = appearance
+				}
+				images = append(images, entry)
+			}
+		}
+		if err := writeCatalogContents(set, map[string]any{"images": images}); err != nil {
+			return err
+		}
+
+		// Images of a previous configuration, such as a removed dark image,
+		// must not stay in the set
+		entries, err := os.ReadDir(set)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if !keep[entry.Name()] {
+				if err := os.Remove(filepath.Join(set, entry.Name())); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return updateFile(i.LaunchScreenPath(), launchScreen(light.background, size))
+}
+
+// launchScreen returns a storyboard filled with the SplashBackground color,
+// showing the SplashImage centered at its point size when size is not zero
+func launchScreen(background color.NRGBA, size image.Point) []byte {
+	var subviews, constraints, imageResource string
+	if size != (image.Point{}) {
+		subviews = `
+                        <subviews>
+                            <imageView userInteractionEnabled="NO" contentMode="scaleAspectFit" image="` + splashImageSet + `" translatesAutoresizingMaskIntoConstraints="NO" id="Spl-sh-Img"/>
+                        </subviews>`
+		constraints = `
+                        <constraints>
+                            <constraint firstItem="Spl-sh-Img" firstAttribute="centerX" secondItem="Ze5-6b-2t3" secondAttribute="centerX" id="Spl-sh-CtX"/>
+                            <constraint firstItem="Spl-sh-Img" firstAttribute="centerY" secondItem="Ze5-6b-2t3" secondAttribute="centerY" id="Spl-sh-CtY"/>
+                        </constraints>`
+		imageResource = fmt.Sprintf(`
+        <image name="%s" width="%d" height="%d"/>`, splashImageSet, size.X, size.Y)
+	}
+	component := func(v uint8) string {
+		return strconv.FormatFloat(float64(v)/255, 'f', 3, 64)
+	}
+
+	return []byte(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!-- Generated by Velo from app.splash in velo.json -->
+<document type="com.apple.InterfaceBuilder3.CocoaTouch.Storyboard.XIB" version="3.0" toolsVersion="21701" targetRuntime="iOS.CocoaTouch" propertyAccessControl="none" useAutolayout="YES" launchScreen="YES" useTraitCollections="YES" useSafeAreas="YES" colorMatched="YES" initialViewController="01J-lp-oVM">
+    <dependencies>
+        <plugIn identifier="com.apple.InterfaceBuilder.IBCocoaTouchPlugin" version="21679"/>
+        <capability name="Named colors" minToolsVersion="9.0"/>
+        <capability name="documents saved in the Xcode 8 format" minToolsVersion="8.0"/>
+    </dependencies>
+    <scenes>
+        <scene sceneID="EHf-IW-A2E">
+            <objects>
+                <viewController id="01J-lp-oVM" sceneMemberID="viewController">
+                    <view key="view" contentMode="scaleToFill" id="Ze5-6b-2t3">
+                        <rect key="frame" x="0.0" y="0.0" width="393" height="852"/>
+                        <autoresizingMask key="autoresizingMask" widthSizable="YES" heightSizable="YES"/>%s
+                        <color key="backgroundColor" name="%s"/>%s
+                    </view>
+                </viewController>
+                <placeholder placeholderIdentifier="IBFirstResponder" id="iYj-Kq-Ea1" userLabel="First Responder" sceneMemberID="firstResponder"/>
+            </objects>
+        </scene>
+    </scenes>
+    <resources>%s
+        <namedColor name="%s">
+            <color red="%s" green="%s" blue="%s" alpha="1" colorSpace="custom" customColorSpace="sRGB"/>
+        </namedColor>
+    </resources>
+</document>
+`, subviews, splashColor, constraints, imageResource, splashColor,
+		component(background.R), component(background.G), component(background.B)))
+}
+
+// ensureCatalog creates the Contents.json Xcode needs to recognize an asset
+// catalog
+func ensureCatalog(catalog string) error {
+	path := filepath.Join(catalog, "Contents.json")
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return writeCatalogContents(catalog, map[string]any{})
+}
+
+// writeCatalogContents writes the Contents.json of an asset catalog entry
+func writeCatalogContents(dir string, contents map[string]any) error {
+	contents["info"] = map[string]any{"author": "xcode", "version": 1}
+	data, err := json.MarshalIndent(contents, "", "  ")
+	if err != nil {
+		return err
+	}
+	return updateFile(filepath.Join(dir, "Contents.json"), append(data, '\n'))
+}
+
+// updateFile writes data to path unless the file already holds it, so build
+// tools do not see generated files as modified
+func updateFile(path string, data []byte) error {
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, data) {
+		return nil
+	}
+	if err := writeFile(path, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/velogo-dev/velo/pkg/config"
+	"github.com/velogo-dev/velo/pkg/plist"
+	"github.com/velogo-dev/velo/pkg/utils"
+)
+
+// Version parts accepted by BumpVersion
+const (
+	BumpMajor = "major"
+	BumpMinor = "minor"
+	BumpPatch = "patch"
+	BumpBuild = "build"
+)
+
+var versionCodePattern = regexp.MustCompile(`(?m)^[ \t]*versionCode\s+(\d+)`)
+
+// BumpVersion returns the semantic version after increasing part. Bumping the
+// build keeps the version as it is.
+func BumpVersion(version, part string) (string, error) {
+	fields := strings.Split(version, ".")
+	if len(fields) != 3 {
+		return "", fmt.Errorf("app.version %q is not a semantic version like 1.2.0", version)
+	}
+	var numbers [3]int
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("app.version %q is not a semantic version like 1.2.0", version)
+		}
+		numbers[i] = n
+	}
+
+	switch part {
+	case BumpMajor:
+		numbers = [3]int{numbers[0] + 1, 0, 0}
+	case BumpMinor:
+		numbers = [3]int{numbers[0], numbers[1] + 1, 0}
+	case BumpPatch:
+		numbers[2]++
+	case BumpBuild:
+	default:
+		return "", fmt.Errorf("unknown version part %q, expected %s, %s, %s or %s", part, BumpMajor, BumpMinor, BumpPatch, BumpBuild)
+	}
+	return fmt.Sprintf("%d.%d.%d", numbers[0], numbers[1], numbers[2]), nil
+}
+
+// HighestBuild returns the largest build number found in velo.json and the
+// platform files. The next build number is derived from it so the Android
+// versionCode and iOS CFBundleVersion always increase, even when one of the
+// files was edited by hand.
+func HighestBuild(rootDir string, cfg *config.Config) int {
+	highest := cfg.App.Build
+	consider := func(n int) {
+		if n > highest {
+			highest = n
+		}
+	}
+
+	android := NewAndroid(rootDir, cfg)
+	if data, err := os.ReadFile(android.BuildGradlePath()); err == nil {
+		if match := versionCodePattern.FindSubmatch(data); match != nil {
+			n, _ := strconv.Atoi(string(match[1]))
+			consider(n)
+		}
+	}
+
+	ios := NewIOS(rootDir, cfg)
+	if data, err := os.ReadFile(ios.InfoPlistPath()); err == nil {
+		if value, err := plist.Unmarshal(data); err == nil {
+			info, _ := value.(map[string]any)
+			version, _ := info["CFBundleVersion"].(string)
+			n, _ := strconv.Atoi(version)
+			consider(n)
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(rootDir, "frontend", "app.json")); err == nil {
+		var app struct {
+			Expo struct {
+				IOS struct {
+					BuildNumber string `json:"buildNumber"`
+				} `json:"ios"`
+				Android struct {
+					VersionCode int `json:"versionCode"`
+				} `json:"android"`
+			} `json:"expo"`
+		}
+		if json.Unmarshal(data, &app) == nil {
+			n, _ := strconv.Atoi(app.Expo.IOS.BuildNumber)
+			consider(n)
+			consider(app.Expo.Android.VersionCode)
+		}
+	}
+	return highest
+}
+
+// WriteVersion writes app.version and app.build of cfg to velo.json and to
+// every platform file holding a version, and returns the paths of every file
+// it rewrote or removed, including the files of the Android shell generated
+// again from the config. Files of shells or frontends the project does not
+// have are skipped.
+func WriteVersion(rootDir string, cfg *config.Config) ([]string, error) {
+	version, build := cfg.App.Version, cfg.App.Build
+	var written []string
+
+	edits := []struct {
+		path  string
+		value any
+		keys  []string
+	}{
+		{filepath.Join(rootDir, config.FileName), version, []string{"app", "version"}},
+		{filepath.Join(rootDir, config.FileName), build, []string{"app", "build"}},
+		{filepath.Join(rootDir, "frontend", "package.json"), version, []string{"version"}},
+		{filepath.Join(rootDir, "frontend", "app.json"), version, []string{"expo", "version"}},
+		{filepath.Join(rootDir, "frontend", "app.json"), strconv.Itoa(build), []string{"expo", "ios", "buildNumber"}},
+		{filepath.Join(rootDir, "frontend", "app.json"), build, []string{"expo", "android", "versionCode"}},
+	}
+	for _, edit := range edits {
+		data, err := os.ReadFile(edit.path)
+		// velo.json is the source of truth and is created when missing
+		if errors.Is(err, os.ErrNotExist) && filepath.Base(edit.path) != config.FileName {
+			continue
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		updated, err := utils.SetJSONValue(data, edit.value, edit.keys...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update %s: %w", filepath.Base(edit.path), err)
+		}
+		if bytes.Equal(updated, data) {
+			continue
+		}
+		if err := os.WriteFile(edit.path, updated, 0644); err != nil {
+			return nil, err
+		}
+		written = appendPath(written, edit.path)
+	}
+
+	android := NewAndroid(rootDir, cfg)
+	if _, err := os.Stat(android.BuildGradlePath()); err == nil {
+		if err := android.GenerateProject(); err != nil {
+			return nil, err
+		}
+		for _, path := range android.Changed {
+			written = appendPath(written, path)
+		}
+	}
+
+	ios := NewIOS(rootDir, cfg)
+	if _, err := os.Stat(ios.InfoPlistPath()); err == nil {
+		release, debug, err := ios.RenderInfoPlists()
+		if err != nil {
+			return nil, err
+		}
+		files := map[string][]byte{ios.InfoPlistPath(): release, ios.DebugInfoPlistPath(): debug}
+		for _, path := range []string{ios.InfoPlistPath(), ios.DebugInfoPlistPath()} {
+			if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, files[path]) {
+				continue
+			}
+			if err := os.WriteFile(path, files[path], 0644); err != nil {
+				return nil, err
+			}
+			written = append(written, path)
+		}
+	}
+	return written, nil
+}
+
+func appendPath(paths []string, path string) []string {
+	for _, p := range paths {
+		if p == path {
+			return paths
+		}
+	}
+	return append(paths, path)
+}
@@ -0,0 +1,98 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/velogo-dev/velo/pkg/config"
+)
+
+// copyTemplate copies the files of the Android template under
+// platform/android to the shell of the project in rootDir
+func copyTemplate(t *testing.T, rootDir string, files ...string) {
+	t.Helper()
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join("..", "..", "platform", "android", filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(rootDir, "mobile-shell", "android", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWriteVersion(t *testing.T) {
+	rootDir := t.TempDir()
+	copyTemplate(t, rootDir,
+		"app/build.gradle",
+		"app/src/main/AndroidManifest.xml",
+		"app/src/main/res/values/strings.xml",
+	)
+	packageJSON := "{\n    \"name\": \"web\",\n    \"version\": \"0.1.0\",\n    \"scripts\": {\"dev\": \"vite\"}\n}\n"
+	if err := os.MkdirAll(filepath.Join(rootDir, "frontend"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(rootDir, "frontend", "package.json"), []byte(packageJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	cfg.App.ID = "com.acme.app"
+	cfg.App.Version = "1.2.0"
+	cfg.App.Build = 7
+	written, err := WriteVersion(rootDir, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(rootDir, "frontend", "package.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(packageJSON, "0.1.0", "1.2.0", 1); string(data) != want {
+		t.Errorf("package.json =\n%s\nwant\n%s", data, want)
+	}
+
+	android := NewAndroid(rootDir, cfg)
+	for _, path := range []string{
+		filepath.Join(rootDir, config.FileName),
+		filepath.Join(rootDir, "frontend", "package.json"),
+		android.BuildGradlePath(),
+		android.VeloGradlePath(),
+		android.DebugManifestPath(),
+	} {
+		if !containsPath(written, path) {
+			t.Errorf("written does not list %s", path)
+		}
+	}
+	for _, path := range written {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("written lists %s: %v", path, err)
+		}
+	}
+
+	// Writing the same version again leaves every file as it is
+	written, err = WriteVersion(rootDir, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 0 {
+		t.Errorf("second write rewrote %q", written)
+	}
+}
+
+func containsPath(paths []string, path string) bool {
+	for _, p := range paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
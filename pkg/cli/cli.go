@@ -44,15 +44,25 @@ func (c *VeloCLI) Run() error {
 	case constants.InitCommand.Name:
 		return commands.NewCommand().InitCommand(context.Background(), os.Args[2:])
 	case constants.BuildCommand.Name:
-		return commands.NewCommand().BuildCommand()
+		return commands.NewCommand(commands.WithArgs(os.Args[1:])).BuildCommand()
 	case constants.DevCommand.Name:
-		return commands.NewCommand().DevCommand()
+		return commands.NewCommand(commands.WithArgs(os.Args[1:])).DevCommand()
 	case constants.HelpCommand.Name:
 		return commands.NewCommand().HelpCommand()
 	case constants.DoctorCommand.Name:
 		return commands.NewCommand().DoctorCommand()
+	case constants.AndroidCommand.Name:
+		return commands.NewCommand(commands.WithArgs(os.Args[1:])).AndroidCommand()
+	case constants.IOSCommand.Name:
+		return commands.NewCommand(commands.WithArgs(os.Args[1:])).IOSCommand()
+	case constants.AssetsCommand.Name:
+		return commands.NewCommand(commands.WithArgs(os.Args[1:])).AssetsCommand()
+	case constants.InspectCommand.Name:
+		return commands.NewCommand(commands.WithArgs(os.Args[1:])).InspectCommand()
 	case constants.VersionCommand.Name:
-		return commands.NewCommand().VersionCommand()
+		return commands.NewCommand(commands.WithArgs(os.Args[1:])).VersionCommand()
+	case constants.LogsCommand.Name:
+		return commands.NewCommand(commands.WithArgs(os.Args[1:])).LogsCommand()
 	default:
 		return commands.NewCommand().HelpCommand()
 	}
@@ -0,0 +1,397 @@
+package commands
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/velogo-dev/velo/pkg/apksign"
+	"github.com/velogo-dev/velo/pkg/builder"
+	"github.com/velogo-dev/velo/pkg/config"
+	"github.com/velogo-dev/velo/pkg/keystore"
+)
+
+// AndroidCommand implements the 'android' command with Android specific tooling
+//
+// Command syntax:
+//
+//	velo android project [--env <environment>]
+//	velo android keystore create [--out <file>] [--alias <alias>] [--dname <dname>] [--validity <days>] [--keyalg RSA|EC] [--keysize <bits>]
+//	velo android keystore info <file>
+//	velo android sign --in <apk|aab> [--out <file>] --keystore <file> [--alias <alias>] [--min-sdk <level>]
+//	velo android verify <apk>
+func (c *command) AndroidCommand() error {
+	if len(c.Args) < 2 {
+		fmt.Println("Usage: velo android [project|keystore|sign|verify]")
+		return fmt.Errorf("missing argument")
+	}
+
+	switch c.Args[1] {
+	case "project":
+		return generateAndroidProject(environmentArg(c.Args[2:]))
+	case "keystore":
+		return c.keystoreCommand(c.Args[2:])
+	case "sign":
+		return signApk(c.Args[2:])
+	case "verify":
+		if len(c.Args) < 3 {
+			fmt.Println("Usage: velo android verify <apk>")
+			return fmt.Errorf("missing apk file")
+		}
+		return verifyApk(c.Args[2])
+	default:
+		fmt.Printf("Unknown argument for 'android' command: %s\n", c.Args[1])
+		fmt.Println("Usage: velo android [project|keystore|sign|verify]")
+		return fmt.Errorf("unknown argument")
+	}
+}
+
+// generateAndroidProject renders the manifest and build.gradle of the Android
+// shell from velo.json for the given build environment
+func generateAndroidProject(environment string) error {
+	rootDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	cfg, err := config.Load(rootDir)
+	if err != nil {
+		return err
+	}
+
+	android := builder.NewAndroid(rootDir, cfg)
+	android.Environment = environment
+	if err := android.GenerateProject(); err != nil {
+		return err
+	}
+	fmt.Printf("Android project updated in %s\n", android.ShellDir)
+	return nil
+}
+
+// keystoreCommand creates or inspects a PKCS#12 signing keystore
+func (c *command) keystoreCommand(args []string) error {
+	if len(args) == 0 {
+		fmt.Println("Usage: velo android keystore [create|info]")
+		return fmt.Errorf("missing argument")
+	}
+
+	switch args[0] {
+	case "create":
+		return createKeystore(args[1:])
+	case "info":
+		if len(args) < 2 {
+			fmt.Println("Usage: velo android keystore info <file>")
+			return fmt.Errorf("missing keystore file")
+		}
+		return keystoreInfo(args[1])
+	default:
+		fmt.Printf("Unknown argument for 'keystore' command: %s\n", args[0])
+		fmt.Println("Usage: velo android keystore [create|info]")
+		return fmt.Errorf("unknown argument")
+	}
+}
+
+func createKeystore(args []string) error {
+	var (
+		out      = "release.keystore"
+		opts     = keystore.Options{Alias: "upload", ValidityDays: 10000, KeyAlgorithm: keystore.RSA}
+		err      error
+		password string
+	)
+
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return fmt.Errorf("missing value for %s flag", args[i])
+		}
+		switch args[i] {
+		case "--out", "-o":
+			out = args[i+1]
+		case "--alias", "-a":
+			opts.Alias = args[i+1]
+		case "--dname", "-d":
+			opts.DName = args[i+1]
+		case "--validity":
+			if opts.ValidityDays, err = strconv.Atoi(args[i+1]); err != nil {
+				return fmt.Errorf("invalid validity %q: %w", args[i+1], err)
+			}
+		case "--keyalg":
+			opts.KeyAlgorithm = args[i+1]
+		case "--keysize":
+			if opts.KeySize, err = strconv.Atoi(args[i+1]); err != nil {
+				return fmt.Errorf("invalid key size %q: %w", args[i+1], err)
+			}
+		default:
+			return fmt.Errorf("unknown flag %s", args[i])
+		}
+		i++
+	}
+
+	if _, err := os.Stat(out); err == nil {
+		return fmt.Errorf("%s already exists, refusing to overwrite it", out)
+	}
+
+	if opts.DName == "" {
+		err := huh.NewInput().
+			Title("Distinguished name of the certificate").
+			Placeholder("CN=Jane Doe, O=Acme, C=US").
+			Validate(func(s string) error {
+				_, err := keystore.ParseDName(s)
+				return err
+			}).
+			Value(&opts.DName).
+			Run()
+		if err != nil {
+			return err
+		}
+	}
+
+	if password, err = keystorePassword(true); err != nil {
+		return err
+	}
+
+	fmt.Printf("Generating %s key for alias %s...\n", opts.KeyAlgorithm, opts.Alias)
+	entry, err := keystore.Generate(opts)
+	if err != nil {
+		return err
+	}
+	if err := keystore.Save(out, password, []keystore.Entry{*entry}); err != nil {
+		return fmt.Errorf("failed to write keystore: %w", err)
+	}
+
+	fmt.Printf("Keystore written to %s\n\n", out)
+	printCertificate(entry.Certificate())
+	fmt.Println("\nKeep this file and its password safe: losing them means you cannot update your app.")
+	fmt.Printf("Reference it from keystore.properties (storeFile=%s, keyAlias=%s) or the %s* environment variables.\n",
+		out, opts.Alias, builder.EnvKeystore)
+	return nil
+}
+
+func keystoreInfo(path string) error {
+	password, err := keystorePassword(false)
+	if err != nil {
+		return err
+	}
+	entries, err := keystore.Load(path, password)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Keystore: %s\n", path)
+	fmt.Printf("Entries: %d\n", len(entries))
+	for _, entry := range entries {
+		kind := "PrivateKeyEntry"
+		if entry.PrivateKey == nil {
+			kind = "trustedCertEntry"
+		}
+		fmt.Printf("\nAlias: %s (%s)\n", entry.Alias, kind)
+		for i, cert := range entry.Certificates {
+			if len(entry.Certificates) > 1 {
+				fmt.Printf("Certificate [%d]:\n", i+1)
+			}
+			printCertificate(cert)
+		}
+	}
+	return nil
+}
+
+func signApk(args []string) error {
+	var (
+		in, out, storeFile, alias string
+		minSDK                    = 21
+		err                       error
+	)
+
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return fmt.Errorf("missing value for %s flag", args[i])
+		}
+		switch args[i] {
+		case "--in", "-i":
+			in = args[i+1]
+		case "--out", "-o":
+			out = args[i+1]
+		case "--keystore", "-k":
+			storeFile = args[i+1]
+		case "--alias", "-a":
+			alias = args[i+1]
+		case "--min-sdk":
+			if minSDK, err = strconv.Atoi(args[i+1]); err != nil {
+				return fmt.Errorf("invalid min SDK %q: %w", args[i+1], err)
+			}
+		default:
+			return fmt.Errorf("unknown flag %s", args[i])
+		}
+		i++
+	}
+
+	if in == "" || storeFile == "" {
+		fmt.Println("Usage: velo android sign --in <apk|aab> --keystore <file> [--out <file>] [--alias <alias>] [--min-sdk <level>]")
+		return fmt.Errorf("missing --in or --keystore flag")
+	}
+	// The input stays as it is, like the unsigned artifacts of velo build
+	if out == "" {
+		out = builder.SignedName(in)
+	}
+	if filepath.Clean(out) == filepath.Clean(in) {
+		return fmt.Errorf("--out must differ from --in, %s is not signed in place", in)
+	}
+
+	password, err := keystorePassword(false)
+	if err != nil {
+		return err
+	}
+	// PKCS#12 keystores use the store password for the key unless another
+	// one is set, as for velo build
+	keyPassword := os.Getenv(builder.EnvKeyPassword)
+	if keyPassword == "" {
+		keyPassword = password
+	}
+	entries, err := keystore.LoadWithKeyPassword(storeFile, password, keyPassword)
+	if err != nil {
+		return err
+	}
+	entry, err := signingEntry(entries, alias)
+	if err != nil {
+		return err
+	}
+
+	// App bundles are JAR signed, the APK schemes do not apply to them
+	opts := apksign.DefaultOptions(minSDK)
+	if strings.EqualFold(filepath.Ext(in), ".aab") {
+		opts = apksign.Options{V1: true}
+	}
+
+	signer := &apksign.Signer{PrivateKey: entry.PrivateKey, Certificates: entry.Certificates}
+	if err := apksign.SignFile(in, out, signer, opts); err != nil {
+		return err
+	}
+
+	fmt.Printf("Signed %s with alias %s\n", out, entry.Alias)
+	fmt.Printf("  v1 (JAR): %t, v2: %t, v3: %t\n", opts.V1, opts.V2, opts.V3)
+	return nil
+}
+
+// signingEntry returns the entry for alias, or the only private key entry of
+// the keystore when no alias is given
+func signingEntry(entries []keystore.Entry, alias string) (*keystore.Entry, error) {
+	if alias != "" {
+		entry, err := keystore.Find(entries, alias)
+		if err != nil {
+			return nil, err
+		}
+		if entry.PrivateKey == nil {
+			return nil, fmt.Errorf("alias %q has no private key", alias)
+		}
+		return entry, nil
+	}
+
+	var found *keystore.Entry
+	for i := range entries {
+		if entries[i].PrivateKey == nil {
+			continue
+		}
+		if found != nil {
+			return nil, errors.New("keystore has several keys, select one with --alias")
+		}
+		found = &entries[i]
+	}
+	if found == nil {
+		return nil, errors.New("keystore has no private key")
+	}
+	return found, nil
+}
+
+func verifyApk(path string) error {
+	result, err := apksign.VerifyFile(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("APK: %s\n", path)
+	schemes := []struct {
+		name   string
+		result apksign.SchemeResult
+	}{
+		{"v1 (JAR)", result.V1},
+		{"v2", result.V2},
+		{"v3", result.V3},
+	}
+	var certs []*x509.Certificate
+	for _, s := range schemes {
+		switch {
+		case !s.result.Present:
+			fmt.Printf("  %-9s not signed\n", s.name)
+		case s.result.Verified:
+			fmt.Printf("  %-9s verified\n", s.name)
+			if certs == nil {
+				certs = s.result.Certificates
+			}
+		default:
+			fmt.Printf("  %-9s FAILED: %v\n", s.name, s.result.Error)
+		}
+	}
+
+	for i, cert := range certs {
+		fmt.Printf("\nSigner #%d certificate:\n", i+1)
+		printCertificate(cert)
+	}
+
+	if !result.Verified() {
+		return fmt.Errorf("%s does not verify", path)
+	}
+	return nil
+}
+
+// keystorePassword reads the keystore password from the environment or
+// prompts for it, asking for confirmation when creating a keystore
+func keystorePassword(confirm bool) (string, error) {
+	if password := os.Getenv(builder.EnvKeystorePassword); password != "" {
+		return password, nil
+	}
+
+	var password, confirmation string
+	fields := []huh.Field{
+		huh.NewInput().
+			Title("Keystore password").
+			EchoMode(huh.EchoModePassword).
+			Validate(func(s string) error {
+				if confirm && len(s) < 6 {
+					return errors.New("password must be at least 6 characters")
+				}
+				return nil
+			}).
+			Value(&password),
+	}
+	if confirm {
+		fields = append(fields, huh.NewInput().
+			Title("Confirm password").
+			EchoMode(huh.EchoModePassword).
+			Validate(func(s string) error {
+				if s != password {
+					return errors.New("passwords do not match")
+				}
+				return nil
+			}).
+			Value(&confirmation))
+	}
+
+	if err := huh.NewForm(huh.NewGroup(fields...)).WithTheme(huh.ThemeDracula()).Run(); err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+// printCertificate prints the details and fingerprints of a certificate
+func printCertificate(cert *x509.Certificate) {
+	fmt.Printf("  Owner:       %s\n", cert.Subject)
+	fmt.Printf("  Issuer:      %s\n", cert.Issuer)
+	fmt.Printf("  Serial:      %x\n", cert.SerialNumber)
+	fmt.Printf("  Valid from:  %s until %s\n", cert.NotBefore.Format("2006-01-02"), cert.NotAfter.Format("2006-01-02"))
+	fmt.Printf("  Algorithm:   %s\n", cert.SignatureAlgorithm)
+	fmt.Printf("  SHA-1:       %s\n", keystore.SHA1Fingerprint(cert))
+	fmt.Printf("  SHA-256:     %s\n", keystore.SHA256Fingerprint(cert))
+}
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/velogo-dev/velo/pkg/builder"
+	"github.com/velogo-dev/velo/pkg/config"
+	"github.com/velogo-dev/velo/pkg/imaging"
+)
+
+// AssetsCommand implements the 'assets' command generating app resources
+//
+// Command syntax:
+//
+//	velo assets icons --source <icon.png> [--background <#RRGGBB>]
+//	velo assets splash
+func (c *command) AssetsCommand() error {
+	if len(c.Args) < 2 {
+		fmt.Println("Usage: velo assets [icons|splash]")
+		return fmt.Errorf("missing argument")
+	}
+
+	switch c.Args[1] {
+	case "icons":
+		return generateIcons(c.Args[2:])
+	case "splash":
+		return generateSplash()
+	default:
+		fmt.Printf("Unknown argument for 'assets' command: %s\n", c.Args[1])
+		fmt.Println("Usage: velo assets [icons|splash]")
+		return fmt.Errorf("unknown argument")
+	}
+}
+
+// generateIcons writes the launcher icons, app icon set and web icons from one
+// source image
+func generateIcons(args []string) error {
+	var source, background string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--source", "-s":
+			if i+1 < len(args) {
+				source = args[i+1]
+				i++
+			}
+		case "--background", "-b":
+			if i+1 < len(args) {
+				background = args[i+1]
+				i++
+			}
+		}
+	}
+	if source == "" {
+		fmt.Println("Usage: velo assets icons --source <icon.png> [--background <#RRGGBB>]")
+		return fmt.Errorf("missing source image")
+	}
+
+	rootDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	cfg, err := config.Load(rootDir)
+	if err != nil {
+		return err
+	}
+	img, err := imaging.Load(source)
+	if err != nil {
+		return err
+	}
+
+	icons := &builder.Icons{RootDir: rootDir, Config: cfg, Source: img, Background: background}
+	written, err := icons.Generate()
+	if err != nil {
+		return err
+	}
+
+	// Reference the new launcher icons from the manifest
+	android := builder.NewAndroid(rootDir, cfg)
+	if _, err := os.Stat(android.ManifestPath()); err == nil {
+		if err := android.GenerateProject(); err != nil {
+			return err
+		}
+	}
+
+	counts := map[string]int{}
+	for _, path := range written {
+		rel, _ := filepath.Rel(rootDir, path)
+		switch {
+		case strings.HasPrefix(rel, filepath.Join("mobile-shell", "android")):
+			counts["Android"]++
+		case strings.HasPrefix(rel, filepath.Join("mobile-shell", "ios")):
+			counts["iOS"]++
+		default:
+			counts["web"]++
+		}
+	}
+	for _, platform := range []string{"Android", "iOS", "web"} {
+		if counts[platform] > 0 {
+			fmt.Printf("%-8s %d files\n", platform, counts[platform])
+		}
+	}
+	return nil
+}
+
+// generateSplash writes the splash screens of app.splash into the shells of
+// the project
+func generateSplash() error {
+	rootDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	cfg, err := config.Load(rootDir)
+	if err != nil {
+		return err
+	}
+
+	generated := false
+	android := builder.NewAndroid(rootDir, cfg)
+	if _, err := os.Stat(android.ManifestPath()); err == nil {
+		// The manifest and build.gradle reference the splash theme
+		if err := android.GenerateProject(); err != nil {
+			return err
+		}
+		fmt.Printf("Android  %s\n", relPath(rootDir, android.SplashPath()))
+		generated = true
+	}
+	ios := builder.NewIOS(rootDir, cfg)
+	if _, err := os.Stat(ios.ShellDir); err == nil {
+		// The project lists the storyboard as a resource
+		if err := ios.GenerateProject(); err != nil {
+			return err
+		}
+		fmt.Printf("iOS      %s\n", relPath(rootDir, ios.LaunchScreenPath()))
+		generated = true
+	}
+	if !generated {
+		return fmt.Errorf("no Android or iOS shell found in %s", rootDir)
+	}
+	return nil
+}
+
+func relPath(rootDir, path string) string {
+	if rel, err := filepath.Rel(rootDir, path); err == nil {
+		return rel
+	}
+	return path
+}
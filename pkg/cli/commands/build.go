@@ -2,6 +2,13 @@ package commands
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/velogo-dev/velo/pkg/builder"
+	"github.com/velogo-dev/velo/pkg/config"
+	"github.com/velogo-dev/velo/pkg/utils"
 )
 
 // BuildCommand implements the 'build' command to build the application
@@ -12,8 +19,12 @@ func (c *command) BuildCommand() error {
 
 	// Process build arguments
 	var (
-		environment = "production"
+		environment = config.DefaultEnvironment
 		output      = "./dist"
+		platform    = "android"
+		format      = builder.FormatAPK
+		release     = false
+		devServer   = false
 	)
 
 	for i := 1; i < len(c.Args); i++ {
@@ -27,13 +38,124 @@ func (c *command) BuildCommand() error {
 				output = c.Args[i+1]
 				i++
 			}
+		} else if c.Args[i] == "--platform" || c.Args[i] == "-p" {
+			if i+1 < len(c.Args) {
+				platform = c.Args[i+1]
+				i++
+			}
+		} else if c.Args[i] == "--format" || c.Args[i] == "-f" {
+			if i+1 < len(c.Args) {
+				format = c.Args[i+1]
+				i++
+			}
+		} else if c.Args[i] == "--release" || c.Args[i] == "-r" {
+			release = true
+		} else if c.Args[i] == "--dev-server" {
+			devServer = true
 		}
 	}
 
+	if release && devServer {
+		return fmt.Errorf("--dev-server cannot be used with --release, release builds load the bundled web app")
+	}
+
 	fmt.Printf("Building for %s environment\n", environment)
 	fmt.Printf("Output directory: %s\n", output)
 
-	// TODO: Implement actual build logic
+	rootDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	cfg, err := config.Load(rootDir)
+	if err != nil {
+		return err
+	}
+
+	// Resolve the environment first so a typo fails before anything is built
+	vars, err := builder.EnvVars(cfg, environment)
+	if err != nil {
+		return err
+	}
+
+	// Debug builds load the bundled web app unless asked to use the dev
+	// server, the builders refuse to point release builds at it
+	var devServerURL string
+	if devServer {
+		devServerURL = cfg.Dev.URL()
+	}
+
+	frontend := builder.NewFrontend(rootDir)
+	frontend.SetEnvironment(vars)
+	if err := frontend.Build(); err != nil {
+		return fmt.Errorf("frontend build failed: %w", err)
+	}
+	if err := frontend.CopyBuildToMobile(); err != nil {
+		return err
+	}
+
+	switch platform {
+	case "android":
+		android := builder.NewAndroid(rootDir, cfg)
+		android.Release = release
+		android.Format = format
+		android.Environment = environment
+		android.DevServerURL = devServerURL
+		if err := android.Build(); err != nil {
+			return fmt.Errorf("android build failed: %w", err)
+		}
+
+		artifact, err := android.FindArtifact()
+		if err != nil {
+			return err
+		}
+		if format == builder.FormatAAB {
+			info, err := builder.ValidateBundle(artifact)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("App bundle is valid: %d entries, modules %s, %.2f MB\n",
+				info.Entries, strings.Join(info.Modules, ", "), float64(info.Size)/(1024*1024))
+		}
+		dst := filepath.Join(output, filepath.Base(artifact))
+		if err := utils.CopyFile(artifact, dst); err != nil {
+			return fmt.Errorf("failed to copy artifact: %w", err)
+		}
+		fmt.Printf("Artifact: %s\n", dst)
+	case "ios":
+		ios := builder.NewIOS(rootDir, cfg)
+		ios.Release = release
+		ios.Environment = environment
+		ios.DevServerURL = devServerURL
+		if err := ios.Build(); err != nil {
+			return fmt.Errorf("ios build failed: %w", err)
+		}
+
+		if release {
+			artifact, err := ios.FindArtifact()
+			if err != nil {
+				return err
+			}
+			dst := filepath.Join(output, filepath.Base(artifact))
+			if err := utils.CopyFile(artifact, dst); err != nil {
+				return fmt.Errorf("failed to copy artifact: %w", err)
+			}
+			fmt.Printf("Artifact: %s\n", dst)
+		}
+	default:
+		return fmt.Errorf("unsupported platform: %s", platform)
+	}
+
 	fmt.Println("Build completed successfully")
 	return nil
 }
+
+// environmentArg returns the value of --env in args, the default environment
+// when it is absent
+func environmentArg(args []string) string {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "--env" || args[i] == "-e" {
+			return args[i+1]
+		}
+	}
+	return config.DefaultEnvironment
+}
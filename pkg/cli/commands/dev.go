@@ -1,36 +1,264 @@
 package commands
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/velogo-dev/velo/pkg/builder"
+	"github.com/velogo-dev/velo/pkg/config"
+	"github.com/velogo-dev/velo/pkg/devcert"
+	"github.com/velogo-dev/velo/pkg/logcat"
 )
 
-// DevCommand implements the 'dev' command to run the application in development mode
+// devServerTimeout is how long velo dev waits for the dev server to accept
+// HTTP requests unless --timeout is given
+const devServerTimeout = 60 * time.Second
+
+// DevCommand implements the 'dev' command to run the application in development mode.
+// It starts the framework dev server behind the Velo dev proxy, waits until it
+// serves HTTP, points the debug shell at the proxy and installs and launches
+// the shell on the target devices. The dev server runs until Ctrl+C.
 func (c *command) DevCommand() error {
+	rootDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	cfg, err := config.Load(rootDir)
+	if err != nil {
+		return err
+	}
+	// The flags override the dev settings, the rebuilds compare those of
+	// velo.json
+	fileDev := cfg.Dev
+
+	opts, err := c.parseDevArgs(cfg)
+	if err != nil {
+		return err
+	}
+	if opts.help {
+		return c.HelpCommand()
+	}
 	fmt.Println("Starting development server...")
 
-	// Process dev arguments
-	var (
-		port = "3000"
-		host = "localhost"
-	)
+	if opts.environment == "" {
+		opts.environment = devEnvironment(cfg)
+	}
+	vars, err := builder.EnvVars(cfg, opts.environment)
+	if err != nil {
+		return err
+	}
+
+	session := &devSession{
+		rootDir:     rootDir,
+		cfg:         cfg,
+		platform:    opts.platform,
+		environment: opts.environment,
+		devices:     opts.devices,
+		logLevel:    opts.logLevel,
+		lan:         opts.lan,
+		https:       opts.https,
+		fileDev:     fileDev,
+	}
+	// Resolve the Android devices before starting anything, a missing device
+	// is the most common reason velo dev cannot run
+	if err := session.resolveDevices(); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Busy ports move to the next free ones unless --strict-port is set, the
+	// proxy, the framework flags, adb reverse and the shells all follow cfg.Dev
+	if err := session.choosePorts(opts.strictPort, opts.bundled); err != nil {
+		return err
+	}
+	var tlsConfig *tls.Config
+	if opts.https {
+		if tlsConfig, err = session.setupHTTPS(); err != nil {
+			return err
+		}
+	}
+
+	frontend := builder.NewFrontend(rootDir)
+	frontend.SetEnvironment(vars)
+	if opts.bundled {
+		return session.runBundled(ctx, frontend, tlsConfig)
+	}
+	return session.runProxied(ctx, frontend, tlsConfig, opts.timeout, opts.verbose)
+}
+
+// devOptions holds the flags of velo dev, the port and host flags are
+// written to the dev settings of the config
+type devOptions struct {
+	platform    string
+	environment string
+	devices     []string
+	timeout     time.Duration
+	verbose     bool
+	bundled     bool
+	logLevel    logcat.Level
+	lan         bool
+	strictPort  bool
+	https       bool
+	help        bool
+}
+
+// parseDevArgs parses the arguments of velo dev. -h is the short form of
+// --host, help is only shown for --help.
+func (c *command) parseDevArgs(cfg *config.Config) (*devOptions, error) {
+	opts := &devOptions{
+		platform: "android",
+		timeout:  devServerTimeout,
+		logLevel: logcat.Info,
+	}
 
 	for i := 1; i < len(c.Args); i++ {
 		if c.Args[i] == "--port" || c.Args[i] == "-p" {
 			if i+1 < len(c.Args) {
-				port = c.Args[i+1]
+				port, err := strconv.Atoi(c.Args[i+1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid port %q", c.Args[i+1])
+				}
+				cfg.Dev.Port = port
 				i++
 			}
 		} else if c.Args[i] == "--host" || c.Args[i] == "-h" {
 			if i+1 < len(c.Args) {
-				host = c.Args[i+1]
+				cfg.Dev.Host = c.Args[i+1]
+				i++
+			}
+		} else if c.Args[i] == "--help" {
+			opts.help = true
+		} else if c.Args[i] == "--platform" {
+			if i+1 < len(c.Args) {
+				opts.platform = c.Args[i+1]
+				i++
+			}
+		} else if c.Args[i] == "--device" || c.Args[i] == "-d" {
+			if i+1 < len(c.Args) {
+				opts.devices = append(opts.devices, c.Args[i+1])
+				i++
+			}
+		} else if c.Args[i] == "--env" || c.Args[i] == "-e" {
+			if i+1 < len(c.Args) {
+				opts.environment = c.Args[i+1]
 				i++
 			}
+		} else if c.Args[i] == "--timeout" {
+			if i+1 < len(c.Args) {
+				d, err := time.ParseDuration(c.Args[i+1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid timeout %q, e.g. 90s", c.Args[i+1])
+				}
+				opts.timeout = d
+				i++
+			}
+		} else if c.Args[i] == "--verbose" || c.Args[i] == "-v" {
+			opts.verbose = true
+		} else if c.Args[i] == "--bundled" {
+			opts.bundled = true
+		} else if c.Args[i] == "--lan" {
+			opts.lan = true
+		} else if c.Args[i] == "--strict-port" {
+			opts.strictPort = true
+		} else if c.Args[i] == "--https" {
+			opts.https = true
+		} else if c.Args[i] == "--log-level" {
+			if i+1 < len(c.Args) {
+				level, err := logcat.ParseLevel(c.Args[i+1])
+				if err != nil {
+					return nil, err
+				}
+				opts.logLevel = level
+				i++
+			}
+		} else if !strings.HasPrefix(c.Args[i], "-") {
+			// A value whose flag is missing or misspelled, e.g. velo dev ios
+			return nil, fmt.Errorf("unexpected argument %q", c.Args[i])
 		}
 	}
 
-	fmt.Printf("Dev server running at: http://%s:%s\n", host, port)
-	fmt.Println("Press Ctrl+C to stop the server")
+	if opts.platform != "android" && opts.platform != "ios" && opts.platform != "web" {
+		return nil, fmt.Errorf("unsupported platform: %s", opts.platform)
+	}
+	return opts, nil
+}
+
+// devEnvironment returns the build environment of development builds, the
+// dev environment when the project defines one
+func devEnvironment(cfg *config.Config) string {
+	if _, ok := cfg.Environments["dev"]; ok {
+		return "dev"
+	}
+	return config.DefaultEnvironment
+}
 
-	// TODO: Implement actual dev server logic
-	select {} // Keep the application running
+// devSession holds the targets of a velo dev run
+type devSession struct {
+	rootDir     string
+	cfg         *config.Config
+	platform    string
+	environment string
+	devices     []string
+	// logLevel is the lowest level of the app log shown for Android
+	logLevel logcat.Level
+	// lan serves the dev server to the devices of the LAN
+	lan bool
+	// https serves the dev server over TLS, ca is the development CA then
+	https bool
+	ca    *devcert.CA
+	// fileDev holds the dev settings of velo.json when velo dev started
+	fileDev config.Dev
+}
+
+// resolveDevices lists the connected Android devices when none were given
+func (s *devSession) resolveDevices() error {
+	if s.platform != "android" || len(s.devices) > 0 {
+		return nil
+	}
+	devices, err := s.android().Devices()
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		// Devices on the LAN get the dev server through the QR code
+		if s.lan {
+			fmt.Println("No Android device is connected over USB, the debug build is only built")
+			return nil
+		}
+		return fmt.Errorf("no Android device or emulator is connected, start an emulator or pass --platform web")
+	}
+	s.devices = devices
+	return nil
+}
+
+func (s *devSession) android() *builder.Android {
+	android := builder.NewAndroid(s.rootDir, s.cfg)
+	android.Environment = s.environment
+	if s.ca != nil {
+		android.DevCA = s.ca.PEM()
+	}
+	return android
+}
+
+func (s *devSession) ios() *builder.IOS {
+	ios := builder.NewIOS(s.rootDir, s.cfg)
+	ios.Environment = s.environment
+	return ios
+}
+
+// scheme returns the scheme of the dev server URLs
+func (s *devSession) scheme() string {
+	if s.https {
+		return "https"
+	}
+	return "http"
 }
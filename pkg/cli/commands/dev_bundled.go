@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/velogo-dev/velo/pkg/builder"
+	"github.com/velogo-dev/velo/pkg/server"
+	"github.com/velogo-dev/velo/pkg/supervisor"
+	"github.com/velogo-dev/velo/pkg/watcher"
+)
+
+// bundledIgnore lists the frontend directories not watched in bundled mode:
+// dependencies, the build output and the caches of the build tools
+var bundledIgnore = []string{"node_modules", "dist", ".git", ".vite", ".next", ".nuxt", ".svelte-kit"}
+
+// runBundled tests the production build. It builds the frontend, serves the
+// assets with live reload and installs the shells loading the bundled assets.
+// Every change of the frontend sources rebuilds the frontend, syncs the
+// assets, reloads the preview pages and pushes the assets to the running
+// shells, which load them again. Only native changes rebuild the shells.
+func (s *devSession) runBundled(ctx context.Context, frontend *builder.Frontend, tlsConfig *tls.Config) error {
+	fmt.Println("Running in bundled mode, changes rebuild the production assets")
+
+	if err := s.buildAssets(frontend); err != nil {
+		return err
+	}
+
+	preview := server.NewPreviewServer(s.rootDir, strconv.Itoa(s.cfg.Dev.Port))
+	preview.TLSConfig = tlsConfig
+	if err := preview.Listen(); err != nil {
+		return err
+	}
+	defer preview.Shutdown()
+	// The preview server listens on every interface, the shells load their
+	// bundled assets
+	if s.lan {
+		hosts, err := lanAddrs(s.cfg.Dev.Port)
+		if err != nil {
+			return err
+		}
+		s.printLAN(hosts, false)
+	}
+
+	if err := s.installShells(""); err != nil {
+		return err
+	}
+	// No process is supervised here, the supervisor only prefixes the log
+	s.followLogs(ctx, supervisor.New().Writer)
+
+	ignore, err := watcher.ReadIgnoreFile(filepath.Join(frontend.RootDir, ".gitignore"))
+	if err != nil {
+		return err
+	}
+	w, err := watcher.New([]string{frontend.RootDir}, watcher.Options{Ignore: append(bundledIgnore, ignore...)})
+	if err != nil {
+		return fmt.Errorf("failed to watch the frontend: %w", err)
+	}
+	defer w.Close()
+	fmt.Printf("Watching %s for changes, press Ctrl+C to stop\n", frontend.RootDir)
+
+	native, err := s.watchNative()
+	if err != nil {
+		return err
+	}
+	if native != nil {
+		defer native.Close()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("\nStopping development server...")
+			return nil
+		case events := <-nativeEvents(native):
+			s.rebuildShells(native, events, "")
+		case events := <-w.Events:
+			fmt.Printf("%d frontend file(s) changed, rebuilding...\n", len(events))
+			// A broken build keeps the last assets, the next save retries
+			if err := s.buildAssets(frontend); err != nil {
+				fmt.Fprintf(os.Stderr, "Rebuild failed: %v\n", err)
+				continue
+			}
+			preview.Reload()
+			if err := s.pushAssets(frontend.AssetsDir); err != nil {
+				// The shell of a physical iOS device, or one that was
+				// uninstalled, gets the assets with a new build
+				fmt.Fprintf(os.Stderr, "Pushing the assets failed, reinstalling the shell: %v\n", err)
+				if err := s.installShells(""); err != nil {
+					fmt.Fprintf(os.Stderr, "Reinstalling the shell failed: %v\n", err)
+				}
+			}
+		}
+	}
+}
+
+// buildAssets builds the frontend and syncs the output to the shell assets
+func (s *devSession) buildAssets(frontend *builder.Frontend) error {
+	if err := frontend.Build(); err != nil {
+		return fmt.Errorf("frontend build failed: %w", err)
+	}
+	changed, err := frontend.SyncBuildToMobile()
+	if err != nil {
+		return fmt.Errorf("failed to sync the assets: %w", err)
+	}
+	fmt.Printf("Synced %d asset file(s) to %s\n", changed, frontend.AssetsDir)
+	return nil
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"path/filepath"
+	"slices"
+
+	"github.com/velogo-dev/velo/pkg/devcert"
+	"github.com/velogo-dev/velo/pkg/server"
+	"github.com/velogo-dev/velo/pkg/utils"
+)
+
+// devCAPath serves the files of the development CA with --https
+const devCAPath = server.EndpointPrefix + "ca/"
+
+// setupHTTPS loads or creates the development CA and the certificate of the
+// dev server for localhost, the dev host and the LAN addresses, and makes the
+// simulators trust the CA. Android debug builds trust it through their
+// network security config.
+func (s *devSession) setupHTTPS() (*tls.Config, error) {
+	dir, err := devcert.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	ca, created, err := devcert.LoadOrCreateCA(dir)
+	if err != nil {
+		return nil, err
+	}
+	s.ca = ca
+	if created {
+		fmt.Printf("Created the Velo development CA in %s\n", dir)
+	}
+
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if ip := net.ParseIP(s.cfg.Dev.Host); s.cfg.Dev.Host != "" && (ip == nil || !ip.IsUnspecified()) && !slices.Contains(hosts, s.cfg.Dev.Host) {
+		hosts = append(hosts, s.cfg.Dev.Host)
+	}
+	// The certificate is issued again when the LAN addresses change
+	if ips, err := utils.LANAddresses(); err == nil {
+		for _, ip := range ips {
+			hosts = append(hosts, ip.String())
+		}
+	}
+	cert, err := ca.Leaf(filepath.Join(s.rootDir, ".velo", "certs"), hosts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the dev server certificate: %w", err)
+	}
+
+	switch s.platform {
+	case "ios":
+		// An empty device ID targets the booted simulator
+		devices := s.devices
+		if len(devices) == 0 {
+			devices = []string{""}
+		}
+		ios := s.ios()
+		for _, device := range devices {
+			if err := ios.TrustRootCert(device, ca.Path(devcert.CACertFile)); err != nil {
+				return nil, fmt.Errorf("failed to add the development CA to the simulator: %w", err)
+			}
+		}
+	case "web":
+		fmt.Printf("Add %s to the trusted certificates of your system or browser to open the dev server without warnings\n", ca.Path(devcert.CACertFile))
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
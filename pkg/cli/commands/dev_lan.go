@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/velogo-dev/velo/pkg/builder"
+	"github.com/velogo-dev/velo/pkg/qrcode"
+	"github.com/velogo-dev/velo/pkg/utils"
+)
+
+// lanAddrs returns the host:port of the LAN addresses of the machine
+func lanAddrs(port int) ([]string, error) {
+	ips, err := utils.LANAddresses()
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no LAN address found, connect to a network or drop --lan")
+	}
+	hosts := make([]string, len(ips))
+	for i, ip := range ips {
+		hosts[i] = net.JoinHostPort(ip.String(), strconv.Itoa(port))
+	}
+	return hosts, nil
+}
+
+// printLAN prints the URLs of the LAN addresses and a QR code of the first
+// one. With shells set the QR code holds the dev link that hands the URL to
+// a debug build of the app.
+func (s *devSession) printLAN(hosts []string, shells bool) {
+	fmt.Println("On your network:")
+	for _, host := range hosts {
+		fmt.Printf("  %s://%s\n", s.scheme(), host)
+	}
+	content := s.scheme() + "://" + hosts[0]
+	if shells && s.platform != "web" {
+		// The application ID is the bundle ID on iOS
+		content = builder.DevLink(s.android().ApplicationID(), content)
+		fmt.Println("Scan the QR code with a device running a debug build of the app, or enter the URL in its dev settings:")
+	} else {
+		fmt.Println("Scan the QR code to open it on a device:")
+	}
+	code, err := qrcode.Encode(content)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+	fmt.Print(code.Terminal())
+}
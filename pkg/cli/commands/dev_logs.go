@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/velogo-dev/velo/pkg/logcat"
+)
+
+// followLogs streams the log of the app on every Android device in the
+// background until ctx is done, writer returns the output of a device
+func (s *devSession) followLogs(ctx context.Context, writer func(name string) io.Writer) {
+	if s.platform != "android" {
+		return
+	}
+	pkg := s.android().ApplicationID()
+	for _, device := range s.devices {
+		name := "logcat"
+		if len(s.devices) > 1 {
+			name += " " + device
+		}
+		out := writer(name)
+		stream := &logcat.Stream{
+			Device:   device,
+			Package:  pkg,
+			MinLevel: s.logLevel,
+			Notify:   func(message string) { fmt.Fprintln(out, message) },
+		}
+		go func() {
+			err := stream.Run(ctx, func(e logcat.Entry) { fmt.Fprintln(out, formatLogEntry(e)) })
+			if err != nil {
+				fmt.Fprintln(out, err)
+			}
+		}()
+	}
+}
+
+// openConsoleLog creates the console log file of the session in .velo/logs
+func (s *devSession) openConsoleLog() (*os.File, error) {
+	dir := filepath.Join(s.rootDir, ".velo", "logs")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	path := filepath.Join(dir, "console-"+time.Now().Format("20060102-150405")+".log")
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create console log: %w", err)
+	}
+	fmt.Printf("Console log: %s\n", path)
+	return f, nil
+}
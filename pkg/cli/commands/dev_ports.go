@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/velogo-dev/velo/pkg/utils"
+)
+
+// maxPortProbes is the number of ports tried after a busy one
+const maxPortProbes = 50
+
+// choosePorts probes the ports of the dev server and, outside bundled mode,
+// of the framework dev server from the configured ones and keeps the first
+// free ports in cfg.Dev, so several projects run side by side. With strict
+// set a busy port is an error.
+func (s *devSession) choosePorts(strict, bundled bool) error {
+	dev := &s.cfg.Dev
+	port, err := choosePort("dev server", dev.Port, strict, nil)
+	if err != nil {
+		return err
+	}
+	dev.Port = port
+	if bundled {
+		return nil
+	}
+	// The framework port follows the dev server port unless it is set
+	start := dev.FrameworkPort
+	if start == 0 {
+		start = port + 1
+	}
+	framework, err := choosePort("framework dev server", start, strict, map[int]string{port: "dev server"})
+	if err != nil {
+		return err
+	}
+	dev.FrameworkPort = framework
+	return nil
+}
+
+// choosePort returns the first free port from start, leaving out the ports
+// taken by the other dev servers, and tells why start cannot be used
+func choosePort(name string, start int, strict bool, taken map[int]string) (int, error) {
+	_, isTaken := taken[start]
+	if !isTaken && utils.PortFree(start) {
+		return start, nil
+	}
+	reason := busyReason(start, taken)
+	if strict {
+		return 0, fmt.Errorf("port %d of the %s %s", start, name, reason)
+	}
+	exclude := make([]int, 0, len(taken))
+	for port := range taken {
+		exclude = append(exclude, port)
+	}
+	port, err := utils.FreePort(start+1, maxPortProbes, exclude...)
+	if err != nil {
+		return 0, fmt.Errorf("no free port for the %s: %w", name, err)
+	}
+	fmt.Printf("Port %d %s, the %s uses port %d\n", start, reason, name, port)
+	return port, nil
+}
+
+// busyReason tells why port cannot be used: another dev server of velo dev
+// takes it, or a process holds it, named when it can be found
+func busyReason(port int, taken map[int]string) string {
+	if owner, ok := taken[port]; ok {
+		return "is already used by the " + owner
+	}
+	if pid, command, err := utils.PortOwner(port); err == nil {
+		return fmt.Sprintf("is in use by %s (pid %d)", command, pid)
+	}
+	return "is in use"
+}
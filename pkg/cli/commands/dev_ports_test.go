@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+// freePort returns a port nothing listens on
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestChoosePortExcludedButFree(t *testing.T) {
+	start := freePort(t)
+	taken := map[int]string{start: "dev server"}
+
+	if reason := busyReason(start, taken); reason != "is already used by the dev server" {
+		t.Errorf("busyReason = %q", reason)
+	}
+	port, err := choosePort("framework dev server", start, false, taken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port == start {
+		t.Errorf("choosePort returned the port of the dev server")
+	}
+
+	_, err = choosePort("framework dev server", start, true, taken)
+	if err == nil || !strings.Contains(err.Error(), "already used by the dev server") {
+		t.Errorf("strict choosePort: %v", err)
+	}
+	if strings.Contains(err.Error(), "in use") {
+		t.Errorf("strict choosePort reports a free port as in use: %v", err)
+	}
+}
+
+func TestChoosePortFree(t *testing.T) {
+	start := freePort(t)
+	if port, err := choosePort("dev server", start, true, nil); err != nil || port != start {
+		t.Errorf("choosePort = %d, %v, want %d", port, err, start)
+	}
+}
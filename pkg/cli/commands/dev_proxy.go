@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/velogo-dev/velo/pkg/builder"
+	"github.com/velogo-dev/velo/pkg/devcert"
+	"github.com/velogo-dev/velo/pkg/server"
+	"github.com/velogo-dev/velo/pkg/supervisor"
+	"github.com/velogo-dev/velo/pkg/utils"
+)
+
+// devServerTail is the number of output lines shown when the dev server fails
+const devServerTail = 30
+
+// runProxied runs the framework dev server behind the dev proxy, waiting
+// timeout for it to serve HTTP, and installs the shells loading the proxy.
+// Native changes rebuild the shells until ctx is done or the dev server
+// gives up.
+func (s *devSession) runProxied(ctx context.Context, frontend *builder.Frontend, tlsConfig *tls.Config, timeout time.Duration, verbose bool) error {
+	// The supervisor prefixes the output of the dev server, restarts it when
+	// it crashes and stops it on the way out
+	sup := supervisor.New()
+	defer sup.Stop()
+
+	// The shells load the proxy, which forwards to the framework dev server
+	// behind it. Starting it first fails early when the port was taken since
+	// it was probed.
+	addr := net.JoinHostPort(s.cfg.Dev.Host, strconv.Itoa(s.cfg.Dev.Port))
+	url := s.scheme() + "://" + addr
+	frameworkURL := s.cfg.Dev.FrameworkURL()
+	proxy, err := server.NewDevProxy(addr, frameworkURL)
+	if err != nil {
+		return err
+	}
+	proxy.Log = sup.Writer("proxy")
+	proxy.Verbose = verbose
+	proxy.TLSConfig = tlsConfig
+	if s.ca != nil {
+		proxy.Handle(devCAPath, http.StripPrefix(devCAPath, s.ca.Handler()))
+	}
+
+	// With --lan the proxy also listens on the LAN addresses and the shells
+	// load the first one, so devices on the same network reach it without
+	// USB. A proxy listening on every interface already serves them.
+	shellURL := url
+	var lanHosts []string
+	if s.lan {
+		if lanHosts, err = lanAddrs(s.cfg.Dev.Port); err != nil {
+			return err
+		}
+		if ip := net.ParseIP(s.cfg.Dev.Host); s.cfg.Dev.Host != "" && (ip == nil || !ip.IsUnspecified()) {
+			proxy.ExtraAddrs = lanHosts
+		}
+		shellURL = s.scheme() + "://" + lanHosts[0]
+	}
+
+	// The console output and errors of the pages are streamed to the
+	// terminal and mirrored to a log file of the session
+	consoleFile, err := s.openConsoleLog()
+	if err != nil {
+		return err
+	}
+	defer consoleFile.Close()
+	proxy.InjectScript(server.ConsoleScript)
+	proxy.Handle(server.ConsolePath, &server.ConsoleLog{Output: sup.Writer("console"), File: consoleFile})
+	if err := proxy.Start(); err != nil {
+		return err
+	}
+	defer proxy.Shutdown()
+
+	tail := utils.NewTailBuffer(devServerTail)
+	devServer := frontend.DevServerProcess(s.cfg.Dev.Framework(), frameworkURL)
+	devServer.Output = tail
+	fmt.Println("Starting frontend dev server...")
+	if err := sup.Start(devServer); err != nil {
+		return err
+	}
+
+	fmt.Printf("Waiting for the framework dev server at %s...\n", frameworkURL)
+	if err := sup.WaitReady(ctx, devServer.Name, timeout); err != nil {
+		printTail(tail)
+		if ctx.Err() != nil {
+			return fmt.Errorf("interrupted while waiting for the dev server")
+		}
+		return fmt.Errorf("dev server not ready: %w, check dev.frameworkPort in velo.json", err)
+	}
+	fmt.Printf("Dev server ready at: %s\n", url)
+
+	if s.platform == "android" {
+		port := strconv.Itoa(s.cfg.Dev.Port)
+		for _, device := range s.devices {
+			if err := s.android().SetupPortForwarding(device, port); err != nil {
+				return fmt.Errorf("port forwarding to %s failed: %w", device, err)
+			}
+		}
+	}
+	if err := s.installShells(shellURL); err != nil {
+		return err
+	}
+	s.followLogs(ctx, sup.Writer)
+
+	native, err := s.watchNative()
+	if err != nil {
+		return err
+	}
+	if native != nil {
+		defer native.Close()
+	}
+
+	fmt.Printf("Dev server running at: %s\n", url)
+	if s.lan {
+		s.printLAN(lanHosts, true)
+	}
+	if s.ca != nil && s.platform == "ios" {
+		fmt.Printf("iOS devices install the CA from %s%s%s, then enable it in Settings > General > About > Certificate Trust Settings\n", shellURL, devCAPath, devcert.MobileConfigFile)
+	}
+	fmt.Println("Press Ctrl+C to stop the server")
+
+	failed := make(chan error, 1)
+	go func() { failed <- sup.Wait(ctx) }()
+	for {
+		select {
+		case err := <-failed:
+			if err != nil {
+				printTail(tail)
+				return err
+			}
+			fmt.Println("\nStopping development server...")
+			return nil
+		case events := <-nativeEvents(native):
+			s.rebuildShells(native, events, shellURL)
+		}
+	}
+}
+
+// printTail prints the last lines of the dev server output
+func printTail(tail *utils.TailBuffer) {
+	lines := tail.Lines()
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Last %d lines of the dev server output:\n", len(lines))
+	fmt.Fprintln(os.Stderr, "  "+strings.Join(lines, "\n  "))
+}
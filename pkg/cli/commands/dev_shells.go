@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/velogo-dev/velo/pkg/config"
+	"github.com/velogo-dev/velo/pkg/watcher"
+)
+
+// installShells builds the debug shell loading devServerURL, the bundled
+// assets when empty, and installs and launches it on the devices
+func (s *devSession) installShells(devServerURL string) error {
+	switch s.platform {
+	case "android":
+		android := s.android()
+		android.DevServerURL = devServerURL
+		if err := android.Build(); err != nil {
+			return fmt.Errorf("android build failed: %w", err)
+		}
+		for _, device := range s.devices {
+			if err := android.InstallApp(device); err != nil {
+				return fmt.Errorf("installing on %s failed: %w", device, err)
+			}
+			if err := android.LaunchApp(device); err != nil {
+				return err
+			}
+		}
+	case "ios":
+		ios := s.ios()
+		ios.DevServerURL = devServerURL
+		if err := ios.Build(); err != nil {
+			return fmt.Errorf("ios build failed: %w", err)
+		}
+		// An empty device ID targets the booted simulator
+		devices := s.devices
+		if len(devices) == 0 {
+			devices = []string{""}
+		}
+		for _, device := range devices {
+			if err := ios.InstallApp(device); err != nil {
+				return err
+			}
+			if err := ios.LaunchApp(device); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pushAssets copies the assets in assetsDir to the shells running on the
+// devices and reloads them
+func (s *devSession) pushAssets(assetsDir string) error {
+	switch s.platform {
+	case "android":
+		android := s.android()
+		for _, device := range s.devices {
+			if err := android.PushAssets(device, assetsDir); err != nil {
+				return fmt.Errorf("%s: %w", device, err)
+			}
+		}
+	case "ios":
+		ios := s.ios()
+		// An empty device ID targets the booted simulator
+		devices := s.devices
+		if len(devices) == 0 {
+			devices = []string{""}
+		}
+		for _, device := range devices {
+			if err := ios.PushAssets(device, assetsDir); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// nativeIgnore lists the build outputs and IDE files of the shells, their
+// .gitignore files add the files generated by velo
+var nativeIgnore = []string{
+	"build/", ".gradle/", ".cxx/", ".idea/", "*.iml", "local.properties",
+	"*.xcodeproj/", "xcuserdata/", "DerivedData/", ".DS_Store",
+}
+
+// nativeEvents returns the changes of the native sources, nil when there is
+// no shell to rebuild
+func nativeEvents(w *watcher.Watcher) chan []watcher.Event {
+	if w == nil {
+		return nil
+	}
+	return w.Events
+}
+
+// watchNative starts watching the native sources of the shell and the
+// configuration, it returns nil for the web platform
+func (s *devSession) watchNative() (*watcher.Watcher, error) {
+	var shellDir string
+	switch s.platform {
+	case "android":
+		shellDir = s.android().ShellDir
+	case "ios":
+		shellDir = s.ios().ShellDir
+	default:
+		return nil, nil
+	}
+
+	paths := []string{shellDir}
+	if _, err := os.Stat(filepath.Join(s.rootDir, config.FileName)); err == nil {
+		paths = append(paths, filepath.Join(s.rootDir, config.FileName))
+	}
+	ignore, err := watcher.ReadIgnoreFile(filepath.Join(shellDir, ".gitignore"))
+	if err != nil {
+		return nil, err
+	}
+	w, err := watcher.New(paths, watcher.Options{Ignore: append(nativeIgnore, ignore...)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch the %s shell: %w", s.platform, err)
+	}
+	if w.Polling() {
+		fmt.Println("Watching the native sources by polling")
+	}
+	return w, nil
+}
+
+// rebuildShells reloads velo.json and rebuilds, reinstalls and relaunches the
+// shells after native sources or the configuration changed. The dev settings
+// stay those velo dev started with.
+func (s *devSession) rebuildShells(w *watcher.Watcher, events []watcher.Event, devServerURL string) {
+	var paths []string
+	for _, e := range events {
+		rel, _ := filepath.Rel(s.rootDir, e.Path)
+		paths = append(paths, rel)
+	}
+	fmt.Printf("Changed: %s\n", strings.Join(paths, ", "))
+
+	cfg, err := config.Load(s.rootDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Not rebuilding the shell: %v\n", err)
+		return
+	}
+	if cfg.Dev != s.fileDev {
+		fmt.Println("The dev settings changed, restart velo dev to apply them")
+	}
+	cfg.Dev = s.cfg.Dev
+	s.cfg = cfg
+
+	fmt.Printf("Native sources changed, rebuilding the %s shell...\n", s.platform)
+	if err := s.installShells(devServerURL); err != nil {
+		fmt.Fprintf(os.Stderr, "Rebuilding the shell failed: %v\n", err)
+	}
+	drainEvents(w)
+}
+
+// drainEvents drops the changes made by a rebuild itself, such as the files
+// of the shell generated from velo.json
+func drainEvents(w *watcher.Watcher) {
+	for {
+		select {
+		case <-w.Events:
+		case <-time.After(watcher.DefaultInterval + 2*watcher.DefaultDebounce):
+			return
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/velogo-dev/velo/pkg/config"
+)
+
+func TestParseDevArgs(t *testing.T) {
+	cfg := config.Default()
+	c := &command{Args: []string{"dev", "-h", "0.0.0.0", "-p", "4000", "--platform", "web"}}
+	opts, err := c.parseDevArgs(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Dev.Host != "0.0.0.0" || cfg.Dev.Port != 4000 || opts.platform != "web" || opts.help {
+		t.Errorf("host %q, port %d, platform %q, help %t", cfg.Dev.Host, cfg.Dev.Port, opts.platform, opts.help)
+	}
+
+	c.Args = []string{"dev", "--help"}
+	if opts, err = c.parseDevArgs(config.Default()); err != nil || !opts.help {
+		t.Errorf("--help: help %t, %v", opts != nil && opts.help, err)
+	}
+
+	// A platform given without its flag
+	c.Args = []string{"dev", "ios"}
+	if _, err := c.parseDevArgs(config.Default()); err == nil {
+		t.Error("leftover argument accepted")
+	}
+}
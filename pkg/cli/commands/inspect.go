@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/velogo-dev/velo/pkg/apksign"
+	"github.com/velogo-dev/velo/pkg/builder"
+	"github.com/velogo-dev/velo/pkg/config"
+)
+
+// InspectCommand implements the 'inspect' command that shows what is inside
+// an APK or app bundle
+//
+// Command syntax:
+//
+//	velo inspect [<apk|aab>] [--release] [--format apk|aab]
+//
+// Without a file, the artifact of the last Android build of the project is used.
+func (c *command) InspectCommand() error {
+	var (
+		artifact string
+		release  bool
+		format   = builder.FormatAPK
+	)
+
+	for i := 1; i < len(c.Args); i++ {
+		switch c.Args[i] {
+		case "--release", "-r":
+			release = true
+		case "--format", "-f":
+			if i+1 < len(c.Args) {
+				format = c.Args[i+1]
+				i++
+			}
+		default:
+			artifact = c.Args[i]
+		}
+	}
+
+	if artifact == "" {
+		rootDir, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+		cfg, err := config.Load(rootDir)
+		if err != nil {
+			return err
+		}
+		android := builder.NewAndroid(rootDir, cfg)
+		android.Release = release
+		android.Format = format
+		if artifact, err = android.FindArtifact(); err != nil {
+			fmt.Println("Usage: velo inspect <apk|aab>")
+			return err
+		}
+	}
+
+	info, err := builder.Inspect(artifact)
+	if err != nil {
+		return err
+	}
+	printArtifactInfo(info)
+	return nil
+}
+
+func printArtifactInfo(info *builder.ArtifactInfo) {
+	fmt.Printf("Artifact:     %s (%s, %s)\n", info.Path, info.Format, formatSize(uint64(info.Size)))
+	fmt.Printf("Package:      %s\n", info.Package)
+	if info.Label != "" {
+		fmt.Printf("Label:        %s\n", info.Label)
+	}
+	fmt.Printf("Version:      %s (code %s)\n", info.VersionName, info.VersionCode)
+	fmt.Printf("SDK:          min %s, target %s\n", info.MinSDK, info.TargetSDK)
+
+	fmt.Printf("\nPermissions (%d):\n", len(info.Permissions))
+	for _, permission := range info.Permissions {
+		fmt.Printf("  %s\n", permission)
+	}
+
+	fmt.Printf("\nActivities (%d):\n", len(info.Activities))
+	for _, activity := range info.Activities {
+		exported := ""
+		if activity.Exported != "" {
+			exported = " exported=" + activity.Exported
+		}
+		fmt.Printf("  %s%s\n", activity.Name, exported)
+		for _, filter := range activity.IntentFilters {
+			fmt.Println("    intent-filter:")
+			printList("action", filter.Actions)
+			printList("category", filter.Categories)
+			printList("data", filter.Data)
+		}
+	}
+
+	fmt.Println("\nSignatures:")
+	schemes := []struct {
+		name   string
+		result apksign.SchemeResult
+	}{
+		{"v1 (JAR)", info.Signing.V1},
+		{"v2", info.Signing.V2},
+		{"v3", info.Signing.V3},
+	}
+	printed := map[string]bool{}
+	for _, s := range schemes {
+		switch {
+		case !s.result.Present:
+			fmt.Printf("  %-9s not signed\n", s.name)
+			continue
+		case s.result.Verified:
+			fmt.Printf("  %-9s verified\n", s.name)
+		default:
+			fmt.Printf("  %-9s FAILED: %v\n", s.name, s.result.Error)
+		}
+		for _, cert := range s.result.Certificates {
+			digest := fmt.Sprintf("%x", apksign.CertificateDigest(cert))
+			if !printed[digest] {
+				printed[digest] = true
+				fmt.Printf("    %s\n    SHA-256 %s\n", cert.Subject, digest)
+			}
+		}
+	}
+
+	fmt.Println("\nSize by directory:")
+	fmt.Printf("  %-20s %6s %12s %12s\n", "Directory", "Files", "Compressed", "Raw")
+	for _, size := range info.Sizes {
+		fmt.Printf("  %-20s %6d %12s %12s\n", size.Name, size.Files, formatSize(size.Compressed), formatSize(size.Uncompressed))
+	}
+}
+
+func printList(label string, values []string) {
+	if len(values) > 0 {
+		fmt.Printf("      %-9s %s\n", label+":", strings.Join(values, ", "))
+	}
+}
+
+// formatSize renders a byte count in B, KB or MB
+func formatSize(size uint64) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.2f MB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	}
+	return fmt.Sprintf("%d B", size)
+}
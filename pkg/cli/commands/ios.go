@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/velogo-dev/velo/pkg/builder"
+	"github.com/velogo-dev/velo/pkg/config"
+	"github.com/velogo-dev/velo/pkg/provisioning"
+)
+
+// IOSCommand implements the 'ios' command with iOS specific tooling
+//
+// Command syntax:
+//
+//	velo ios project [--env <environment>]
+//	velo ios profiles inspect <file.mobileprovision>
+func (c *command) IOSCommand() error {
+	if len(c.Args) < 2 {
+		fmt.Println("Usage: velo ios [project|profiles]")
+		return fmt.Errorf("missing argument")
+	}
+
+	switch c.Args[1] {
+	case "project":
+		return generateIOSProject(environmentArg(c.Args[2:]))
+	case "profiles":
+		if len(c.Args) < 4 || c.Args[2] != "inspect" {
+			fmt.Println("Usage: velo ios profiles inspect <file.mobileprovision>")
+			return fmt.Errorf("missing argument")
+		}
+		return inspectProfile(c.Args[3])
+	default:
+		fmt.Printf("Unknown argument for 'ios' command: %s\n", c.Args[1])
+		fmt.Println("Usage: velo ios [project|profiles]")
+		return fmt.Errorf("unknown argument")
+	}
+}
+
+// generateIOSProject regenerates the Xcode project of the iOS shell for the
+// given build environment
+func generateIOSProject(environment string) error {
+	rootDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	cfg, err := config.Load(rootDir)
+	if err != nil {
+		return err
+	}
+
+	ios := builder.NewIOS(rootDir, cfg)
+	ios.Environment = environment
+	if err := ios.GenerateProject(); err != nil {
+		return err
+	}
+	fmt.Printf("Xcode project written to %s\n", ios.XcodeProjectPath)
+	return nil
+}
+
+// inspectProfile prints the contents of a provisioning profile
+func inspectProfile(path string) error {
+	profile, err := provisioning.ParseFile(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Profile:      %s\n", profile.Name)
+	fmt.Printf("UUID:         %s\n", profile.UUID)
+	fmt.Printf("Type:         %s\n", profile.Type())
+	fmt.Printf("Team:         %s (%s)\n", profile.TeamName, strings.Join(profile.TeamIDs, ", "))
+	fmt.Printf("App ID:       %s (%s)\n", profile.AppIDPattern(), profile.AppIDName)
+	if len(profile.Platforms) > 0 {
+		fmt.Printf("Platforms:    %s\n", strings.Join(profile.Platforms, ", "))
+	}
+	fmt.Printf("Created:      %s\n", profile.CreationDate.Format("2006-01-02"))
+	if profile.Expired(time.Now()) {
+		fmt.Printf("Expires:      %s (EXPIRED)\n", profile.Expiration.Format("2006-01-02"))
+	} else {
+		days := int(time.Until(profile.Expiration).Hours() / 24)
+		fmt.Printf("Expires:      %s (%d days left)\n", profile.Expiration.Format("2006-01-02"), days)
+	}
+	if profile.Signer != nil {
+		fmt.Printf("Signed by:    %s\n", profile.Signer.Subject)
+	}
+
+	fmt.Println("\nEntitlements:")
+	for _, key := range profile.EntitlementKeys() {
+		fmt.Printf("  %s: %v\n", key, profile.Entitlements[key])
+	}
+
+	switch {
+	case profile.ProvisionsAllDevices:
+		fmt.Println("\nDevices: all devices")
+	case len(profile.Devices) > 0:
+		fmt.Printf("\nDevices (%d):\n", len(profile.Devices))
+		for _, udid := range profile.Devices {
+			fmt.Printf("  %s\n", udid)
+		}
+	}
+
+	for n, cert := range profile.Certificates {
+		fmt.Printf("\nCertificate #%d:\n", n+1)
+		printCertificate(cert)
+	}
+	return nil
+}
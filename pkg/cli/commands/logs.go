@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/velogo-dev/velo/pkg/builder"
+	"github.com/velogo-dev/velo/pkg/config"
+	"github.com/velogo-dev/velo/pkg/logcat"
+)
+
+// LogsCommand implements the 'logs' command streaming the log of the app
+//
+// Command syntax:
+//
+//	velo logs android [--device <device-id>] [--env <environment>] [--level <level>] [--json]
+//
+// The log is filtered to the processes of the app of the build environment,
+// the dev environment of velo dev by default, and follows the app when it
+// restarts.
+func (c *command) LogsCommand() error {
+	if len(c.Args) < 2 || c.Args[1] != "android" {
+		fmt.Println("Usage: velo logs android [--device <device-id>] [--env <environment>] [--level <level>] [--json]")
+		if len(c.Args) < 2 {
+			return fmt.Errorf("missing platform")
+		}
+		return fmt.Errorf("unsupported platform: %s", c.Args[1])
+	}
+
+	var (
+		device      string
+		environment string
+		level       = logcat.Verbose
+		jsonOutput  bool
+	)
+	for i := 2; i < len(c.Args); i++ {
+		switch c.Args[i] {
+		case "--device", "-d":
+			if i+1 < len(c.Args) {
+				device = c.Args[i+1]
+				i++
+			}
+		case "--env", "-e":
+			if i+1 < len(c.Args) {
+				environment = c.Args[i+1]
+				i++
+			}
+		case "--level", "-l":
+			if i+1 < len(c.Args) {
+				l, err := logcat.ParseLevel(c.Args[i+1])
+				if err != nil {
+					return err
+				}
+				level = l
+				i++
+			}
+		case "--json":
+			jsonOutput = true
+		default:
+			return fmt.Errorf("unknown argument for 'logs' command: %s", c.Args[i])
+		}
+	}
+
+	rootDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	cfg, err := config.Load(rootDir)
+	if err != nil {
+		return err
+	}
+	if environment == "" {
+		environment = devEnvironment(cfg)
+	}
+	android := builder.NewAndroid(rootDir, cfg)
+	android.Environment = environment
+
+	if device == "" {
+		devices, err := android.Devices()
+		if err != nil {
+			return err
+		}
+		switch len(devices) {
+		case 0:
+			return fmt.Errorf("no Android device or emulator is connected")
+		case 1:
+			device = devices[0]
+		default:
+			return fmt.Errorf("several devices are connected (%s), select one with --device", strings.Join(devices, ", "))
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The JSON lines stay alone on stdout
+	notes := io.Writer(os.Stdout)
+	if jsonOutput {
+		notes = os.Stderr
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	stream := &logcat.Stream{
+		Device:   device,
+		Package:  android.ApplicationID(),
+		MinLevel: level,
+		Backlog:  true,
+		Notify:   func(message string) { fmt.Fprintf(notes, "--- %s\n", message) },
+	}
+	return stream.Run(ctx, func(e logcat.Entry) {
+		if jsonOutput {
+			encoder.Encode(e)
+			return
+		}
+		fmt.Println(formatLogEntry(e))
+	})
+}
+
+var logLevelStyles = map[logcat.Level]lipgloss.Style{
+	logcat.Verbose: lipgloss.NewStyle().Foreground(lipgloss.Color("8")),
+	logcat.Debug:   lipgloss.NewStyle().Foreground(lipgloss.Color("4")),
+	logcat.Info:    lipgloss.NewStyle().Foreground(lipgloss.Color("2")),
+	logcat.Warn:    lipgloss.NewStyle().Foreground(lipgloss.Color("3")),
+	logcat.Error:   lipgloss.NewStyle().Foreground(lipgloss.Color("1")),
+	logcat.Fatal:   lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Bold(true),
+}
+
+// formatLogEntry renders an entry as `time pid level tag: message`
+func formatLogEntry(e logcat.Entry) string {
+	return fmt.Sprintf("%s %5d %s %s: %s", e.Time.Format("15:04:05.000"), e.PID,
+		logLevelStyles[e.Level].Render(e.Level.Letter()), e.Tag, e.Message)
+}
@@ -25,6 +25,13 @@ func WithName(name string) func(*command) {
 	}
 }
 
+// WithArgs sets the command-line arguments, starting with the command name
+func WithArgs(args []string) func(*command) {
+	return func(cmd *command) {
+		cmd.Args = args
+	}
+}
+
 func WithAction(action func() error) func(*command) {
 	return func(cmd *command) {
 		action := func(ctx context.Context) error {
@@ -2,12 +2,26 @@ package commands
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/velogo-dev/velo/pkg/builder"
+	"github.com/velogo-dev/velo/pkg/config"
 	"github.com/velogo-dev/velo/pkg/utils"
 )
 
+// VersionCommand implements the 'version' command
+//
+// Command syntax:
+//
+//	velo version
+//	velo version bump <major|minor|patch|build> [--commit] [--tag]
 func (c *command) VersionCommand() error {
+	if len(c.Args) > 1 && c.Args[1] == "bump" {
+		return bumpVersion(c.Args[2:])
+	}
+
 	latestTag, err := utils.GetLatestGitTag()
 	if err != nil {
 		fmt.Printf("Error getting latest git tag: %s\n", err)
@@ -18,3 +32,88 @@ func (c *command) VersionCommand() error {
 	fmt.Println(titleStyle.Render("✨ Velo CLI " + latestTag + " ✨"))
 	return nil
 }
+
+// bumpVersion increases the app version and build number and writes them to
+// velo.json and every platform file
+func bumpVersion(args []string) error {
+	var (
+		part   string
+		commit bool
+		tag    bool
+	)
+	for _, arg := range args {
+		switch arg {
+		case "--commit":
+			commit = true
+		case "--tag":
+			// A tag needs a commit holding the new version
+			commit, tag = true, true
+		default:
+			part = arg
+		}
+	}
+	if part == "" {
+		fmt.Println("Usage: velo version bump <major|minor|patch|build> [--commit] [--tag]")
+		return fmt.Errorf("missing version part")
+	}
+
+	rootDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	cfg, err := config.Load(rootDir)
+	if err != nil {
+		return err
+	}
+
+	previous := fmt.Sprintf("%s (%d)", cfg.App.Version, cfg.App.Build)
+	if cfg.App.Version, err = builder.BumpVersion(cfg.App.Version, part); err != nil {
+		return err
+	}
+	cfg.App.Build = builder.HighestBuild(rootDir, cfg) + 1
+
+	written, err := builder.WriteVersion(rootDir, cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Version %s -> %s (%d)\n", previous, cfg.App.Version, cfg.App.Build)
+	for _, path := range written {
+		if rel, err := filepath.Rel(rootDir, path); err == nil {
+			path = rel
+		}
+		fmt.Printf("  updated %s\n", path)
+	}
+
+	if !commit {
+		return nil
+	}
+	name := "v" + cfg.App.Version
+	message := fmt.Sprintf("Release %s (build %d)", name, cfg.App.Build)
+	// Generated files git ignores, such as velo.gradle, stay out of the
+	// commit, as do removed files git never tracked
+	var stage []string
+	for _, path := range written {
+		if utils.GitIsIgnored(rootDir, path) {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil && !utils.GitIsTracked(rootDir, path) {
+			continue
+		}
+		stage = append(stage, path)
+	}
+	if err := utils.GitAddFiles(stage...); err != nil {
+		return fmt.Errorf("failed to stage version files: %w", err)
+	}
+	if err := utils.GitCommit(message); err != nil {
+		return fmt.Errorf("failed to commit version bump: %w", err)
+	}
+	fmt.Printf("Committed %q\n", message)
+
+	if tag {
+		if err := utils.GitTag(name, message); err != nil {
+			return fmt.Errorf("failed to create tag %s: %w", name, err)
+		}
+		fmt.Printf("Tagged %s\n", name)
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+// Package config loads the project configuration stored in velo.json.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FileName is the name of the project configuration file at the project root
+const FileName = "velo.json"
+
+// Config represents the velo.json project configuration
+type Config struct {
+	App     App     `json:"app"`
+	Android Android `json:"android"`
+	IOS     IOS     `json:"ios"`
+	Dev     Dev     `json:"dev"`
+	// Environments maps environment names, such as dev, staging and prod, to
+	// the settings a build for that environment uses
+	Environments map[string]Environment `json:"environments"`
+}
+
+// App holds the settings shared by every platform
+type App struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Version is the user visible semantic version, e.g. 1.2.0
+	Version string `json:"version"`
+	// Build is the build number, increased for every store upload
+	Build int `json:"build"`
+	// Permissions maps the permissions the app requests, such as camera or
+	// location, to the reason shown to the user by iOS
+	Permissions map[string]string `json:"permissions"`
+	// URLSchemes are the custom schemes that open the app, e.g. myapp://
+	URLSchemes []string `json:"urlSchemes"`
+	// Orientations lists the supported orientations: portrait,
+	// portrait-upside-down, landscape, landscape-left and landscape-right
+	Orientations []string `json:"orientations"`
+	Splash       Splash   `json:"splash"`
+}
+
+// Splash configures the native splash screen shown until the web app is
+// ready
+type Splash struct {
+	// Background is the #RRGGBB color behind the image
+	Background string `json:"background"`
+	// Image is the path, relative to the project root, of a PNG or JPEG drawn
+	// in the center of the screen
+	Image string `json:"image"`
+	// DarkBackground and DarkImage replace Background and Image in dark mode
+	DarkBackground string `json:"darkBackground"`
+	DarkImage      string `json:"darkImage"`
+	// Timeout is how long, in milliseconds, the splash screen waits for the
+	// web app to signal that it is ready
+	Timeout int `json:"timeout"`
+}
+
+// Dev holds the settings of the dev server used by velo dev. The shells load
+// a proxy on Host:Port which forwards to the framework dev server.
+type Dev struct {
+	// Host is the address the shells load the dev server from
+	Host string `json:"host"`
+	// Port is the port of the dev server, the same for Android and iOS
+	Port int `json:"port"`
+	// FrameworkPort is the port the framework dev server, e.g. Vite, listens
+	// on behind the proxy, Port+1 when 0
+	FrameworkPort int `json:"frameworkPort"`
+}
+
+// URL returns the address the shells load the dev server from
+func (d Dev) URL() string {
+	return "http://" + net.JoinHostPort(d.Host, strconv.Itoa(d.Port))
+}
+
+// Framework returns the port of the framework dev server
+func (d Dev) Framework() int {
+	if d.FrameworkPort != 0 {
+		return d.FrameworkPort
+	}
+	return d.Port + 1
+}
+
+// FrameworkURL returns the address the proxy forwards to
+func (d Dev) FrameworkURL() string {
+	return "http://" + net.JoinHostPort("localhost", strconv.Itoa(d.Framework()))
+}
+
+// Environment holds the settings of a build environment
+type Environment struct {
+	// URLs are the base URLs of the services the app talks to, e.g.
+	// {"api": "https://staging.example.com/api"}
+	URLs map[string]string `json:"urls"`
+	// AppIDSuffix is appended to app.id, e.g. ".staging", so builds of
+	// several environments can be installed side by side
+	AppIDSuffix string `json:"appIdSuffix"`
+	// NameSuffix is appended to the app name shown on the home screen, e.g.
+	// " Staging"
+	NameSuffix string `json:"nameSuffix"`
+	// Features are flags turned on or off per environment
+	Features map[string]bool `json:"features"`
+}
+
+// DefaultEnvironment is used when a build does not select an environment
+const DefaultEnvironment = "prod"
+
+// Environment returns the settings of the named environment. Projects without
+// environments only have the default one, which has no settings.
+func (c *Config) Environment(name string) (Environment, error) {
+	if env, ok := c.Environments[name]; ok {
+		return env, nil
+	}
+	if len(c.Environments) == 0 && name == DefaultEnvironment {
+		return Environment{}, nil
+	}
+
+	names := make([]string, 0, len(c.Environments))
+	for n := range c.Environments {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return Environment{}, fmt.Errorf("unknown environment %q, no environments are defined in %s", name, FileName)
+	}
+	return Environment{}, fmt.Errorf("unknown environment %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// Android holds the Android specific settings
+type Android struct {
+	// MinSDK is the minSdkVersion of the app, which decides the signature schemes
+	MinSDK     int `json:"minSdk"`
+	TargetSDK  int `json:"targetSdk"`
+	CompileSDK int `json:"compileSdk"`
+	// DeepLinks are the https URLs opened by the app, e.g.
+	// https://example.com/app. They are verified as Android App Links.
+	DeepLinks []string `json:"deepLinks"`
+	Signing   Signing  `json:"signing"`
+}
+
+// Signing describes where release signing credentials come from.
+//
+// Credentials are never stored in velo.json or the tracked build.gradle. They are
+// read from environment variables first, then from a gitignored properties file.
+type Signing struct {
+	// PropertiesFile is the path, relative to the project root, of a properties
+	// file with storeFile, storePassword, keyAlias and keyPassword entries
+	PropertiesFile string `json:"propertiesFile"`
+}
+
+// IOS holds the iOS specific settings
+type IOS struct {
+	// DeploymentTarget is the minimum iOS version of the app
+	DeploymentTarget string `json:"deploymentTarget"`
+	// Entitlements are written to the app's .entitlements file, e.g.
+	// {"aps-environment": "production"}
+	Entitlements map[string]any `json:"entitlements"`
+	// BackgroundModes are the UIBackgroundModes of the app, e.g. audio or
+	// remote-notification
+	BackgroundModes []string   `json:"backgroundModes"`
+	Signing         IOSSigning `json:"signing"`
+}
+
+// IOSSigning holds the code signing and export settings of iOS release builds
+type IOSSigning struct {
+	TeamID string `json:"teamId"`
+	// Style is automatic or manual
+	Style string `json:"style"`
+	// ExportMethod is app-store, ad-hoc, enterprise or development
+	ExportMethod string `json:"exportMethod"`
+	// Certificate is the signing certificate used with manual signing,
+	// e.g. "Apple Distribution"
+	Certificate string `json:"certificate"`
+	// ProvisioningProfiles maps bundle IDs to the name or UUID of the
+	// provisioning profile used with manual signing
+	ProvisioningProfiles map[string]string `json:"provisioningProfiles"`
+}
+
+// Default returns the configuration used when no velo.json is present
+func Default() *Config {
+	return &Config{
+		App: App{
+			ID:      "com.example.golangmobile",
+			Name:    "Golang Mobile",
+			Version: "1.0.0",
+			Build:   1,
+			Splash: Splash{
+				Background: "#FFFFFF",
+				Timeout:    5000,
+			},
+		},
+		Dev: Dev{
+			Host: "localhost",
+			Port: 3000,
+		},
+		Android: Android{
+			MinSDK:     21,
+			TargetSDK:  34,
+			CompileSDK: 34,
+			Signing: Signing{
+				PropertiesFile: "keystore.properties",
+			},
+		},
+		IOS: IOS{
+			DeploymentTarget: "13.0",
+			Signing: IOSSigning{
+				Style:        "automatic",
+				ExportMethod: "app-store",
+			},
+		},
+	}
+}
+
+// Load reads velo.json from rootDir on top of the defaults
+func Load(rootDir string) (*Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(filepath.Join(rootDir, FileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", FileName, err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", FileName, err)
+	}
+	return cfg, nil
+}
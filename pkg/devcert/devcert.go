@@ -0,0 +1,310 @@
+// Package devcert creates the local certificate authority of velo dev --https
+// and the certificates of the dev server it signs.
+//
+// The CA is created once per user and kept in the user config directory, so
+// devices trust it once for every project. Its certificate is exported as
+// PEM for the network security config of Android debug builds, and as DER and
+// a configuration profile for iOS.
+package devcert
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+)
+
+// Files of the CA directory
+const (
+	CACertFile       = "ca.pem"
+	CAKeyFile        = "ca-key.pem"
+	CADERFile        = "velo-dev-ca.cer"
+	MobileConfigFile = "velo-dev-ca.mobileconfig"
+)
+
+const (
+	caValidityYears = 10
+	// Apple rejects server certificates valid for more than 398 days
+	leafValidityDays = 397
+	// leafRenewal is how long before expiring a certificate is replaced
+	leafRenewal = 30 * 24 * time.Hour
+)
+
+// CA is the local development certificate authority
+type CA struct {
+	Cert *x509.Certificate
+	Key  crypto.Signer
+	// Dir holds the CA files
+	Dir string
+}
+
+// DefaultDir returns the directory of the CA in the user config directory,
+// e.g. ~/.config/velo/devcert on Linux
+func DefaultDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "velo", "devcert"), nil
+}
+
+// LoadOrCreateCA loads the CA of dir, creating it first when dir holds none.
+// created tells whether a new CA was created, which the devices must trust
+// again.
+func LoadOrCreateCA(dir string) (ca *CA, created bool, err error) {
+	ca, err = loadCA(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		ca, err = createCA(dir)
+		created = true
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	// The exports are refreshed so they always match the CA
+	if err := ca.export(); err != nil {
+		return nil, false, err
+	}
+	return ca, created, nil
+}
+
+func loadCA(dir string) (*CA, error) {
+	certPEM, err := os.ReadFile(filepath.Join(dir, CACertFile))
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := os.ReadFile(filepath.Join(dir, CAKeyFile))
+	if err != nil {
+		return nil, err
+	}
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid development CA in %s: %w", dir, err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok || !cert.IsCA {
+		return nil, fmt.Errorf("invalid development CA in %s", dir)
+	}
+	return &CA{Cert: cert, Key: key, Dir: dir}, nil
+}
+
+func createCA(dir string) (*CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	keyID, err := subjectKeyID(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	notBefore := time.Now().Add(-time.Minute).UTC()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:         "Velo Development CA",
+			Organization:       []string{"Velo development CA"},
+			OrganizationalUnit: []string{owner()},
+		},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.AddDate(caValidityYears, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+		SubjectKeyId:          keyID,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := writeKey(filepath.Join(dir, CAKeyFile), key); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, CACertFile), encodeCert(cert), 0644); err != nil {
+		return nil, err
+	}
+	return &CA{Cert: cert, Key: key, Dir: dir}, nil
+}
+
+// owner names the user and machine of the CA, devices trusting the CAs of
+// several machines tell them apart by it
+func owner() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		name += "@" + host
+	}
+	return name
+}
+
+// PEM returns the certificate of the CA in PEM form
+func (ca *CA) PEM() []byte {
+	return encodeCert(ca.Cert)
+}
+
+// Path returns the path of a file of the CA directory
+func (ca *CA) Path(name string) string {
+	return filepath.Join(ca.Dir, name)
+}
+
+// export writes the certificate as DER, which iOS opens from Files or
+// AirDrop, and as a configuration profile
+func (ca *CA) export() error {
+	profile, err := ca.MobileConfig()
+	if err != nil {
+		return err
+	}
+	exports := map[string][]byte{
+		CADERFile:        ca.Cert.Raw,
+		MobileConfigFile: profile,
+	}
+	for name, content := range exports {
+		if current, err := os.ReadFile(ca.Path(name)); err == nil && bytes.Equal(current, content) {
+			continue
+		}
+		if err := os.WriteFile(ca.Path(name), content, 0644); err != nil {
+			return fmt.Errorf("failed to export the development CA: %w", err)
+		}
+	}
+	return nil
+}
+
+// Leaf returns the certificate of the dev server for hosts, names or IP
+// addresses, kept in dir as cert.pem and key.pem. The certificate is reused
+// while it is signed by the CA, covers every host and is not about to
+// expire, and issued again otherwise.
+func (ca *CA) Leaf(dir string, hosts []string) (tls.Certificate, error) {
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	if pair, err := tls.LoadX509KeyPair(certPath, keyPath); err == nil && ca.covers(pair, hosts) {
+		return pair, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to generate key: %w", err)
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	notBefore := time.Now().Add(-time.Minute).UTC()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:         hosts[0],
+			Organization:       []string{"Velo development certificate"},
+			OrganizationalUnit: []string{owner()},
+		},
+		NotBefore:      notBefore,
+		NotAfter:       notBefore.AddDate(0, 0, leafValidityDays),
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		AuthorityKeyId: ca.Cert.SubjectKeyId,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, key.Public(), ca.Key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := writeKey(keyPath, key); err != nil {
+		return tls.Certificate{}, err
+	}
+	// The chain lets clients verify the certificate without the CA
+	chain := append(encodeCert(cert), ca.PEM()...)
+	if err := os.WriteFile(certPath, chain, 0644); err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair(chain, encodeKeyPEM(key))
+}
+
+// covers reports whether a certificate is signed by the CA, valid for every
+// host and not about to expire
+func (ca *CA) covers(pair tls.Certificate, hosts []string) bool {
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil || time.Until(cert.NotAfter) < leafRenewal {
+		return false
+	}
+	if cert.CheckSignatureFrom(ca.Cert) != nil {
+		return false
+	}
+	for _, host := range hosts {
+		if cert.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+}
+
+func subjectKeyID(pub crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	id := sha1.Sum(der)
+	return id[:], nil
+}
+
+func encodeCert(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+func encodeKeyPEM(key *ecdsa.PrivateKey) []byte {
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+// writeKey writes a private key readable by the user only
+func writeKey(path string, key *ecdsa.PrivateKey) error {
+	return os.WriteFile(path, encodeKeyPEM(key), 0600)
+}
@@ -0,0 +1,239 @@
+package devcert
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"testing"
+
+	"github.com/velogo-dev/velo/pkg/plist"
+)
+
+func testCA(t *testing.T) *CA {
+	t.Helper()
+	ca, created, err := LoadOrCreateCA(filepath.Join(t.TempDir(), "devcert"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Fatal("created = false for an empty directory")
+	}
+	return ca
+}
+
+func TestLoadOrCreateCA(t *testing.T) {
+	ca := testCA(t)
+	if !ca.Cert.IsCA || !ca.Cert.MaxPathLenZero || ca.Cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		t.Errorf("not a CA certificate: IsCA %t, MaxPathLenZero %t", ca.Cert.IsCA, ca.Cert.MaxPathLenZero)
+	}
+	if err := ca.Cert.CheckSignatureFrom(ca.Cert); err != nil {
+		t.Errorf("not self-signed: %v", err)
+	}
+	for _, name := range []string{CACertFile, CAKeyFile, CADERFile, MobileConfigFile} {
+		if _, err := os.Stat(ca.Path(name)); err != nil {
+			t.Error(err)
+		}
+	}
+	if info, err := os.Stat(ca.Path(CAKeyFile)); err == nil && runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("key is readable by others: %v", info.Mode())
+	}
+	if der, err := os.ReadFile(ca.Path(CADERFile)); err != nil || !bytes.Equal(der, ca.Cert.Raw) {
+		t.Errorf("DER export differs from the certificate: %v", err)
+	}
+	if block, _ := pem.Decode(ca.PEM()); block == nil || !bytes.Equal(block.Bytes, ca.Cert.Raw) {
+		t.Error("PEM does not hold the certificate")
+	}
+
+	// The CA is loaded again and the exports are restored
+	if err := os.Remove(ca.Path(CADERFile)); err != nil {
+		t.Fatal(err)
+	}
+	loaded, created, err := LoadOrCreateCA(ca.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || !loaded.Cert.Equal(ca.Cert) {
+		t.Errorf("created %t, same certificate %t", created, loaded.Cert.Equal(ca.Cert))
+	}
+	if _, err := os.Stat(ca.Path(CADERFile)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadInvalidCA(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{CACertFile, CAKeyFile} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("garbage"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := LoadOrCreateCA(dir); err == nil {
+		t.Error("loaded an invalid CA")
+	}
+
+	// A leaf certificate is not a CA
+	ca := testCA(t)
+	leafDir := t.TempDir()
+	if _, err := ca.Leaf(leafDir, []string{"localhost"}); err != nil {
+		t.Fatal(err)
+	}
+	for src, dst := range map[string]string{"cert.pem": CACertFile, "key.pem": CAKeyFile} {
+		data, err := os.ReadFile(filepath.Join(leafDir, src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, dst), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := LoadOrCreateCA(dir); err == nil {
+		t.Error("loaded a leaf certificate as CA")
+	}
+}
+
+func TestLeaf(t *testing.T) {
+	ca := testCA(t)
+	dir := t.TempDir()
+	hosts := []string{"localhost", "192.168.1.20"}
+	pair, err := ca.Leaf(dir, hosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+	for _, host := range hosts {
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots}); err != nil {
+			t.Errorf("%s: %v", host, err)
+		}
+	}
+	if len(pair.Certificate) != 2 || !bytes.Equal(pair.Certificate[1], ca.Cert.Raw) {
+		t.Error("the chain does not include the CA")
+	}
+	if days := leaf.NotAfter.Sub(leaf.NotBefore).Hours() / 24; days > 398 {
+		t.Errorf("valid for %.0f days, Apple accepts 398", days)
+	}
+
+	// The certificate is reused while it covers the hosts
+	again, err := ca.Leaf(dir, []string{"192.168.1.20"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again.Certificate[0], pair.Certificate[0]) {
+		t.Error("certificate issued again for a covered host")
+	}
+	other, err := ca.Leaf(dir, []string{"localhost", "10.0.0.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(other.Certificate[0], pair.Certificate[0]) {
+		t.Error("certificate reused for a new host")
+	}
+	// and while it is signed by the CA
+	newCA := testCA(t)
+	renewed, err := newCA.Leaf(dir, []string{"localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(renewed.Certificate[0], other.Certificate[0]) {
+		t.Error("certificate of another CA reused")
+	}
+}
+
+// A client trusting the CA connects to a server with the leaf certificate
+func TestHandshake(t *testing.T) {
+	ca := testCA(t)
+	pair, err := ca.Leaf(t.TempDir(), []string{"127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{pair}}
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.PEM())
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestMobileConfig(t *testing.T) {
+	ca := testCA(t)
+	data, err := ca.MobileConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := plist.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := v.(map[string]any)
+	payloads, _ := profile["PayloadContent"].([]any)
+	if len(payloads) != 1 {
+		t.Fatalf("%d payloads", len(payloads))
+	}
+	root := payloads[0].(map[string]any)
+	if root["PayloadType"] != "com.apple.security.root" {
+		t.Errorf("PayloadType = %v", root["PayloadType"])
+	}
+	if der, _ := root["PayloadContent"].([]byte); !bytes.Equal(der, ca.Cert.Raw) {
+		t.Error("payload does not hold the CA certificate")
+	}
+
+	uuid := regexp.MustCompile(`^[0-9A-F]{8}-[0-9A-F]{4}-4[0-9A-F]{3}-[89AB][0-9A-F]{3}-[0-9A-F]{12}$`)
+	for _, id := range []any{profile["PayloadUUID"], root["PayloadUUID"]} {
+		if s, _ := id.(string); !uuid.MatchString(s) {
+			t.Errorf("invalid UUID %v", id)
+		}
+	}
+	if profile["PayloadUUID"] == root["PayloadUUID"] {
+		t.Error("profile and payload share a UUID")
+	}
+	// The profile is the same every time for a CA
+	if again, _ := ca.MobileConfig(); !bytes.Equal(again, data) {
+		t.Error("MobileConfig is not stable")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	ca := testCA(t)
+	server := httptest.NewServer(ca.Handler())
+	defer server.Close()
+
+	for name, contentType := range exportTypes {
+		resp, err := http.Get(server.URL + "/velo/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != contentType {
+			t.Errorf("%s: %s, %s", name, resp.Status, resp.Header.Get("Content-Type"))
+		}
+	}
+	// The private key is never served
+	for _, path := range []string{"/" + CAKeyFile, "/velo/" + CAKeyFile, "/"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: %s", path, resp.Status)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package devcert
+
+import (
+	"net/http"
+	"path"
+)
+
+// exportTypes are the content types of the CA files served by Handler, iOS
+// only offers to install profiles served as Apple configurations
+var exportTypes = map[string]string{
+	CACertFile:       "application/x-pem-file",
+	CADERFile:        "application/x-x509-ca-cert",
+	MobileConfigFile: "application/x-apple-aspen-config",
+}
+
+// Handler serves the certificate of the CA as PEM, DER and configuration
+// profile by file name, so devices download it from the dev server. The
+// private key is never served.
+func (ca *CA) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Base(r.URL.Path)
+		contentType, ok := exportTypes[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+		http.ServeFile(w, r, ca.Path(name))
+	})
+}
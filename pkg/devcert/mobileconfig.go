@@ -0,0 +1,53 @@
+package devcert
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/velogo-dev/velo/pkg/plist"
+)
+
+// profileIdentifier is the identifier of the configuration profile, a new
+// CA replaces the profile of the previous one on the device
+const profileIdentifier = "dev.velo.devcert"
+
+// MobileConfig returns a configuration profile installing the CA as a root
+// certificate on iOS. After installing it the CA must still be enabled in
+// Settings > General > About > Certificate Trust Settings.
+func (ca *CA) MobileConfig() ([]byte, error) {
+	name := ca.Cert.Subject.CommonName
+	if len(ca.Cert.Subject.OrganizationalUnit) > 0 {
+		name += " (" + ca.Cert.Subject.OrganizationalUnit[0] + ")"
+	}
+	profile := map[string]any{
+		"PayloadContent": []any{
+			map[string]any{
+				"PayloadCertificateFileName": CADERFile,
+				"PayloadContent":             ca.Cert.Raw,
+				"PayloadDescription":         "Adds the CA root certificate of velo dev --https",
+				"PayloadDisplayName":         name,
+				"PayloadIdentifier":          profileIdentifier + ".root",
+				"PayloadType":                "com.apple.security.root",
+				"PayloadUUID":                ca.uuid("root"),
+				"PayloadVersion":             1,
+			},
+		},
+		"PayloadDescription":       "Trusts the HTTPS dev server of Velo. Only install it on your own devices.",
+		"PayloadDisplayName":       name,
+		"PayloadIdentifier":        profileIdentifier,
+		"PayloadRemovalDisallowed": false,
+		"PayloadType":              "Configuration",
+		"PayloadUUID":              ca.uuid("profile"),
+		"PayloadVersion":           1,
+	}
+	return plist.Marshal(profile)
+}
+
+// uuid derives a stable version 4 style UUID from the CA certificate, the
+// profile is the same every time it is exported
+func (ca *CA) uuid(name string) string {
+	b := sha256.Sum256(append(ca.Cert.Raw, name...))
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%X-%X-%X-%X-%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
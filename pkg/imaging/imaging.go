@@ -0,0 +1,292 @@
+// Package imaging resizes and composes the raster images Velo generates,
+// such as app icons and splash screens, with the standard library only.
+//
+// Images are processed as premultiplied *image.RGBA so that transparent
+// edges do not darken when scaled.
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Load decodes the PNG or JPEG image at path
+func Load(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// EncodePNG returns img encoded as PNG
+func EncodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SavePNG writes img to path, creating parent directories as needed
+func SavePNG(path string, img image.Image) error {
+	data, err := EncodePNG(img)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// toRGBA returns img as an *image.RGBA with bounds starting at 0,0
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	if rgba, ok := img.(*image.RGBA); ok && bounds.Min == (image.Point{}) {
+		return rgba
+	}
+	out := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(out, out.Bounds(), img, bounds.Min, draw.Src)
+	return out
+}
+
+// Resize scales img to width x height. Every destination pixel is the
+// average of the source area it covers, which gives smooth results for the
+// large reductions of icon generation.
+func Resize(img image.Image, width, height int) *image.RGBA {
+	src := toRGBA(img)
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if sw == width && sh == height {
+		return src
+	}
+
+	// Horizontal then vertical pass over float channels
+	columns := weights(sw, width)
+	rows := weights(sh, height)
+	tmp := make([]float64, width*sh*4)
+	for y := 0; y < sh; y++ {
+		line := src.Pix[y*src.Stride:]
+		for x, taps := range columns {
+			var acc [4]float64
+			for _, t := range taps {
+				p := line[t.index*4:]
+				for c := 0; c < 4; c++ {
+					acc[c] += float64(p[c]) * t.weight
+				}
+			}
+			copy(tmp[(y*width+x)*4:], acc[:])
+		}
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y, taps := range rows {
+		for x := 0; x < width; x++ {
+			var acc [4]float64
+			for _, t := range taps {
+				p := tmp[(t.index*width+x)*4:]
+				for c := 0; c < 4; c++ {
+					acc[c] += p[c] * t.weight
+				}
+			}
+			o := out.Pix[y*out.Stride+x*4:]
+			for c := 0; c < 4; c++ {
+				o[c] = clamp(acc[c])
+			}
+		}
+	}
+	return out
+}
+
+type tap struct {
+	index  int
+	weight float64
+}
+
+// weights returns, for each of the n destination pixels, the source pixels it
+// covers and how much of each
+func weights(size, n int) [][]tap {
+	scale := float64(size) / float64(n)
+	out := make([][]tap, n)
+	for i := range out {
+		start := float64(i) * scale
+		end := start + scale
+		if scale < 1 {
+			// Enlarging: sample the nearest source pixel
+			out[i] = []tap{{index: int(start + scale/2), weight: 1}}
+			continue
+		}
+		for j := int(start); j < size && float64(j) < end; j++ {
+			cover := math.Min(end, float64(j+1)) - math.Max(start, float64(j))
+			if cover > 0 {
+				out[i] = append(out[i], tap{index: j, weight: cover / scale})
+			}
+		}
+	}
+	return out
+}
+
+func clamp(v float64) uint8 {
+	v = math.Round(v)
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint8(v)
+}
+
+// Fit scales img to fit in size x size while keeping its aspect ratio
+func Fit(img image.Image, size int) *image.RGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	scale := float64(size) / float64(max(w, h))
+	return Resize(img, max(1, int(math.Round(float64(w)*scale))), max(1, int(math.Round(float64(h)*scale))))
+}
+
+// Place draws img scaled to fit in size x size centered on a transparent
+// canvas of canvas x canvas pixels
+func Place(img image.Image, size, canvas int) *image.RGBA {
+	out := image.NewRGBA(image.Rect(0, 0, canvas, canvas))
+	fitted := Fit(img, size)
+	x := (canvas - fitted.Bounds().Dx()) / 2
+	y := (canvas - fitted.Bounds().Dy()) / 2
+	draw.Draw(out, fitted.Bounds().Add(image.Pt(x, y)), fitted, image.Point{}, draw.Over)
+	return out
+}
+
+// Flatten draws img over a solid background, removing all transparency
+func Flatten(img image.Image, background color.Color) *image.RGBA {
+	src := toRGBA(img)
+	out := image.NewRGBA(src.Bounds())
+	draw.Draw(out, out.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	draw.Draw(out, out.Bounds(), src, image.Point{}, draw.Over)
+	return out
+}
+
+// Opaque reports whether every pixel of img is fully opaque
+func Opaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0xffff {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// CircleMask returns img clipped to the circle inscribed in its bounds, with
+// anti-aliased edges
+func CircleMask(img image.Image) *image.RGBA {
+	src := toRGBA(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	out := image.NewRGBA(src.Bounds())
+	cx, cy := float64(w)/2, float64(h)/2
+	r := math.Min(cx, cy)
+
+	const samples = 4
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			inside := 0
+			for sy := 0; sy < samples; sy++ {
+				for sx := 0; sx < samples; sx++ {
+					dx := float64(x) + (float64(sx)+0.5)/samples - cx
+					dy := float64(y) + (float64(sy)+0.5)/samples - cy
+					if dx*dx+dy*dy <= r*r {
+						inside++
+					}
+				}
+			}
+			if inside == 0 {
+				continue
+			}
+			coverage := float64(inside) / samples / samples
+			i := y*src.Stride + x*4
+			for c := 0; c < 4; c++ {
+				out.Pix[i+c] = clamp(float64(src.Pix[i+c]) * coverage)
+			}
+		}
+	}
+	return out
+}
+
+// ParseHexColor parses #RGB, #RRGGBB or #AARRGGBB, the forms used by Android
+// color resources
+func ParseHexColor(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex = "ff" + hex
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 8 || err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q, expected #RRGGBB", s)
+	}
+	return color.NRGBA{A: uint8(v >> 24), R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v)}, nil
+}
+
+// HexColor formats c as #RRGGBB
+func HexColor(c color.NRGBA) string {
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+}
+
+// EncodeICO returns a .ico file holding each image as a PNG entry. Images
+// must be at most 256 pixels wide.
+func EncodeICO(images ...image.Image) ([]byte, error) {
+	var entries [][]byte
+	for _, img := range images {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		entries = append(entries, buf.Bytes())
+	}
+
+	var out bytes.Buffer
+	// ICONDIR: reserved, type 1 (icon), image count
+	binary.Write(&out, binary.LittleEndian, [3]uint16{0, 1, uint16(len(images))})
+	offset := 6 + 16*len(images)
+	for i, img := range images {
+		w, h := img.Bounds().Dx(), img.Bounds().Dy()
+		if w > 256 || h > 256 {
+			return nil, fmt.Errorf("icon images must be at most 256 pixels, got %dx%d", w, h)
+		}
+		// A size of 0 stands for 256
+		out.WriteByte(byte(w % 256))
+		out.WriteByte(byte(h % 256))
+		out.Write([]byte{0, 0})                             // palette size, reserved
+		binary.Write(&out, binary.LittleEndian, uint16(1))  // color planes
+		binary.Write(&out, binary.LittleEndian, uint16(32)) // bits per pixel
+		binary.Write(&out, binary.LittleEndian, uint32(len(entries[i])))
+		binary.Write(&out, binary.LittleEndian, uint32(offset))
+		offset += len(entries[i])
+	}
+	for _, entry := range entries {
+		out.Write(entry)
+	}
+	return out.Bytes(), nil
+}
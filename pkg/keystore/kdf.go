@@ -0,0 +1,78 @@
+package keystore
+
+import (
+	"hash"
+	"math/big"
+	"unicode/utf16"
+)
+
+// bmpString encodes s as a null terminated UTF-16 big-endian string, the
+// password encoding used by the PKCS#12 key derivation function.
+func bmpString(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	out := make([]byte, 0, 2*len(units)+2)
+	for _, u := range units {
+		out = append(out, byte(u>>8), byte(u))
+	}
+	return append(out, 0, 0)
+}
+
+// pkcs12KDF implements the key derivation of RFC 7292 appendix B.2.
+//
+// id selects the purpose of the derived bytes: 1 for cipher keys, 2 for IVs
+// and 3 for MAC keys. password must already be BMP encoded.
+func pkcs12KDF(newHash func() hash.Hash, id byte, password, salt []byte, iterations, size int) []byte {
+	h := newHash()
+	v := h.BlockSize()
+
+	d := make([]byte, v)
+	for i := range d {
+		d[i] = id
+	}
+
+	fill := func(src []byte) []byte {
+		if len(src) == 0 {
+			return nil
+		}
+		out := make([]byte, v*((len(src)+v-1)/v))
+		for i := range out {
+			out[i] = src[i%len(src)]
+		}
+		return out
+	}
+	i := append(fill(salt), fill(password)...)
+
+	one := big.NewInt(1)
+	out := make([]byte, 0, size)
+	for len(out) < size {
+		h.Reset()
+		h.Write(d)
+		h.Write(i)
+		a := h.Sum(nil)
+		for r := 1; r < iterations; r++ {
+			h.Reset()
+			h.Write(a)
+			a = h.Sum(a[:0])
+		}
+		out = append(out, a...)
+
+		if len(out) >= size {
+			break
+		}
+
+		// I_j = (I_j + B + 1) mod 2^(v*8) for every v-byte block of I
+		b := new(big.Int).SetBytes(fill(a)[:v])
+		b.Add(b, one)
+		for j := 0; j < len(i); j += v {
+			block := new(big.Int).SetBytes(i[j : j+v])
+			block.Add(block, b)
+			sum := block.Bytes()
+			if len(sum) > v {
+				sum = sum[len(sum)-v:]
+			}
+			clear(i[j : j+v])
+			copy(i[j+v-len(sum):j+v], sum)
+		}
+	}
+	return out[:size]
+}
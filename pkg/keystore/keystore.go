@@ -0,0 +1,254 @@
+// Package keystore creates and reads PKCS#12 keystores used to sign Android apps.
+//
+// It replaces the JDK keytool for the common cases: generating a signing key
+// with a self-signed certificate and listing the certificates of a keystore.
+package keystore
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// Key algorithms supported by Generate
+const (
+	RSA = "RSA"
+	EC  = "EC"
+)
+
+// Entry is a private key and its certificate chain stored under an alias.
+// Trusted certificate entries have no private key.
+type Entry struct {
+	Alias        string
+	PrivateKey   crypto.Signer
+	Certificates []*x509.Certificate
+}
+
+// Certificate returns the leaf certificate of the entry
+func (e *Entry) Certificate() *x509.Certificate {
+	if len(e.Certificates) == 0 {
+		return nil
+	}
+	return e.Certificates[0]
+}
+
+// Options configures the key generated by Generate
+type Options struct {
+	Alias string
+	// DName is the distinguished name in keytool syntax, e.g. "CN=Jane, O=Acme, C=US"
+	DName string
+	// ValidityDays is how long the certificate is valid. Google Play requires
+	// upload keys to be valid until at least 2033.
+	ValidityDays int
+	// KeyAlgorithm is RSA or EC
+	KeyAlgorithm string
+	// KeySize is the RSA modulus size in bits or the EC curve size (256, 384, 521)
+	KeySize int
+}
+
+// Generate creates a new key pair with a self-signed certificate
+func Generate(opts Options) (*Entry, error) {
+	if opts.Alias == "" {
+		return nil, fmt.Errorf("alias is required")
+	}
+	if opts.ValidityDays <= 0 {
+		return nil, fmt.Errorf("validity must be a positive number of days")
+	}
+	subject, err := ParseDName(opts.DName)
+	if err != nil {
+		return nil, err
+	}
+
+	var key crypto.Signer
+	switch strings.ToUpper(opts.KeyAlgorithm) {
+	case RSA, "":
+		size := opts.KeySize
+		if size == 0 {
+			size = 2048
+		}
+		if size < 2048 {
+			return nil, fmt.Errorf("RSA keys must be at least 2048 bits")
+		}
+		key, err = rsa.GenerateKey(rand.Reader, size)
+	case EC:
+		var curve elliptic.Curve
+		switch opts.KeySize {
+		case 0, 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported EC key size %d, expected 256, 384 or 521", opts.KeySize)
+		}
+		key, err = ecdsa.GenerateKey(curve, rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %q, expected %s or %s", opts.KeyAlgorithm, RSA, EC)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 63))
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	keyID := sha1.Sum(publicKey)
+
+	notBefore := time.Now().Add(-time.Minute).UTC()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      subject,
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.AddDate(0, 0, opts.ValidityDays),
+		SubjectKeyId: keyID[:],
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Entry{
+		Alias:        opts.Alias,
+		PrivateKey:   key,
+		Certificates: []*x509.Certificate{cert},
+	}, nil
+}
+
+// Load reads and decrypts the keystore at path
+func Load(path, password string) ([]Entry, error) {
+	return LoadWithKeyPassword(path, password, password)
+}
+
+// LoadWithKeyPassword reads the keystore at path and decrypts its private
+// keys with keyPassword
+func LoadWithKeyPassword(path, password, keyPassword string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := DecodeWithKeyPassword(data, password, keyPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// Save writes entries to a new keystore at path
+func Save(path, password string, entries []Entry) error {
+	data, err := Encode(entries, password)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Find returns the entry stored under alias, matched case-insensitively like keytool
+func Find(entries []Entry, alias string) (*Entry, error) {
+	for i := range entries {
+		if strings.EqualFold(entries[i].Alias, alias) {
+			return &entries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("alias %q not found in keystore", alias)
+}
+
+// Fingerprint formats a digest as colon separated upper-case hex, the form
+// shown by keytool and expected by the Play Console and assetlinks.json
+func Fingerprint(digest []byte) string {
+	encoded := strings.ToUpper(hex.EncodeToString(digest))
+	parts := make([]string, 0, len(digest))
+	for i := 0; i < len(encoded); i += 2 {
+		parts = append(parts, encoded[i:i+2])
+	}
+	return strings.Join(parts, ":")
+}
+
+// SHA1Fingerprint returns the SHA-1 fingerprint of a certificate
+func SHA1Fingerprint(cert *x509.Certificate) string {
+	sum := sha1.Sum(cert.Raw)
+	return Fingerprint(sum[:])
+}
+
+// SHA256Fingerprint returns the SHA-256 fingerprint of a certificate
+func SHA256Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return Fingerprint(sum[:])
+}
+
+// ParseDName parses a distinguished name in keytool syntax such as
+// "CN=Jane Doe, OU=Mobile, O=Acme, L=Berlin, ST=Berlin, C=DE".
+// Commas inside values can be escaped with a backslash.
+func ParseDName(dname string) (pkix.Name, error) {
+	var name pkix.Name
+	if strings.TrimSpace(dname) == "" {
+		return name, fmt.Errorf("distinguished name is required")
+	}
+
+	var parts []string
+	var current strings.Builder
+	for i := 0; i < len(dname); i++ {
+		switch {
+		case dname[i] == '\\' && i+1 < len(dname):
+			i++
+			current.WriteByte(dname[i])
+		case dname[i] == ',':
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(dname[i])
+		}
+	}
+	parts = append(parts, current.String())
+
+	for _, part := range parts {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return name, fmt.Errorf("invalid distinguished name component %q", strings.TrimSpace(part))
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToUpper(strings.TrimSpace(key)) {
+		case "CN":
+			name.CommonName = value
+		case "OU":
+			name.OrganizationalUnit = append(name.OrganizationalUnit, value)
+		case "O":
+			name.Organization = append(name.Organization, value)
+		case "L":
+			name.Locality = append(name.Locality, value)
+		case "ST", "S":
+			name.Province = append(name.Province, value)
+		case "C":
+			name.Country = append(name.Country, value)
+		case "STREET":
+			name.StreetAddress = append(name.StreetAddress, value)
+		default:
+			return name, fmt.Errorf("unsupported distinguished name attribute %q", strings.TrimSpace(key))
+		}
+	}
+	if name.CommonName == "" {
+		return name, fmt.Errorf("distinguished name must include CN")
+	}
+	return name, nil
+}
@@ -0,0 +1,40 @@
+package keystore
+
+import (
+	"testing"
+)
+
+func TestParseDName(t *testing.T) {
+	name, err := ParseDName(`CN=Jane Doe, OU=Mobile, O=Acme\, Inc., L=Berlin, ST=Berlin, C=DE`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name.CommonName != "Jane Doe" || name.Organization[0] != "Acme, Inc." || name.Country[0] != "DE" || name.Province[0] != "Berlin" {
+		t.Errorf("parsed %+v", name)
+	}
+
+	for _, dname := range []string{"", "O=Acme", "CN=Jane, X=1", "CN"} {
+		if _, err := ParseDName(dname); err == nil {
+			t.Errorf("ParseDName(%q) accepted", dname)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	if got := Fingerprint([]byte{0x0a, 0xff, 0x10}); got != "0A:FF:10" {
+		t.Errorf("Fingerprint = %s, want 0A:FF:10", got)
+	}
+}
+
+func TestGenerateRejectsWeakKeys(t *testing.T) {
+	for _, opts := range []Options{
+		{Alias: "upload", DName: "CN=Test", ValidityDays: 1, KeySize: 1024},
+		{Alias: "upload", DName: "CN=Test", ValidityDays: 1, KeyAlgorithm: EC, KeySize: 224},
+		{Alias: "upload", DName: "CN=Test", ValidityDays: 0},
+		{DName: "CN=Test", ValidityDays: 1},
+	} {
+		if _, err := Generate(opts); err == nil {
+			t.Errorf("Generate(%+v) accepted", opts)
+		}
+	}
+}
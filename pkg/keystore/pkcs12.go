@@ -0,0 +1,700 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"unicode/utf16"
+)
+
+var (
+	oidData                 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedData        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+	oidKeyBag               = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidShroudedKeyBag       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidX509Certificate      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidFriendlyName         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidLocalKeyID           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+	oidPBES2                = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2               = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1         = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256       = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA512       = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC           = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+	oidPBEWithSHA3KeyTDES   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidPBEWithSHA40BitRC2   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 6}
+	oidSHA1                 = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256               = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA512               = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	errIncorrectPassword    = errors.New("keystore password was incorrect")
+	errIncorrectKeyPassword = errors.New("key password was incorrect")
+	errUnsupportedKeystore  = errors.New("unsupported keystore format")
+)
+
+// Parameters used when writing keystores. They match what recent JDK keytool
+// versions produce so the files can be read by Gradle and apksigner.
+const (
+	encodeIterations = 10000
+	saltSize         = 20
+)
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           asn1.RawValue `asn1:"tag:0,optional"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+	Attributes []pkcs12Attribute `asn1:"set,optional"`
+}
+
+type pkcs12Attribute struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	KeyLength  int                      `asn1:"optional"`
+	PRF        pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+// Encode serializes entries into a password protected PKCS#12 keystore.
+//
+// Keys and certificates are encrypted with PBES2 (PBKDF2-HMAC-SHA256 and
+// AES-256-CBC) and the file is authenticated with an HMAC-SHA256 MAC.
+func Encode(entries []Entry, password string) ([]byte, error) {
+	return encode(entries, password, password)
+}
+
+// encode is Encode with the private keys encrypted with their own password
+func encode(entries []Entry, password, keyPassword string) ([]byte, error) {
+	var keyBags, certBags []safeBag
+
+	for _, entry := range entries {
+		if len(entry.Certificates) == 0 {
+			return nil, fmt.Errorf("entry %q has no certificate", entry.Alias)
+		}
+		localKeyID := sha1.Sum(entry.Certificates[0].Raw)
+		attrs, err := bagAttributes(entry.Alias, localKeyID[:])
+		if err != nil {
+			return nil, err
+		}
+
+		if entry.PrivateKey != nil {
+			pkcs8, err := x509.MarshalPKCS8PrivateKey(entry.PrivateKey)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal key for %q: %w", entry.Alias, err)
+			}
+			alg, encrypted, err := pbes2Encrypt(pkcs8, keyPassword)
+			if err != nil {
+				return nil, err
+			}
+			shrouded, err := asn1.Marshal(encryptedPrivateKeyInfo{Algorithm: alg, EncryptedData: encrypted})
+			if err != nil {
+				return nil, err
+			}
+			keyBags = append(keyBags, safeBag{
+				ID:         oidShroudedKeyBag,
+				Value:      explicit0(shrouded),
+				Attributes: attrs,
+			})
+		}
+
+		for i, cert := range entry.Certificates {
+			bag, err := asn1.Marshal(certBag{ID: oidX509Certificate, Data: cert.Raw})
+			if err != nil {
+				return nil, err
+			}
+			var certAttrs []pkcs12Attribute
+			if i == 0 {
+				certAttrs = attrs
+			}
+			certBags = append(certBags, safeBag{
+				ID:         oidCertBag,
+				Value:      explicit0(bag),
+				Attributes: certAttrs,
+			})
+		}
+	}
+
+	var authSafe []contentInfo
+
+	certContents, err := asn1.Marshal(certBags)
+	if err != nil {
+		return nil, err
+	}
+	alg, encrypted, err := pbes2Encrypt(certContents, password)
+	if err != nil {
+		return nil, err
+	}
+	encData, err := asn1.Marshal(encryptedData{
+		EncryptedContentInfo: encryptedContentInfo{
+			ContentType:                oidData,
+			ContentEncryptionAlgorithm: alg,
+			EncryptedContent:           asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: encrypted},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	authSafe = append(authSafe, contentInfo{
+		ContentType: oidEncryptedData,
+		Content:     explicit0(encData),
+	})
+
+	if len(keyBags) > 0 {
+		keyContents, err := asn1.Marshal(keyBags)
+		if err != nil {
+			return nil, err
+		}
+		data, err := asn1.Marshal(keyContents)
+		if err != nil {
+			return nil, err
+		}
+		authSafe = append(authSafe, contentInfo{
+			ContentType: oidData,
+			Content:     explicit0(data),
+		})
+	}
+
+	authSafeBytes, err := asn1.Marshal(authSafe)
+	if err != nil {
+		return nil, err
+	}
+	authSafeData, err := asn1.Marshal(authSafeBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	mac := computeMac(sha256.New, authSafeBytes, password, salt, encodeIterations)
+
+	return asn1.Marshal(pfxPdu{
+		Version: 3,
+		AuthSafe: contentInfo{
+			ContentType: oidData,
+			Content:     explicit0(authSafeData),
+		},
+		MacData: macData{
+			Mac: digestInfo{
+				Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue},
+				Digest:    mac,
+			},
+			MacSalt:    salt,
+			Iterations: encodeIterations,
+		},
+	})
+}
+
+// Decode parses a PKCS#12 keystore and returns its entries.
+//
+// PBES2 and pbeWithSHAAnd3-KeyTripleDES-CBC encryption are supported, which
+// covers keystores written by keytool, OpenSSL and Android Studio.
+func Decode(data []byte, password string) ([]Entry, error) {
+	return DecodeWithKeyPassword(data, password, password)
+}
+
+// DecodeWithKeyPassword is Decode for keystores whose private keys are
+// encrypted with a password other than the one of the keystore, as Gradle's
+// keyPassword allows
+func DecodeWithKeyPassword(data []byte, password, keyPassword string) ([]Entry, error) {
+	var pfx pfxPdu
+	rest, err := asn1.Unmarshal(data, &pfx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errUnsupportedKeystore, err)
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing data", errUnsupportedKeystore)
+	}
+	if pfx.Version != 3 || !pfx.AuthSafe.ContentType.Equal(oidData) {
+		return nil, fmt.Errorf("%w: not a PKCS#12 file", errUnsupportedKeystore)
+	}
+
+	authSafeBytes, err := octets(pfx.AuthSafe.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pfx.MacData.Mac.Digest) > 0 {
+		newHash, err := hashForOID(pfx.MacData.Mac.Algorithm.Algorithm)
+		if err != nil {
+			return nil, err
+		}
+		expected := computeMac(newHash, authSafeBytes, password, pfx.MacData.MacSalt, pfx.MacData.Iterations)
+		if !hmac.Equal(expected, pfx.MacData.Mac.Digest) {
+			return nil, errIncorrectPassword
+		}
+	}
+
+	var authSafe []contentInfo
+	if _, err := asn1.Unmarshal(authSafeBytes, &authSafe); err != nil {
+		return nil, fmt.Errorf("%w: %v", errUnsupportedKeystore, err)
+	}
+
+	var bags []safeBag
+	for _, ci := range authSafe {
+		var contents []byte
+		switch {
+		case ci.ContentType.Equal(oidData):
+			contents, err = octets(ci.Content)
+		case ci.ContentType.Equal(oidEncryptedData):
+			var ed encryptedData
+			if _, err = asn1.Unmarshal(ci.Content.Bytes, &ed); err != nil {
+				break
+			}
+			var encrypted []byte
+			if encrypted, err = octets(ed.EncryptedContentInfo.EncryptedContent); err != nil {
+				break
+			}
+			contents, err = decrypt(ed.EncryptedContentInfo.ContentEncryptionAlgorithm, encrypted, password)
+		default:
+			err = fmt.Errorf("%w: content type %v", errUnsupportedKeystore, ci.ContentType)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var safeContents []safeBag
+		if _, err := asn1.Unmarshal(contents, &safeContents); err != nil {
+			return nil, fmt.Errorf("%w: %v", errUnsupportedKeystore, err)
+		}
+		bags = append(bags, safeContents...)
+	}
+
+	return collectEntries(bags, keyPassword)
+}
+
+// collectEntries groups keys and certificates into entries by local key ID,
+// keeping the order of the private keys in the file. The keys are decrypted
+// with keyPassword.
+func collectEntries(bags []safeBag, keyPassword string) ([]Entry, error) {
+	var entries []*Entry
+	byKeyID := map[string]*Entry{}
+	var certs []*x509.Certificate
+	certKeyIDs := map[*x509.Certificate]string{}
+	certAliases := map[*x509.Certificate]string{}
+
+	for _, bag := range bags {
+		alias, keyID, err := parseAttributes(bag.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		if keyID == "" && alias != "" {
+			// Pair keys and certificates by alias when there is no local key ID
+			keyID = "alias:" + alias
+		}
+
+		switch {
+		case bag.ID.Equal(oidShroudedKeyBag), bag.ID.Equal(oidKeyBag):
+			pkcs8 := bag.Value.Bytes
+			if bag.ID.Equal(oidShroudedKeyBag) {
+				var epki encryptedPrivateKeyInfo
+				if _, err := asn1.Unmarshal(bag.Value.Bytes, &epki); err != nil {
+					return nil, fmt.Errorf("%w: %v", errUnsupportedKeystore, err)
+				}
+				if pkcs8, err = decrypt(epki.Algorithm, epki.EncryptedData, keyPassword); err != nil {
+					if errors.Is(err, errIncorrectPassword) {
+						return nil, fmt.Errorf("%w for %q", errIncorrectKeyPassword, alias)
+					}
+					return nil, err
+				}
+			}
+			key, err := x509.ParsePKCS8PrivateKey(pkcs8)
+			if err != nil && bag.ID.Equal(oidShroudedKeyBag) {
+				// A wrong password passes the padding check now and then
+				return nil, fmt.Errorf("%w for %q", errIncorrectKeyPassword, alias)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse private key %q: %w", alias, err)
+			}
+			signer, ok := key.(crypto.Signer)
+			if !ok {
+				return nil, fmt.Errorf("private key %q cannot sign", alias)
+			}
+			entry := &Entry{Alias: alias, PrivateKey: signer}
+			entries = append(entries, entry)
+			if keyID != "" {
+				byKeyID[keyID] = entry
+			}
+		case bag.ID.Equal(oidCertBag):
+			var cb certBag
+			if _, err := asn1.Unmarshal(bag.Value.Bytes, &cb); err != nil {
+				return nil, fmt.Errorf("%w: %v", errUnsupportedKeystore, err)
+			}
+			if !cb.ID.Equal(oidX509Certificate) {
+				continue
+			}
+			cert, err := x509.ParseCertificate(cb.Data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse certificate: %w", err)
+			}
+			certs = append(certs, cert)
+			certKeyIDs[cert] = keyID
+			certAliases[cert] = alias
+		}
+	}
+
+	// Attach leaf certificates, then walk the chain through the remaining ones
+	used := map[*x509.Certificate]bool{}
+	for _, cert := range certs {
+		if entry, ok := byKeyID[certKeyIDs[cert]]; ok && len(entry.Certificates) == 0 {
+			entry.Certificates = append(entry.Certificates, cert)
+			used[cert] = true
+		}
+	}
+	for _, entry := range entries {
+		for len(entry.Certificates) > 0 {
+			last := entry.Certificates[len(entry.Certificates)-1]
+			if bytes.Equal(last.RawIssuer, last.RawSubject) {
+				break
+			}
+			var issuer *x509.Certificate
+			for _, cert := range certs {
+				if !used[cert] && bytes.Equal(cert.RawSubject, last.RawIssuer) {
+					issuer = cert
+					break
+				}
+			}
+			if issuer == nil {
+				break
+			}
+			entry.Certificates = append(entry.Certificates, issuer)
+			used[issuer] = true
+		}
+	}
+
+	result := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, *entry)
+	}
+	// Certificates that belong to no key are trusted certificate entries
+	for _, cert := range certs {
+		if !used[cert] && certAliases[cert] != "" {
+			result = append(result, Entry{Alias: certAliases[cert], Certificates: []*x509.Certificate{cert}})
+		}
+	}
+	return result, nil
+}
+
+// explicit0 wraps an encoded element in an explicit [0] tag. encoding/asn1
+// ignores the explicit field parameter when marshaling a RawValue.
+func explicit0(inner []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: inner}
+}
+
+func bagAttributes(alias string, localKeyID []byte) ([]pkcs12Attribute, error) {
+	units := utf16.Encode([]rune(alias))
+	name := make([]byte, 0, 2*len(units))
+	for _, u := range units {
+		name = append(name, byte(u>>8), byte(u))
+	}
+	friendlyName, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagBMPString, Bytes: name})
+	if err != nil {
+		return nil, err
+	}
+	keyID, err := asn1.Marshal(localKeyID)
+	if err != nil {
+		return nil, err
+	}
+	return []pkcs12Attribute{
+		{ID: oidFriendlyName, Value: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: friendlyName}},
+		{ID: oidLocalKeyID, Value: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: keyID}},
+	}, nil
+}
+
+func parseAttributes(attrs []pkcs12Attribute) (alias, keyID string, err error) {
+	for _, attr := range attrs {
+		switch {
+		case attr.ID.Equal(oidFriendlyName):
+			var raw asn1.RawValue
+			if _, err := asn1.Unmarshal(attr.Value.Bytes, &raw); err != nil {
+				return "", "", fmt.Errorf("%w: %v", errUnsupportedKeystore, err)
+			}
+			if len(raw.Bytes)%2 != 0 {
+				return "", "", fmt.Errorf("%w: invalid friendly name", errUnsupportedKeystore)
+			}
+			units := make([]uint16, len(raw.Bytes)/2)
+			for i := range units {
+				units[i] = uint16(raw.Bytes[2*i])<<8 | uint16(raw.Bytes[2*i+1])
+			}
+			alias = string(utf16.Decode(units))
+		case attr.ID.Equal(oidLocalKeyID):
+			var id []byte
+			if _, err := asn1.Unmarshal(attr.Value.Bytes, &id); err != nil {
+				return "", "", fmt.Errorf("%w: %v", errUnsupportedKeystore, err)
+			}
+			keyID = string(id)
+		}
+	}
+	return alias, keyID, nil
+}
+
+// octets returns the contents of an OCTET STRING, joining the segments of a
+// constructed BER encoding
+func octets(raw asn1.RawValue) ([]byte, error) {
+	if !raw.IsCompound {
+		if raw.Tag == asn1.TagOctetString || raw.Class == asn1.ClassContextSpecific {
+			return raw.Bytes, nil
+		}
+		return nil, fmt.Errorf("%w: expected octet string", errUnsupportedKeystore)
+	}
+	var out []byte
+	rest := raw.Bytes
+	for len(rest) > 0 {
+		var part asn1.RawValue
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &part); err != nil {
+			return nil, fmt.Errorf("%w: %v", errUnsupportedKeystore, err)
+		}
+		segment, err := octets(part)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, segment...)
+	}
+	return out, nil
+}
+
+func computeMac(newHash func() hash.Hash, message []byte, password string, salt []byte, iterations int) []byte {
+	key := pkcs12KDF(newHash, 3, bmpString(password), salt, iterations, newHash().Size())
+	mac := hmac.New(newHash, key)
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+func hashForOID(oid asn1.ObjectIdentifier) (func() hash.Hash, error) {
+	switch {
+	case oid.Equal(oidSHA1), oid.Equal(oidHMACWithSHA1):
+		return sha1.New, nil
+	case oid.Equal(oidSHA256), oid.Equal(oidHMACWithSHA256):
+		return sha256.New, nil
+	case oid.Equal(oidSHA512), oid.Equal(oidHMACWithSHA512):
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("%w: digest algorithm %v", errUnsupportedKeystore, oid)
+}
+
+func pbes2Encrypt(plaintext []byte, password string) (pkix.AlgorithmIdentifier, []byte, error) {
+	salt := make([]byte, saltSize)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, encodeIterations, 32)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	ciphertext := pad(plaintext, aes.BlockSize)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, ciphertext)
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:       salt,
+		Iterations: encodeIterations,
+		PRF:        pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	ivParam, err := asn1.Marshal(iv)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParam}},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+
+	return pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}}, ciphertext, nil
+}
+
+func decrypt(alg pkix.AlgorithmIdentifier, ciphertext []byte, password string) ([]byte, error) {
+	var (
+		block cipher.Block
+		iv    []byte
+		err   error
+	)
+
+	switch {
+	case alg.Algorithm.Equal(oidPBES2):
+		block, iv, err = pbes2Cipher(alg.Parameters.FullBytes, password)
+	case alg.Algorithm.Equal(oidPBEWithSHA3KeyTDES):
+		var params pbeParams
+		if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &params); err != nil {
+			return nil, fmt.Errorf("%w: %v", errUnsupportedKeystore, err)
+		}
+		bmp := bmpString(password)
+		key := pkcs12KDF(sha1.New, 1, bmp, params.Salt, params.Iterations, 24)
+		iv = pkcs12KDF(sha1.New, 2, bmp, params.Salt, params.Iterations, 8)
+		block, err = des.NewTripleDESCipher(key)
+	case alg.Algorithm.Equal(oidPBEWithSHA40BitRC2):
+		return nil, fmt.Errorf("%w: RC2 encryption is not supported, convert the keystore with a recent keytool", errUnsupportedKeystore)
+	default:
+		return nil, fmt.Errorf("%w: encryption algorithm %v", errUnsupportedKeystore, alg.Algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) == 0 || len(ciphertext)%block.BlockSize() != 0 {
+		return nil, fmt.Errorf("%w: invalid ciphertext length", errUnsupportedKeystore)
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+	return unpad(plaintext, block.BlockSize())
+}
+
+func pbes2Cipher(rawParams []byte, password string) (cipher.Block, []byte, error) {
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(rawParams, &params); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errUnsupportedKeystore, err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, nil, fmt.Errorf("%w: key derivation %v", errUnsupportedKeystore, params.KeyDerivationFunc.Algorithm)
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errUnsupportedKeystore, err)
+	}
+
+	prf := sha1.New
+	if len(kdf.PRF.Algorithm) > 0 {
+		var err error
+		if prf, err = hashForOID(kdf.PRF.Algorithm); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errUnsupportedKeystore, err)
+	}
+
+	scheme := params.EncryptionScheme.Algorithm
+	keyLen := 0
+	switch {
+	case scheme.Equal(oidAES128CBC):
+		keyLen = 16
+	case scheme.Equal(oidAES192CBC):
+		keyLen = 24
+	case scheme.Equal(oidAES256CBC):
+		keyLen = 32
+	case scheme.Equal(oidDESEDE3CBC):
+		keyLen = 24
+	default:
+		return nil, nil, fmt.Errorf("%w: cipher %v", errUnsupportedKeystore, scheme)
+	}
+
+	key, err := pbkdf2.Key(prf, password, kdf.Salt, kdf.Iterations, keyLen)
+	if err != nil {
+		return nil, nil, err
+	}
+	if scheme.Equal(oidDESEDE3CBC) {
+		block, err := des.NewTripleDESCipher(key)
+		return block, iv, err
+	}
+	block, err := aes.NewCipher(key)
+	return block, iv, err
+}
+
+func pad(data []byte, blockSize int) []byte {
+	n := blockSize - len(data)%blockSize
+	out := make([]byte, len(data), len(data)+n)
+	copy(out, data)
+	return append(out, bytes.Repeat([]byte{byte(n)}, n)...)
+}
+
+func unpad(data []byte, blockSize int) ([]byte, error) {
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize || n > len(data) {
+		return nil, errIncorrectPassword
+	}
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, errIncorrectPassword
+		}
+	}
+	return data[:len(data)-n], nil
+}
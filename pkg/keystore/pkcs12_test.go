@@ -0,0 +1,168 @@
+package keystore
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	rsaEntry, err := Generate(Options{Alias: "upload", DName: "CN=Jane Doe, O=Acme, C=US", ValidityDays: 365})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecEntry, err := Generate(Options{Alias: "Release", DName: "CN=Acme", ValidityDays: 365, KeyAlgorithm: EC, KeySize: 384})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A trusted certificate without a key
+	trusted := Entry{Alias: "ca", Certificates: []*x509.Certificate{ecEntry.Certificate()}}
+
+	path := filepath.Join(t.TempDir(), "release.keystore")
+	if err := Save(path, "changeit", []Entry{*rsaEntry, *ecEntry, trusted}); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Load(path, "changeit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("loaded %d entries, want 3", len(entries))
+	}
+
+	upload, err := Find(entries, "UPLOAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, ok := upload.PrivateKey.(*rsa.PrivateKey)
+	if !ok || !key.Equal(rsaEntry.PrivateKey) {
+		t.Error("RSA key differs after the round trip")
+	}
+	if !upload.Certificate().Equal(rsaEntry.Certificate()) {
+		t.Error("RSA certificate differs after the round trip")
+	}
+
+	release, err := Find(entries, "release")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, ok := release.PrivateKey.(*ecdsa.PrivateKey); !ok || !key.Equal(ecEntry.PrivateKey) {
+		t.Error("EC key differs after the round trip")
+	}
+
+	ca, err := Find(entries, "ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ca.PrivateKey != nil || !ca.Certificate().Equal(ecEntry.Certificate()) {
+		t.Error("trusted certificate entry not kept")
+	}
+
+	if _, err := Load(path, "wrong"); !errors.Is(err, errIncorrectPassword) {
+		t.Errorf("Load with the wrong password: %v", err)
+	}
+}
+
+func TestKeyPassword(t *testing.T) {
+	entry, err := Generate(Options{Alias: "upload", DName: "CN=Test", ValidityDays: 1, KeyAlgorithm: EC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := encode([]Entry{*entry}, "store-secret", "key-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := DecodeWithKeyPassword(data, "store-secret", "key-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].PrivateKey == nil {
+		t.Fatalf("decoded %d entries, want the key of upload", len(entries))
+	}
+	if _, err := Decode(data, "store-secret"); !errors.Is(err, errIncorrectKeyPassword) {
+		t.Errorf("Decode with the store password for the key: %v, want %v", err, errIncorrectKeyPassword)
+	}
+	if _, err := DecodeWithKeyPassword(data, "key-secret", "key-secret"); !errors.Is(err, errIncorrectPassword) {
+		t.Errorf("Decode with the wrong store password: %v, want %v", err, errIncorrectPassword)
+	}
+}
+
+// The fixtures were written by OpenSSL 3 from the same EC key, with its
+// default PBES2 AES-256 encryption and with the legacy 3DES one:
+//
+//	openssl pkcs12 -export -inkey key.pem -in cert.pem -name upload -passout pass:changeit
+//	openssl pkcs12 -export ... -keypbe PBE-SHA1-3DES -certpbe PBE-SHA1-3DES -macalg sha1
+func TestDecodeOpenSSL(t *testing.T) {
+	const fingerprint = "F1:82:64:A8:26:0D:73:B8:C1:13:D4:52:4E:98:38:15:37:76:8B:EE:0F:9A:C1:DB:04:77:3D:20:45:9C:A3:E0"
+	for _, name := range []string{"openssl-aes.p12", "openssl-3des.p12"} {
+		t.Run(name, func(t *testing.T) {
+			entries, err := Load(filepath.Join("testdata", name), "changeit")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("loaded %d entries, want 1", len(entries))
+			}
+			entry := entries[0]
+			if entry.Alias != "upload" {
+				t.Errorf("alias = %q, want upload", entry.Alias)
+			}
+			if got := SHA256Fingerprint(entry.Certificate()); got != fingerprint {
+				t.Errorf("fingerprint = %s, want %s", got, fingerprint)
+			}
+			key, ok := entry.PrivateKey.(*ecdsa.PrivateKey)
+			if !ok || !key.PublicKey.Equal(entry.Certificate().PublicKey) {
+				t.Error("private key does not match the certificate")
+			}
+		})
+	}
+}
+
+// Known answers of the PKCS#12 key derivation for SHA-1, as used by the
+// BouncyCastle and OpenSSL test suites
+func TestPKCS12KDF(t *testing.T) {
+	tests := []struct {
+		password, salt string
+		id             byte
+		iterations     int
+		want           string
+	}{
+		{"smeg", "0a58cf64530d823f", 1, 1, "8aaae6297b6cb04642ab5b077851284eb7128f1a2a7fbca3"},
+		{"smeg", "0a58cf64530d823f", 2, 1, "79993dfe048d3b76"},
+		{"queeg", "1682c0fc5b3f7ec5", 1, 1000, "483dd6e919d7de2e8e648ba8f862f3fbfbdc2bcb2c02957f"},
+		{"queeg", "1682c0fc5b3f7ec5", 2, 1000, "9d461d1b00355c50"},
+	}
+	for _, tt := range tests {
+		salt, _ := hex.DecodeString(tt.salt)
+		want, _ := hex.DecodeString(tt.want)
+		got := pkcs12KDF(sha1.New, tt.id, bmpString(tt.password), salt, tt.iterations, len(want))
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("KDF(%s, id %d, %d iterations) = %x, want %s", tt.password, tt.id, tt.iterations, got, tt.want)
+		}
+	}
+}
+
+func TestSaveIsPrivate(t *testing.T) {
+	entry, err := Generate(Options{Alias: "upload", DName: "CN=Test", ValidityDays: 1, KeyAlgorithm: EC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "release.keystore")
+	if err := Save(path, "changeit", []Entry{*entry}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode&0077 != 0 && filepath.Separator == '/' {
+		t.Errorf("keystore mode = %v, want no access for others", mode)
+	}
+}
@@ -0,0 +1,149 @@
+// Package logcat parses the output of `adb logcat -v threadtime` and streams
+// the log of an Android app, following it across restarts.
+package logcat
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Level is the priority of a log entry
+type Level int
+
+const (
+	Verbose Level = iota
+	Debug
+	Info
+	Warn
+	Error
+	Fatal
+)
+
+var levelNames = []string{"verbose", "debug", "info", "warn", "error", "fatal"}
+
+// levelLetters are the priorities of logcat in the order of the levels, A
+// (assert) is read as F
+const levelLetters = "VDIWEF"
+
+func (l Level) String() string {
+	if l < Verbose || l > Fatal {
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// Letter returns the logcat letter of the level, e.g. W
+func (l Level) Letter() string {
+	if l < Verbose || l > Fatal {
+		return "?"
+	}
+	return levelLetters[l : l+1]
+}
+
+// MarshalText encodes the level by its name
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// ParseLevel accepts a level by name or logcat letter, e.g. warn or W
+func ParseLevel(s string) (Level, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "a" || s == "assert" {
+		return Fatal, nil
+	}
+	if s == "warning" {
+		return Warn, nil
+	}
+	for i, name := range levelNames {
+		if s == name || s == strings.ToLower(levelLetters[i:i+1]) {
+			return Level(i), nil
+		}
+	}
+	return Verbose, fmt.Errorf("unknown log level %q, expected verbose, debug, info, warn, error or fatal", s)
+}
+
+// Entry is a line of the log
+type Entry struct {
+	Time    time.Time `json:"time"`
+	PID     int       `json:"pid"`
+	TID     int       `json:"tid"`
+	Level   Level     `json:"level"`
+	Tag     string    `json:"tag"`
+	Message string    `json:"message"`
+}
+
+// threadtimeLine matches `MM-DD HH:MM:SS.mmm PID TID L Tag: message`. Older
+// Android versions pad the tag with spaces.
+var threadtimeLine = regexp.MustCompile(`^(\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{3})\s+(\d+)\s+(\d+)\s+([VDIWEFA])\s(.*?)\s*: ?(.*)$`)
+
+// Parse parses a line of `adb logcat -v threadtime`. Buffer banners such as
+// `--------- beginning of main` and other lines are rejected. The year,
+// missing from the format, is taken from now.
+func Parse(line string, now time.Time) (Entry, bool) {
+	m := threadtimeLine.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+	if m == nil {
+		return Entry{}, false
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04:05.000", fmt.Sprintf("%d-%s", now.Year(), m[1]), now.Location())
+	if err != nil {
+		return Entry{}, false
+	}
+	// An entry of December read in January
+	if t.After(now.AddDate(0, 0, 1)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	pid, _ := strconv.Atoi(m[2])
+	tid, _ := strconv.Atoi(m[3])
+	level := Fatal
+	if m[4] != "A" {
+		level = Level(strings.Index(levelLetters, m[4]))
+	}
+	return Entry{
+		Time:    t,
+		PID:     pid,
+		TID:     tid,
+		Level:   level,
+		Tag:     m[5],
+		Message: m[6],
+	}, true
+}
+
+// Process lifecycle messages of the ActivityManager, in the formats of
+// Android 5.1 and later and of earlier versions
+var (
+	startProc    = regexp.MustCompile(`^Start proc (\d+):([\w.:]+)/\w+ for `)
+	startProcOld = regexp.MustCompile(`^Start proc ([\w.:]+) for .*: pid=(\d+) `)
+	killProc     = regexp.MustCompile(`^Killing (\d+):([\w.:]+)/`)
+	diedProc     = regexp.MustCompile(`^Process ([\w.:]+) \(pid (\d+)\) has died`)
+)
+
+// processStarted returns the process name and PID of a process start logged
+// by the ActivityManager
+func processStarted(message string) (string, int, bool) {
+	if m := startProc.FindStringSubmatch(message); m != nil {
+		pid, _ := strconv.Atoi(m[1])
+		return m[2], pid, true
+	}
+	if m := startProcOld.FindStringSubmatch(message); m != nil {
+		pid, _ := strconv.Atoi(m[2])
+		return m[1], pid, true
+	}
+	return "", 0, false
+}
+
+// processDied returns the process name and PID of a process killed or dead
+// as logged by the ActivityManager
+func processDied(message string) (string, int, bool) {
+	if m := killProc.FindStringSubmatch(message); m != nil {
+		pid, _ := strconv.Atoi(m[1])
+		return m[2], pid, true
+	}
+	if m := diedProc.FindStringSubmatch(message); m != nil {
+		pid, _ := strconv.Atoi(m[2])
+		return m[1], pid, true
+	}
+	return "", 0, false
+}
@@ -0,0 +1,348 @@
+package logcat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	october := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		line string
+		// now defaults to October 18
+		now  time.Time
+		want Entry
+		ok   bool
+	}{
+		{
+			name: "entry",
+			line: "10-18 09:41:02.131  8234  8261 W com.acme.app: Unexpected CPU variant for x86: x86_64.",
+			want: Entry{
+				Time:    time.Date(2026, time.October, 18, 9, 41, 2, 131e6, time.UTC),
+				PID:     8234,
+				TID:     8261,
+				Level:   Warn,
+				Tag:     "com.acme.app",
+				Message: "Unexpected CPU variant for x86: x86_64.",
+			},
+			ok: true,
+		},
+		{
+			name: "padded tag",
+			line: "10-18 09:41:05.763   512   541 W Zygote  : Process 8234 exited due to signal 9 (Killed)",
+			want: Entry{
+				Time:    time.Date(2026, time.October, 18, 9, 41, 5, 763e6, time.UTC),
+				PID:     512,
+				TID:     541,
+				Level:   Warn,
+				Tag:     "Zygote",
+				Message: "Process 8234 exited due to signal 9 (Killed)",
+			},
+			ok: true,
+		},
+		{
+			name: "tag with spaces",
+			line: "10-18 09:41:05.763  2145  2145 I Web Console: App mounted",
+			want: Entry{
+				Time:    time.Date(2026, time.October, 18, 9, 41, 5, 763e6, time.UTC),
+				PID:     2145,
+				TID:     2145,
+				Level:   Info,
+				Tag:     "Web Console",
+				Message: "App mounted",
+			},
+			ok: true,
+		},
+		{
+			name: "continuation of a multi-line message",
+			line: "10-18 09:41:05.642  8234  8234 E AndroidRuntime: \tat com.acme.app.Bridge.call(Bridge.kt:42)",
+			want: Entry{
+				Time:    time.Date(2026, time.October, 18, 9, 41, 5, 642e6, time.UTC),
+				PID:     8234,
+				TID:     8234,
+				Level:   Error,
+				Tag:     "AndroidRuntime",
+				Message: "\tat com.acme.app.Bridge.call(Bridge.kt:42)",
+			},
+			ok: true,
+		},
+		{
+			name: "empty message and CRLF",
+			line: "10-18 09:41:05.642  8234  8234 D chromium: \r\n",
+			want: Entry{
+				Time:  time.Date(2026, time.October, 18, 9, 41, 5, 642e6, time.UTC),
+				PID:   8234,
+				TID:   8234,
+				Level: Debug,
+				Tag:   "chromium",
+			},
+			ok: true,
+		},
+		{
+			name: "assert",
+			line: "10-18 09:41:05.642  8234  8234 A libc    : Fatal signal 6 (SIGABRT)",
+			want: Entry{
+				Time:    time.Date(2026, time.October, 18, 9, 41, 5, 642e6, time.UTC),
+				PID:     8234,
+				TID:     8234,
+				Level:   Fatal,
+				Tag:     "libc",
+				Message: "Fatal signal 6 (SIGABRT)",
+			},
+			ok: true,
+		},
+		{
+			name: "entry of last year",
+			line: "12-31 23:59:59.999   389   403 I ActivityManager: Displayed",
+			now:  time.Date(2026, time.January, 2, 8, 0, 0, 0, time.UTC),
+			want: Entry{
+				Time:    time.Date(2025, time.December, 31, 23, 59, 59, 999e6, time.UTC),
+				PID:     389,
+				TID:     403,
+				Level:   Info,
+				Tag:     "ActivityManager",
+				Message: "Displayed",
+			},
+			ok: true,
+		},
+		{name: "banner", line: "--------- beginning of main"},
+		{name: "crash banner", line: "--------- beginning of crash"},
+		{name: "brief format", line: "I/ActivityManager(  389): Displayed"},
+		{name: "empty", line: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			now := test.now
+			if now.IsZero() {
+				now = october
+			}
+			got, ok := Parse(test.line, now)
+			if ok != test.ok {
+				t.Fatalf("Parse(%q) ok = %v, want %v", test.line, ok, test.ok)
+			}
+			if !got.Time.Equal(test.want.Time) {
+				t.Errorf("Time = %v, want %v", got.Time, test.want.Time)
+			}
+			got.Time = test.want.Time
+			if got != test.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", test.line, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]Level{
+		"verbose": Verbose,
+		"V":       Verbose,
+		"debug":   Debug,
+		"i":       Info,
+		"warning": Warn,
+		" W ":     Warn,
+		"Error":   Error,
+		"fatal":   Fatal,
+		"A":       Fatal,
+		"assert":  Fatal,
+	}
+	for s, want := range tests {
+		got, err := ParseLevel(s)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("ParseLevel(loud) did not fail")
+	}
+}
+
+func TestProcessMessages(t *testing.T) {
+	tests := []struct {
+		message string
+		started bool
+		died    bool
+		name    string
+		pid     int
+	}{
+		{
+			message: "Start proc 8410:com.acme.app/u0a190 for top-activity {com.acme.app/com.acme.app.MainActivity}",
+			started: true, name: "com.acme.app", pid: 8410,
+		},
+		{
+			message: "Start proc 8452:com.acme.app:sync/u0a190 for service {com.acme.app/com.acme.app.SyncService}",
+			started: true, name: "com.acme.app:sync", pid: 8452,
+		},
+		{
+			message: "Start proc com.acme.app for activity com.acme.app/.MainActivity: pid=2145 uid=10052 gids={50052, 3003}",
+			started: true, name: "com.acme.app", pid: 2145,
+		},
+		{
+			message: "Killing 8452:com.acme.app:sync/u0a190 (adj 905): empty #17",
+			died:    true, name: "com.acme.app:sync", pid: 8452,
+		},
+		{
+			message: "Process com.acme.app (pid 8234) has died: fg  TOP ",
+			died:    true, name: "com.acme.app", pid: 8234,
+		},
+		{
+			message: "Process com.acme.app (pid 2145) has died.",
+			died:    true, name: "com.acme.app", pid: 2145,
+		},
+		{message: "Displayed com.acme.app/.MainActivity: +612ms"},
+		{message: "Process 8234 exited due to signal 9 (Killed)"},
+	}
+
+	for _, test := range tests {
+		name, pid, ok := processStarted(test.message)
+		if ok != test.started || (ok && (name != test.name || pid != test.pid)) {
+			t.Errorf("processStarted(%q) = %q, %d, %v", test.message, name, pid, ok)
+		}
+		name, pid, ok = processDied(test.message)
+		if ok != test.died || (ok && (name != test.name || pid != test.pid)) {
+			t.Errorf("processDied(%q) = %q, %d, %v", test.message, name, pid, ok)
+		}
+	}
+}
+
+// TestStreamFollowsApp reads captures of `adb logcat -v threadtime` and
+// checks the entries and notifications of the app
+func TestStreamFollowsApp(t *testing.T) {
+	tests := []struct {
+		name     string
+		capture  string
+		now      time.Time
+		running  int
+		minLevel Level
+		entries  []string
+		notified []string
+	}{
+		{
+			name:    "restart with reused pid",
+			capture: "restart.txt",
+			now:     time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC),
+			running: 8234,
+			entries: []string{
+				"8234 I com.acme.app: Late-enabling -Xcheck:jni",
+				"8234 W com.acme.app: Unexpected CPU variant for x86: x86_64.",
+				"8234 D HostConnection: HostConnection::get() New Host Connection established 0x7b4a3c8e2c50, tid 8261",
+				`8234 I chromium: [INFO:CONSOLE(14)] "App mounted", source: http://10.0.2.2:5173/src/main.ts (14)`,
+				`8234 I chromium: [INFO:CONSOLE(27)] "Loaded settings {`,
+				`8234 I chromium:   "theme": "dark",`,
+				`8234 I chromium:   "lang": "en"`,
+				`8234 I chromium: }", source: http://10.0.2.2:5173/src/settings.ts (27)`,
+				"8234 D AndroidRuntime: Shutting down VM",
+				"8234 E AndroidRuntime: FATAL EXCEPTION: main",
+				"8234 E AndroidRuntime: Process: com.acme.app, PID: 8234",
+				"8234 E AndroidRuntime: java.lang.IllegalStateException: bridge not ready",
+				"8234 E AndroidRuntime: \tat com.acme.app.Bridge.call(Bridge.kt:42)",
+				"8234 E AndroidRuntime: \tat com.acme.app.MainActivity.onResume(MainActivity.kt:88)",
+				"8234 I Process: Sending signal. PID: 8234 SIG: 9",
+				"8410 I com.acme.app: Late-enabling -Xcheck:jni",
+				"8410 D WebView: Using WebView provider com.google.android.webview",
+				`8410 I chromium: [INFO:CONSOLE(14)] "App mounted", source: http://10.0.2.2:5173/src/main.ts (14)`,
+				"8452 W SyncService: No network, retrying in 30s",
+			},
+			notified: []string{
+				"com.acme.app died (pid 8234)",
+				"com.acme.app started (pid 8410)",
+				"com.acme.app:sync started (pid 8452)",
+				"com.acme.app:sync died (pid 8452)",
+			},
+		},
+		{
+			name:     "minimum level",
+			capture:  "restart.txt",
+			now:      time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC),
+			running:  8234,
+			minLevel: Warn,
+			entries: []string{
+				"8234 W com.acme.app: Unexpected CPU variant for x86: x86_64.",
+				"8234 E AndroidRuntime: FATAL EXCEPTION: main",
+				"8234 E AndroidRuntime: Process: com.acme.app, PID: 8234",
+				"8234 E AndroidRuntime: java.lang.IllegalStateException: bridge not ready",
+				"8234 E AndroidRuntime: \tat com.acme.app.Bridge.call(Bridge.kt:42)",
+				"8234 E AndroidRuntime: \tat com.acme.app.MainActivity.onResume(MainActivity.kt:88)",
+				"8452 W SyncService: No network, retrying in 30s",
+			},
+			notified: []string{
+				"com.acme.app died (pid 8234)",
+				"com.acme.app started (pid 8410)",
+				"com.acme.app:sync started (pid 8452)",
+				"com.acme.app:sync died (pid 8452)",
+			},
+		},
+		{
+			name:    "app started after the stream",
+			capture: "restart.txt",
+			now:     time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC),
+			entries: []string{
+				"8410 I com.acme.app: Late-enabling -Xcheck:jni",
+				"8410 D WebView: Using WebView provider com.google.android.webview",
+				`8410 I chromium: [INFO:CONSOLE(14)] "App mounted", source: http://10.0.2.2:5173/src/main.ts (14)`,
+				"8452 W SyncService: No network, retrying in 30s",
+			},
+			notified: []string{
+				"com.acme.app started (pid 8410)",
+				"com.acme.app:sync started (pid 8452)",
+				"com.acme.app:sync died (pid 8452)",
+			},
+		},
+		{
+			name:    "Android 4 over new year",
+			capture: "legacy.txt",
+			now:     time.Date(2026, time.January, 3, 0, 0, 5, 0, time.UTC),
+			entries: []string{
+				"2145 D dalvikvm: Late-enabling CheckJNI",
+				"2145 D libEGL: loaded /system/lib/egl/libEGL_emulation.so",
+				"2145 I Web Console: App mounted at http://10.0.2.2:5173/src/main.ts:14",
+				"2145 W chromium: [WARNING:proxy_service.cc(890)] PAC support disabled because there is no system implementation",
+			},
+			notified: []string{
+				"com.acme.app started (pid 2145)",
+				"com.acme.app died (pid 2145)",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file, err := os.Open(filepath.Join("testdata", test.capture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			var entries, notified []string
+			s := &Stream{
+				Package:  "com.acme.app",
+				MinLevel: test.minLevel,
+				Notify:   func(message string) { notified = append(notified, message) },
+				pids:     map[int]bool{},
+			}
+			if test.running > 0 {
+				s.pids[test.running] = true
+			}
+			s.read(file, func() time.Time { return test.now }, func(e Entry) {
+				if e.Time.After(test.now) || e.Time.Before(test.now.AddDate(0, 0, -1)) {
+					t.Errorf("entry %q logged at %v", e.Message, e.Time)
+				}
+				entries = append(entries, fmt.Sprintf("%d %s %s: %s", e.PID, e.Level.Letter(), e.Tag, e.Message))
+			})
+
+			compareLines(t, "entries", entries, test.entries)
+			compareLines(t, "notifications", notified, test.notified)
+		})
+	}
+}
+
+func compareLines(t *testing.T, what string, got, want []string) {
+	t.Helper()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("%s =\n%s\nwant\n%s", what, strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,132 @@
+package logcat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stream follows the log of the processes of an app on a device. The
+// processes are found with pidof when the stream starts and then tracked
+// through the process starts and deaths the ActivityManager logs, so the
+// stream follows the app across restarts and crashes.
+type Stream struct {
+	// Device is the serial of the device, empty when only one is connected
+	Device string
+	// Package is the application ID, secondary processes such as
+	// com.acme.app:remote are included
+	Package string
+	// MinLevel drops the entries below it
+	MinLevel Level
+	// Backlog includes the entries logged before the stream started, only
+	// new entries are streamed otherwise
+	Backlog bool
+	// Notify receives the starts and deaths of the app when set
+	Notify func(message string)
+
+	pids map[int]bool
+}
+
+// Run streams the entries of the app to handle until ctx is done or adb
+// logcat exits, e.g. because the device was disconnected
+func (s *Stream) Run(ctx context.Context, handle func(Entry)) error {
+	s.pids = map[int]bool{}
+	if pid := s.runningPID(ctx); pid > 0 {
+		s.pids[pid] = true
+		s.notify("%s is running (pid %d)", s.Package, pid)
+	} else {
+		s.notify("Waiting for %s to start", s.Package)
+	}
+
+	args := s.adbArgs("logcat", "-v", "threadtime")
+	if !s.Backlog {
+		// -T starts with the last line instead of dumping the buffer
+		args = append(args, "-T", "1")
+	}
+	cmd := exec.CommandContext(ctx, "adb", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start adb logcat: %w", err)
+	}
+
+	s.read(stdout, time.Now, handle)
+
+	err = cmd.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("adb logcat failed: %w %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return fmt.Errorf("adb logcat ended, is the device still connected?")
+}
+
+// read passes the entries of the app read from the output of logcat to
+// handle, the year of the entries is taken from now
+func (s *Stream) read(r io.Reader, now func() time.Time, handle func(Entry)) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		e, ok := Parse(scanner.Text(), now())
+		if !ok {
+			continue
+		}
+		s.track(e)
+		if s.pids[e.PID] && e.Level >= s.MinLevel {
+			handle(e)
+		}
+	}
+}
+
+// track updates the processes of the app from the entries of the
+// ActivityManager
+func (s *Stream) track(e Entry) {
+	if name, pid, ok := processStarted(e.Message); ok && s.ownProcess(name) {
+		s.pids[pid] = true
+		s.notify("%s started (pid %d)", name, pid)
+	} else if name, pid, ok := processDied(e.Message); ok && s.ownProcess(name) && s.pids[pid] {
+		delete(s.pids, pid)
+		s.notify("%s died (pid %d)", name, pid)
+	}
+}
+
+// ownProcess reports whether name is the main or a secondary process of the
+// app
+func (s *Stream) ownProcess(name string) bool {
+	return name == s.Package || strings.HasPrefix(name, s.Package+":")
+}
+
+// runningPID returns the PID of the running app, 0 when it is not running
+func (s *Stream) runningPID(ctx context.Context) int {
+	output, err := exec.CommandContext(ctx, "adb", s.adbArgs("shell", "pidof", "-s", s.Package)...).Output()
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(output)))
+	return pid
+}
+
+func (s *Stream) adbArgs(args ...string) []string {
+	if s.Device != "" {
+		// The device must come before the command
+		return append([]string{"-s", s.Device}, args...)
+	}
+	return args
+}
+
+func (s *Stream) notify(format string, args ...any) {
+	if s.Notify != nil {
+		s.Notify(fmt.Sprintf(format, args...))
+	}
+}
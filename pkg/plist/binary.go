@@ -0,0 +1,484 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+	"unicode/utf16"
+)
+
+const binaryMagic = "bplist00"
+
+// Object markers of the binary format. The low nibble holds the size or count.
+const (
+	markerFalse  = 0x08
+	markerTrue   = 0x09
+	markerInt    = 0x10
+	markerReal   = 0x20
+	markerDate   = 0x33
+	markerData   = 0x40
+	markerASCII  = 0x50
+	markerUTF16  = 0x60
+	markerArray  = 0xA0
+	markerDict   = 0xD0
+	binaryMaxLen = 0x0F
+)
+
+// Dates are stored as seconds since 2001-01-01 UTC
+var binaryEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// IsBinary reports whether data is a binary property list
+func IsBinary(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(binaryMagic))
+}
+
+// binaryObject is an encoded object whose references to other objects are
+// written once the total number of objects, and so the reference size, is known
+type binaryObject struct {
+	head []byte
+	refs []int
+}
+
+type binaryEncoder struct {
+	objects []binaryObject
+	strings map[string]int
+}
+
+// MarshalBinary encodes v as a binary property list (bplist00)
+func MarshalBinary(v any) ([]byte, error) {
+	e := &binaryEncoder{strings: map[string]int{}}
+	top, err := e.add(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+
+	refSize := intSize(uint64(len(e.objects)))
+	var buf bytes.Buffer
+	buf.WriteString(binaryMagic)
+	offsets := make([]uint64, len(e.objects))
+	for i, object := range e.objects {
+		offsets[i] = uint64(buf.Len())
+		buf.Write(object.head)
+		for _, ref := range object.refs {
+			writeUint(&buf, uint64(ref), refSize)
+		}
+	}
+
+	tableOffset := uint64(buf.Len())
+	offsetSize := intSize(tableOffset)
+	for _, offset := range offsets {
+		writeUint(&buf, offset, offsetSize)
+	}
+
+	var trailer [32]byte
+	trailer[6] = byte(offsetSize)
+	trailer[7] = byte(refSize)
+	binary.BigEndian.PutUint64(trailer[8:], uint64(len(e.objects)))
+	binary.BigEndian.PutUint64(trailer[16:], uint64(top))
+	binary.BigEndian.PutUint64(trailer[24:], tableOffset)
+	buf.Write(trailer[:])
+	return buf.Bytes(), nil
+}
+
+// add appends v and the values it contains to the object list and returns its
+// index. Strings are shared, which mostly deduplicates dictionary keys.
+func (e *binaryEncoder) add(v reflect.Value) (int, error) {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return 0, fmt.Errorf("plist: cannot encode nil value")
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.String {
+		if index, ok := e.strings[v.String()]; ok {
+			return index, nil
+		}
+	}
+
+	index := len(e.objects)
+	e.objects = append(e.objects, binaryObject{})
+	var object binaryObject
+
+	if t, ok := v.Interface().(time.Time); ok {
+		var b [9]byte
+		b[0] = markerDate
+		binary.BigEndian.PutUint64(b[1:], math.Float64bits(t.Sub(binaryEpoch).Seconds()))
+		e.objects[index] = binaryObject{head: b[:]}
+		return index, nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		e.strings[v.String()] = index
+		object.head = encodeString(v.String())
+	case reflect.Bool:
+		if v.Bool() {
+			object.head = []byte{markerTrue}
+		} else {
+			object.head = []byte{markerFalse}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		object.head = encodeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return 0, fmt.Errorf("plist: integer %d out of range", v.Uint())
+		}
+		object.head = encodeInt(int64(v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		var b [9]byte
+		b[0] = markerReal | 3
+		binary.BigEndian.PutUint64(b[1:], math.Float64bits(v.Float()))
+		object.head = b[:]
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			data := v.Bytes()
+			object.head = append(encodeHeader(markerData, len(data)), data...)
+			break
+		}
+		object.head = encodeHeader(markerArray, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			ref, err := e.add(v.Index(i))
+			if err != nil {
+				return 0, err
+			}
+			object.refs = append(object.refs, ref)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return 0, fmt.Errorf("plist: dictionary keys must be strings, got %s", v.Type().Key())
+		}
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+
+		object.head = encodeHeader(markerDict, len(keys))
+		values := make([]int, 0, len(keys))
+		for _, key := range keys {
+			ref, err := e.add(reflect.ValueOf(key))
+			if err != nil {
+				return 0, err
+			}
+			object.refs = append(object.refs, ref)
+		}
+		for _, key := range keys {
+			ref, err := e.add(v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())))
+			if err != nil {
+				return 0, err
+			}
+			values = append(values, ref)
+		}
+		object.refs = append(object.refs, values...)
+	default:
+		return 0, fmt.Errorf("plist: unsupported type %s", v.Type())
+	}
+
+	e.objects[index] = object
+	return index, nil
+}
+
+// encodeHeader returns the marker of an object with count bytes or elements.
+// Counts of 15 and above follow the marker as an integer object.
+func encodeHeader(marker byte, count int) []byte {
+	if count < binaryMaxLen {
+		return []byte{marker | byte(count)}
+	}
+	return append([]byte{marker | binaryMaxLen}, encodeInt(int64(count))...)
+}
+
+func encodeInt(n int64) []byte {
+	// Negative integers are always stored on 8 bytes
+	size := 8
+	if n >= 0 {
+		size = intSize(uint64(n))
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(markerInt | byte(bitsLog2(size)))
+	writeUint(&buf, uint64(n), size)
+	return buf.Bytes()
+}
+
+// encodeString stores ASCII strings as bytes and others as UTF-16BE
+func encodeString(s string) []byte {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return append(encodeHeader(markerASCII, len(s)), s...)
+	}
+
+	units := utf16.Encode([]rune(s))
+	out := encodeHeader(markerUTF16, len(units))
+	for _, unit := range units {
+		out = append(out, byte(unit>>8), byte(unit))
+	}
+	return out
+}
+
+// intSize returns the number of bytes needed to store n
+func intSize(n uint64) int {
+	switch {
+	case n <= math.MaxUint8:
+		return 1
+	case n <= math.MaxUint16:
+		return 2
+	case n <= math.MaxUint32:
+		return 4
+	}
+	return 8
+}
+
+func bitsLog2(size int) int {
+	switch size {
+	case 1:
+		return 0
+	case 2:
+		return 1
+	case 4:
+		return 2
+	}
+	return 3
+}
+
+func writeUint(buf *bytes.Buffer, n uint64, size int) {
+	for i := size - 1; i >= 0; i-- {
+		buf.WriteByte(byte(n >> (8 * i)))
+	}
+}
+
+func readUint(b []byte) uint64 {
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n
+}
+
+type binaryDecoder struct {
+	data       []byte
+	offsets    []uint64
+	refSize    int
+	inProgress map[uint64]bool
+}
+
+var errBinaryTruncated = errors.New("plist: truncated binary property list")
+
+// unmarshalBinary decodes a binary property list
+func unmarshalBinary(data []byte) (any, error) {
+	if len(data) < len(binaryMagic)+32 {
+		return nil, errBinaryTruncated
+	}
+	trailer := data[len(data)-32:]
+	offsetSize := int(trailer[6])
+	refSize := int(trailer[7])
+	count := binary.BigEndian.Uint64(trailer[8:])
+	top := binary.BigEndian.Uint64(trailer[16:])
+	tableOffset := binary.BigEndian.Uint64(trailer[24:])
+
+	if offsetSize < 1 || offsetSize > 8 || refSize < 1 || refSize > 8 {
+		return nil, errors.New("plist: invalid binary trailer")
+	}
+	tableEnd := uint64(len(data) - 32)
+	if tableOffset > tableEnd || count > (tableEnd-tableOffset)/uint64(offsetSize) || top >= count {
+		return nil, errors.New("plist: invalid binary offset table")
+	}
+
+	d := &binaryDecoder{
+		data:       data[:tableOffset],
+		offsets:    make([]uint64, count),
+		refSize:    refSize,
+		inProgress: map[uint64]bool{},
+	}
+	for i := range d.offsets {
+		start := tableOffset + uint64(i*offsetSize)
+		d.offsets[i] = readUint(data[start : start+uint64(offsetSize)])
+	}
+	return d.object(top)
+}
+
+// object decodes the object at index ref
+func (d *binaryDecoder) object(ref uint64) (any, error) {
+	if ref >= uint64(len(d.offsets)) {
+		return nil, fmt.Errorf("plist: object reference %d out of range", ref)
+	}
+	if d.inProgress[ref] {
+		return nil, errors.New("plist: cyclic object reference")
+	}
+	d.inProgress[ref] = true
+	defer delete(d.inProgress, ref)
+
+	offset := d.offsets[ref]
+	if offset >= uint64(len(d.data)) {
+		return nil, errBinaryTruncated
+	}
+	marker := d.data[offset]
+	info := int(marker & 0x0F)
+	body := offset + 1
+
+	switch marker & 0xF0 {
+	case 0x00:
+		switch marker {
+		case markerFalse:
+			return false, nil
+		case markerTrue:
+			return true, nil
+		}
+		return nil, fmt.Errorf("plist: unsupported object marker 0x%02x", marker)
+	case markerInt:
+		b, err := d.bytes(body, 1<<info)
+		if err != nil {
+			return nil, err
+		}
+		// 16 byte integers hold unsigned values in their low 8 bytes
+		if len(b) > 8 {
+			b = b[len(b)-8:]
+		}
+		return int64(readUint(b)), nil
+	case markerReal:
+		b, err := d.bytes(body, 1<<info)
+		if err != nil {
+			return nil, err
+		}
+		switch len(b) {
+		case 4:
+			return float64(math.Float32frombits(uint32(readUint(b)))), nil
+		case 8:
+			return math.Float64frombits(readUint(b)), nil
+		}
+		return nil, fmt.Errorf("plist: invalid real size %d", len(b))
+	case markerDate & 0xF0:
+		b, err := d.bytes(body, 8)
+		if err != nil {
+			return nil, err
+		}
+		seconds := math.Float64frombits(readUint(b))
+		return binaryEpoch.Add(time.Duration(seconds * float64(time.Second))), nil
+	case markerData:
+		n, start, err := d.count(body, info)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.bytes(start, n)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case markerASCII:
+		n, start, err := d.count(body, info)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.bytes(start, n)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case markerUTF16:
+		n, start, err := d.count(body, info)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.bytes(start, 2*n)
+		if err != nil {
+			return nil, err
+		}
+		units := make([]uint16, n)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(b[2*i:])
+		}
+		return string(utf16.Decode(units)), nil
+	case markerArray:
+		n, start, err := d.count(body, info)
+		if err != nil {
+			return nil, err
+		}
+		refs, err := d.refs(start, n)
+		if err != nil {
+			return nil, err
+		}
+		array := make([]any, 0, n)
+		for _, r := range refs {
+			value, err := d.object(r)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		return array, nil
+	case markerDict:
+		n, start, err := d.count(body, info)
+		if err != nil {
+			return nil, err
+		}
+		refs, err := d.refs(start, 2*n)
+		if err != nil {
+			return nil, err
+		}
+		dict := make(map[string]any, n)
+		for i := 0; i < n; i++ {
+			key, err := d.object(refs[i])
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("plist: dictionary key is a %T, not a string", key)
+			}
+			if dict[name], err = d.object(refs[n+i]); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("plist: unsupported object marker 0x%02x", marker)
+}
+
+// count returns the size of a data, string or container object and the offset
+// of its content
+func (d *binaryDecoder) count(offset uint64, info int) (int, uint64, error) {
+	if info != binaryMaxLen {
+		return info, offset, nil
+	}
+	if offset >= uint64(len(d.data)) || d.data[offset]&0xF0 != markerInt {
+		return 0, 0, errors.New("plist: invalid object length")
+	}
+	size := 1 << (d.data[offset] & 0x0F)
+	b, err := d.bytes(offset+1, size)
+	if err != nil {
+		return 0, 0, err
+	}
+	n := readUint(b)
+	if n > uint64(len(d.data)) {
+		return 0, 0, errBinaryTruncated
+	}
+	return int(n), offset + 1 + uint64(size), nil
+}
+
+func (d *binaryDecoder) bytes(offset uint64, n int) ([]byte, error) {
+	if n < 0 || offset+uint64(n) > uint64(len(d.data)) {
+		return nil, errBinaryTruncated
+	}
+	return d.data[offset : offset+uint64(n)], nil
+}
+
+func (d *binaryDecoder) refs(offset uint64, n int) ([]uint64, error) {
+	b, err := d.bytes(offset, n*d.refSize)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]uint64, n)
+	for i := range refs {
+		refs[i] = readUint(b[i*d.refSize : (i+1)*d.refSize])
+	}
+	return refs, nil
+}
@@ -0,0 +1,172 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Unmarshal decodes an XML or binary property list. Values are returned as
+// string, bool, int64, float64, time.Time, []byte, []any and map[string]any.
+func Unmarshal(data []byte) (any, error) {
+	if IsBinary(data) {
+		return unmarshalBinary(data)
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// The DOCTYPE and plist element are skipped until the first value
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, errors.New("plist: no value found")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("plist: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local != "plist" {
+			return decodeElement(decoder, start)
+		}
+	}
+}
+
+// decodeElement decodes the value started by start
+func decodeElement(decoder *xml.Decoder, start xml.StartElement) (any, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := map[string]any{}
+		for {
+			key, end, err := nextElement(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if end {
+				return dict, nil
+			}
+			if key.Name.Local != "key" {
+				return nil, fmt.Errorf("plist: expected key in dict, got %s", key.Name.Local)
+			}
+			name, err := text(decoder)
+			if err != nil {
+				return nil, err
+			}
+
+			value, end, err := nextElement(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if end {
+				return nil, fmt.Errorf("plist: missing value for key %s", name)
+			}
+			if dict[name], err = decodeElement(decoder, value); err != nil {
+				return nil, err
+			}
+		}
+	case "array":
+		array := []any{}
+		for {
+			element, end, err := nextElement(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if end {
+				return array, nil
+			}
+			value, err := decodeElement(decoder, element)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+	case "true", "false":
+		if err := decoder.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	s, err := text(decoder)
+	if err != nil {
+		return nil, err
+	}
+	switch start.Name.Local {
+	case "string":
+		return s, nil
+	case "integer":
+		s = strings.TrimSpace(s)
+		if v, err := strconv.ParseInt(s, 0, 64); err == nil {
+			return v, nil
+		}
+		v, err := strconv.ParseUint(s, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("plist: invalid integer %q", s)
+		}
+		return int64(v), nil
+	case "real":
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("plist: invalid real %q", s)
+		}
+		return v, nil
+	case "date":
+		v, err := time.Parse(time.RFC3339, strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("plist: invalid date %q", s)
+		}
+		return v, nil
+	case "data":
+		clean := strings.Map(func(r rune) rune {
+			if r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+				return -1
+			}
+			return r
+		}, s)
+		v, err := base64.StdEncoding.DecodeString(clean)
+		if err != nil {
+			return nil, fmt.Errorf("plist: invalid data: %w", err)
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("plist: unknown element %s", start.Name.Local)
+}
+
+// nextElement returns the next start element, or end=true when the enclosing
+// element ends first
+func nextElement(decoder *xml.Decoder) (xml.StartElement, bool, error) {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.StartElement{}, false, fmt.Errorf("plist: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			return t, false, nil
+		case xml.EndElement:
+			return xml.StartElement{}, true, nil
+		}
+	}
+}
+
+// text returns the character data of the current element and consumes its end
+func text(decoder *xml.Decoder) (string, error) {
+	var b strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("plist: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			b.Write(t)
+		case xml.EndElement:
+			return b.String(), nil
+		case xml.StartElement:
+			return "", fmt.Errorf("plist: unexpected element %s", t.Name.Local)
+		}
+	}
+}
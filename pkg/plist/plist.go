@@ -0,0 +1,139 @@
+// Package plist encodes and decodes property lists in Apple's XML and binary
+// (bplist00) formats.
+//
+// Values map to plist types as follows: string, bool, signed and unsigned
+// integers, float32/float64, time.Time (date), []byte (data), slices (array)
+// and map[string]T (dict). Dictionary keys are written in sorted order so the
+// output is deterministic.
+package plist
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+`
+
+// Format is the encoding of a property list file
+type Format int
+
+const (
+	XMLFormat Format = iota
+	BinaryFormat
+)
+
+// DetectFormat returns the format of an encoded property list
+func DetectFormat(data []byte) Format {
+	if IsBinary(data) {
+		return BinaryFormat
+	}
+	return XMLFormat
+}
+
+// MarshalFormat encodes v in the given format
+func MarshalFormat(v any, format Format) ([]byte, error) {
+	if format == BinaryFormat {
+		return MarshalBinary(v)
+	}
+	return Marshal(v)
+}
+
+// Marshal encodes v as an XML property list
+func Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xmlHeader)
+	if err := encodeValue(&buf, reflect.ValueOf(v), 0); err != nil {
+		return nil, err
+	}
+	buf.WriteString("</plist>\n")
+	return buf.Bytes(), nil
+}
+
+func encodeValue(buf *bytes.Buffer, v reflect.Value, depth int) error {
+	indent := strings.Repeat("\t", depth)
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return fmt.Errorf("plist: cannot encode nil value")
+		}
+		v = v.Elem()
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		fmt.Fprintf(buf, "%s<date>%s</date>\n", indent, t.UTC().Format(time.RFC3339))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		fmt.Fprintf(buf, "%s<string>%s</string>\n", indent, escape(v.String()))
+	case reflect.Bool:
+		if v.Bool() {
+			fmt.Fprintf(buf, "%s<true/>\n", indent)
+		} else {
+			fmt.Fprintf(buf, "%s<false/>\n", indent)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		fmt.Fprintf(buf, "%s<integer>%d</integer>\n", indent, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		fmt.Fprintf(buf, "%s<integer>%d</integer>\n", indent, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		fmt.Fprintf(buf, "%s<real>%s</real>\n", indent, strconv.FormatFloat(v.Float(), 'g', -1, 64))
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			fmt.Fprintf(buf, "%s<data>%s</data>\n", indent, base64.StdEncoding.EncodeToString(v.Bytes()))
+			return nil
+		}
+		if v.Len() == 0 {
+			fmt.Fprintf(buf, "%s<array/>\n", indent)
+			return nil
+		}
+		fmt.Fprintf(buf, "%s<array>\n", indent)
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeValue(buf, v.Index(i), depth+1); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(buf, "%s</array>\n", indent)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("plist: dictionary keys must be strings, got %s", v.Type().Key())
+		}
+		if v.Len() == 0 {
+			fmt.Fprintf(buf, "%s<dict/>\n", indent)
+			return nil
+		}
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintf(buf, "%s<dict>\n", indent)
+		for _, key := range keys {
+			fmt.Fprintf(buf, "%s\t<key>%s</key>\n", indent, escape(key))
+			if err := encodeValue(buf, v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())), depth+1); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(buf, "%s</dict>\n", indent)
+	default:
+		return fmt.Errorf("plist: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// escaper escapes the XML special characters of strings and keys
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
@@ -0,0 +1,143 @@
+package plist
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testdata/Info.plist was written by Python's plistlib with
+// plistlib.dumps(value, fmt=plistlib.FMT_BINARY), an encoder independent of
+// this package, from the value below
+func fixtureValue() map[string]any {
+	numbers := make([]any, 20)
+	for i := range numbers {
+		numbers[i] = int64(i)
+	}
+	return map[string]any{
+		"CFBundleIdentifier":            "com.acme.app",
+		"CFBundleDisplayName":           "Café ☕",
+		"CFBundleVersion":               int64(7),
+		"Negative":                      int64(-42),
+		"Large":                         int64(1 << 40),
+		"Scale":                         2.5,
+		"ITSAppUsesNonExemptEncryption": false,
+		"LSRequiresIPhoneOS":            true,
+		"Created":                       time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+		"Data":                          []byte("\x00\x01\x02velo"),
+		"UISupportedInterfaceOrientations": []any{
+			"UIInterfaceOrientationPortrait",
+			"UIInterfaceOrientationLandscapeLeft",
+		},
+		"Numbers": numbers,
+		"UIApplicationSceneManifest": map[string]any{
+			"UIApplicationSupportsMultipleScenes": false,
+			"Empty":                               map[string]any{},
+		},
+	}
+}
+
+// equal compares decoded values, dates by instant
+func equal(t *testing.T, got, want any) {
+	t.Helper()
+	gotMap, ok := got.(map[string]any)
+	if !ok {
+		t.Fatalf("decoded a %T, want a dictionary", got)
+	}
+	for key, value := range want.(map[string]any) {
+		if date, ok := value.(time.Time); ok {
+			if got, ok := gotMap[key].(time.Time); !ok || !got.Equal(date) {
+				t.Errorf("%s = %v, want %v", key, gotMap[key], date)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(gotMap[key], value) {
+			t.Errorf("%s = %#v, want %#v", key, gotMap[key], value)
+		}
+	}
+	if len(gotMap) != len(want.(map[string]any)) {
+		t.Errorf("decoded %d keys, want %d", len(gotMap), len(want.(map[string]any)))
+	}
+}
+
+func TestUnmarshalBinaryFixture(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "Info.plist"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if DetectFormat(data) != BinaryFormat {
+		t.Fatal("fixture not detected as binary")
+	}
+	v, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal(t, v, fixtureValue())
+}
+
+func TestRoundTrip(t *testing.T) {
+	for name, format := range map[string]Format{"XML": XMLFormat, "binary": BinaryFormat} {
+		t.Run(name, func(t *testing.T) {
+			data, err := MarshalFormat(fixtureValue(), format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if DetectFormat(data) != format {
+				t.Errorf("DetectFormat = %v", DetectFormat(data))
+			}
+			v, err := Unmarshal(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			equal(t, v, fixtureValue())
+
+			// Sorted keys make the output deterministic
+			again, err := MarshalFormat(fixtureValue(), format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != string(data) {
+				t.Error("encoding the same value twice differs")
+			}
+		})
+	}
+}
+
+func TestMarshalEscapes(t *testing.T) {
+	data, err := Marshal(map[string]any{"a<b": "x & y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "<key>a&lt;b</key>") || !strings.Contains(string(data), "<string>x &amp; y</string>") {
+		t.Errorf("special characters not escaped:\n%s", data)
+	}
+	v, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, map[string]any{"a<b": "x & y"}) {
+		t.Errorf("decoded %#v", v)
+	}
+}
+
+func TestUnmarshalBinaryErrors(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "Info.plist"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, b := range map[string][]byte{
+		"magic only": []byte(binaryMagic),
+		"truncated":  data[:len(data)-10],
+		"no trailer": data[:len(data)/2],
+	} {
+		if _, err := Unmarshal(b); err == nil {
+			t.Errorf("%s: Unmarshal succeeded", name)
+		}
+	}
+	if _, err := MarshalBinary(map[int]string{1: "a"}); err == nil {
+		t.Error("MarshalBinary with integer keys succeeded")
+	}
+}
@@ -0,0 +1,150 @@
+package provisioning
+
+import "errors"
+
+// ASN.1 tags used by the CMS envelope
+const (
+	tagOctetString = 0x04
+	tagOID         = 0x06
+)
+
+// element is a BER encoded TLV. Provisioning profiles are signed with
+// indefinite length encoding, which encoding/asn1 does not accept.
+type element struct {
+	class       int
+	constructed bool
+	tag         int
+	// content is the value of primitive elements and the encoded children of
+	// constructed ones
+	content []byte
+	// raw is the whole element, header included
+	raw []byte
+}
+
+var errTruncated = errors.New("truncated BER data")
+
+// parseElement reads the element at the start of data and returns the rest
+func parseElement(data []byte) (element, []byte, error) {
+	if len(data) < 2 {
+		return element{}, nil, errTruncated
+	}
+	e := element{
+		class:       int(data[0] >> 6),
+		constructed: data[0]&0x20 != 0,
+		tag:         int(data[0] & 0x1f),
+	}
+	offset := 1
+	if e.tag == 0x1f {
+		// High tag numbers are base-128 encoded
+		e.tag = 0
+		for {
+			if offset >= len(data) {
+				return element{}, nil, errTruncated
+			}
+			b := data[offset]
+			offset++
+			e.tag = e.tag<<7 | int(b&0x7f)
+			if b&0x80 == 0 {
+				break
+			}
+		}
+	}
+
+	if offset >= len(data) {
+		return element{}, nil, errTruncated
+	}
+	first := data[offset]
+	offset++
+
+	switch {
+	case first == 0x80:
+		// Indefinite length, the content ends with an end-of-contents element
+		if !e.constructed {
+			return element{}, nil, errors.New("indefinite length on a primitive element")
+		}
+		start := offset
+		rest := data[offset:]
+		for {
+			if len(rest) >= 2 && rest[0] == 0 && rest[1] == 0 {
+				e.content = data[start : len(data)-len(rest)]
+				rest = rest[2:]
+				e.raw = data[:len(data)-len(rest)]
+				return e, rest, nil
+			}
+			var err error
+			if _, rest, err = parseElement(rest); err != nil {
+				return element{}, nil, err
+			}
+		}
+	case first&0x80 != 0:
+		n := int(first & 0x7f)
+		if n > 4 || offset+n > len(data) {
+			return element{}, nil, errors.New("invalid BER length")
+		}
+		length := 0
+		for _, b := range data[offset : offset+n] {
+			length = length<<8 | int(b)
+		}
+		offset += n
+		if length < 0 || offset+length > len(data) {
+			return element{}, nil, errTruncated
+		}
+		e.content = data[offset : offset+length]
+		e.raw = data[:offset+length]
+		return e, data[offset+length:], nil
+	default:
+		length := int(first)
+		if offset+length > len(data) {
+			return element{}, nil, errTruncated
+		}
+		e.content = data[offset : offset+length]
+		e.raw = data[:offset+length]
+		return e, data[offset+length:], nil
+	}
+}
+
+// children parses the elements inside a constructed element
+func (e element) children() ([]element, error) {
+	if !e.constructed {
+		return nil, errors.New("primitive element has no children")
+	}
+	var out []element
+	rest := e.content
+	for len(rest) > 0 {
+		var (
+			child element
+			err   error
+		)
+		if child, rest, err = parseElement(rest); err != nil {
+			return nil, err
+		}
+		out = append(out, child)
+	}
+	return out, nil
+}
+
+// octets returns the value of an OCTET STRING, joining the segments of the
+// constructed form
+func (e element) octets() ([]byte, error) {
+	if !e.constructed {
+		return e.content, nil
+	}
+	children, err := e.children()
+	if err != nil {
+		return nil, err
+	}
+	var out []byte
+	for _, child := range children {
+		segment, err := child.octets()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, segment...)
+	}
+	return out, nil
+}
+
+// isContext reports whether e is the context-specific tag n
+func (e element) isContext(n int) bool {
+	return e.class == 2 && e.tag == n
+}
@@ -0,0 +1,118 @@
+package provisioning
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Requirements describe the app a profile is used to sign
+type Requirements struct {
+	BundleID string
+	TeamID   string
+	// ExportMethod is the xcodebuild export method, compared with Type
+	ExportMethod string
+	// Entitlements requested by the app. Each must be granted by the profile.
+	Entitlements map[string]any
+}
+
+// Check verifies that the profile can sign the app described by req at time
+// now. All problems are reported in a single error.
+func (p *Profile) Check(req Requirements, now time.Time) error {
+	var problems []string
+
+	if p.Expired(now) {
+		problems = append(problems, fmt.Sprintf("expired on %s", p.Expiration.Format("2006-01-02")))
+	}
+	if req.TeamID != "" && !contains(p.TeamIDs, req.TeamID) {
+		problems = append(problems, fmt.Sprintf("belongs to team %s, not %s", strings.Join(p.TeamIDs, ", "), req.TeamID))
+	}
+	if req.BundleID != "" && !matchPattern(p.AppIDPattern(), req.BundleID) {
+		problems = append(problems, fmt.Sprintf("app ID %s does not match bundle ID %s", p.AppIDPattern(), req.BundleID))
+	}
+	if req.ExportMethod != "" && req.ExportMethod != p.Type() {
+		problems = append(problems, fmt.Sprintf("is %s %s profile, but the export method is %s", article(p.Type()), p.Type(), req.ExportMethod))
+	}
+
+	for key, want := range req.Entitlements {
+		have, ok := p.Entitlements[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("does not grant the %s entitlement", key))
+			continue
+		}
+		if !granted(want, have) {
+			problems = append(problems, fmt.Sprintf("grants %s = %v, the app requests %v", key, have, want))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("provisioning profile %q %s", p.Name, strings.Join(problems, "; "))
+}
+
+// article returns the indefinite article of a profile type, "an ad-hoc"
+// but "a development"
+func article(word string) string {
+	if word != "" && strings.ContainsRune("aeiou", rune(word[0])) {
+		return "an"
+	}
+	return "a"
+}
+
+// granted reports whether the profile value have allows the requested value.
+// Profile strings may end with a * wildcard and arrays allow any subset of
+// their elements.
+func granted(want, have any) bool {
+	switch h := have.(type) {
+	case string:
+		w, ok := want.(string)
+		return ok && matchPattern(h, w)
+	case []any:
+		wants, ok := want.([]any)
+		if !ok {
+			wants = []any{want}
+		}
+		for _, w := range wants {
+			found := false
+			for _, candidate := range h {
+				if granted(w, candidate) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(normalize(want), normalize(have))
+}
+
+// normalize converts JSON numbers to the int64 used by the plist decoder
+func normalize(v any) any {
+	if f, ok := v.(float64); ok && f == float64(int64(f)) {
+		return int64(f)
+	}
+	return v
+}
+
+// matchPattern matches a value against a profile pattern, where a trailing *
+// matches any suffix
+func matchPattern(pattern, value string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(value, prefix)
+	}
+	return pattern == value
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,265 @@
+// Package provisioning decodes iOS provisioning profiles (.mobileprovision)
+// and checks them against the app being signed.
+//
+// A profile is a CMS SignedData envelope whose content is an XML plist with
+// the team, app ID, entitlements, devices and developer certificates.
+package provisioning
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/velogo-dev/velo/pkg/plist"
+)
+
+// Profile types, named like the export methods of xcodebuild
+const (
+	TypeDevelopment = "development"
+	TypeAdHoc       = "ad-hoc"
+	TypeAppStore    = "app-store"
+	TypeEnterprise  = "enterprise"
+)
+
+var oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+
+// Profile is a decoded provisioning profile
+type Profile struct {
+	Path string
+
+	Name         string
+	UUID         string
+	AppIDName    string
+	TeamName     string
+	TeamIDs      []string
+	Platforms    []string
+	CreationDate time.Time
+	Expiration   time.Time
+	// Devices are the UDIDs the profile can be installed on. Empty for App
+	// Store and enterprise profiles.
+	Devices              []string
+	ProvisionsAllDevices bool
+	Entitlements         map[string]any
+	Certificates         []*x509.Certificate
+	// Signer is the certificate that signed the profile, normally Apple's
+	// provisioning profile signing certificate
+	Signer *x509.Certificate
+}
+
+// ParseFile decodes the provisioning profile at path
+func ParseFile(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	profile.Path = path
+	return profile, nil
+}
+
+// Parse decodes a provisioning profile
+func Parse(data []byte) (*Profile, error) {
+	content, signer, err := unwrapCMS(data)
+	if err != nil {
+		return nil, err
+	}
+	value, err := plist.Unmarshal(content)
+	if err != nil {
+		return nil, err
+	}
+	dict, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("profile content is not a dictionary")
+	}
+
+	p := &Profile{
+		Name:                 stringValue(dict["Name"]),
+		UUID:                 stringValue(dict["UUID"]),
+		AppIDName:            stringValue(dict["AppIDName"]),
+		TeamName:             stringValue(dict["TeamName"]),
+		TeamIDs:              stringList(dict["TeamIdentifier"]),
+		Platforms:            stringList(dict["Platform"]),
+		Devices:              stringList(dict["ProvisionedDevices"]),
+		ProvisionsAllDevices: dict["ProvisionsAllDevices"] == true,
+		Signer:               signer,
+	}
+	p.CreationDate, _ = dict["CreationDate"].(time.Time)
+	p.Expiration, _ = dict["ExpirationDate"].(time.Time)
+	if p.Entitlements, ok = dict["Entitlements"].(map[string]any); !ok {
+		p.Entitlements = map[string]any{}
+	}
+
+	if certs, ok := dict["DeveloperCertificates"].([]any); ok {
+		for _, c := range certs {
+			der, ok := c.([]byte)
+			if !ok {
+				continue
+			}
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return nil, fmt.Errorf("invalid developer certificate: %w", err)
+			}
+			p.Certificates = append(p.Certificates, cert)
+		}
+	}
+	return p, nil
+}
+
+// unwrapCMS returns the content of a CMS SignedData envelope and the first
+// certificate it carries
+func unwrapCMS(data []byte) ([]byte, *x509.Certificate, error) {
+	invalid := func(what string) ([]byte, *x509.Certificate, error) {
+		return nil, nil, fmt.Errorf("invalid CMS envelope: %s", what)
+	}
+
+	contentInfo, _, err := parseElement(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	fields, err := contentInfo.children()
+	if err != nil || len(fields) < 2 || fields[0].tag != tagOID {
+		return invalid("malformed ContentInfo")
+	}
+	var oid asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(fields[0].raw, &oid); err != nil || !oid.Equal(oidSignedData) {
+		return invalid("content is not SignedData")
+	}
+
+	wrapper, err := fields[1].children()
+	if err != nil || len(wrapper) != 1 {
+		return invalid("malformed SignedData wrapper")
+	}
+	signedData, err := wrapper[0].children()
+	if err != nil || len(signedData) < 3 {
+		return invalid("malformed SignedData")
+	}
+
+	// version, digestAlgorithms, encapContentInfo, [0] certificates, ...
+	encap, err := signedData[2].children()
+	if err != nil || len(encap) < 2 || !encap[1].isContext(0) {
+		return invalid("missing encapsulated content")
+	}
+	econtent, err := encap[1].children()
+	if err != nil || len(econtent) != 1 || econtent[0].tag != tagOctetString {
+		return invalid("malformed encapsulated content")
+	}
+	content, err := econtent[0].octets()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var signer *x509.Certificate
+	for _, field := range signedData[3:] {
+		if !field.isContext(0) {
+			continue
+		}
+		if certs, err := field.children(); err == nil && len(certs) > 0 {
+			signer, _ = x509.ParseCertificate(certs[0].raw)
+		}
+	}
+	return content, signer, nil
+}
+
+// Type returns the kind of distribution the profile allows
+func (p *Profile) Type() string {
+	switch {
+	case p.ProvisionsAllDevices:
+		return TypeEnterprise
+	case p.Entitlements["get-task-allow"] == true:
+		return TypeDevelopment
+	case len(p.Devices) > 0:
+		return TypeAdHoc
+	}
+	return TypeAppStore
+}
+
+// ApplicationIdentifier returns the app ID of the profile including the team
+// prefix, e.g. ABCDE12345.com.example.* for a wildcard profile
+func (p *Profile) ApplicationIdentifier() string {
+	return stringValue(p.Entitlements["application-identifier"])
+}
+
+// AppIDPattern returns the bundle ID pattern without the team prefix
+func (p *Profile) AppIDPattern() string {
+	id := p.ApplicationIdentifier()
+	if _, pattern, ok := strings.Cut(id, "."); ok {
+		return pattern
+	}
+	return id
+}
+
+// Expired reports whether the profile is expired at t
+func (p *Profile) Expired(t time.Time) bool {
+	return !p.Expiration.IsZero() && t.After(p.Expiration)
+}
+
+// EntitlementKeys returns the entitlement names in sorted order
+func (p *Profile) EntitlementKeys() []string {
+	keys := make([]string, 0, len(p.Entitlements))
+	for key := range p.Entitlements {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Find returns the installed profile whose name or UUID is nameOrUUID
+func Find(nameOrUUID string) (*Profile, error) {
+	var matches []*Profile
+	for _, dir := range InstalledDirs() {
+		paths, _ := filepath.Glob(filepath.Join(dir, "*.mobileprovision"))
+		for _, path := range paths {
+			profile, err := ParseFile(path)
+			if err != nil {
+				continue
+			}
+			if profile.UUID == nameOrUUID || profile.Name == nameOrUUID {
+				matches = append(matches, profile)
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("provisioning profile %q is not installed", nameOrUUID)
+	}
+
+	// Several profiles can share a name after renewals, use the newest one
+	sort.Slice(matches, func(i, j int) bool { return matches[i].CreationDate.After(matches[j].CreationDate) })
+	return matches[0], nil
+}
+
+// InstalledDirs returns the directories Xcode installs profiles into
+func InstalledDirs() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return []string{
+		filepath.Join(home, "Library", "Developer", "Xcode", "UserData", "Provisioning Profiles"),
+		filepath.Join(home, "Library", "MobileDevice", "Provisioning Profiles"),
+	}
+}
+
+func stringValue(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+func stringList(v any) []string {
+	values, _ := v.([]any)
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
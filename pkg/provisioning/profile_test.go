@@ -0,0 +1,171 @@
+package provisioning
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testdata/acme.mobileprovision is a development profile signed with
+//
+//	openssl cms -sign -nodetach -binary -stream -outform DER
+//
+// which, like Apple's signing service, writes BER with indefinite lengths and
+// a chunked content octet string
+const fixture = "testdata/acme.mobileprovision"
+
+func TestParseFile(t *testing.T) {
+	p, err := ParseFile(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Path != fixture {
+		t.Errorf("Path = %q", p.Path)
+	}
+	for field, pair := range map[string][2]string{
+		"Name":      {p.Name, "Acme Development"},
+		"UUID":      {p.UUID, "0F0E0D0C-0B0A-0908-0706-050403020100"},
+		"AppIDName": {p.AppIDName, "Acme"},
+		"TeamName":  {p.TeamName, "Acme Inc."},
+		"Type":      {p.Type(), TypeDevelopment},
+		"AppID":     {p.ApplicationIdentifier(), "ABCDE12345.com.acme.*"},
+		"Pattern":   {p.AppIDPattern(), "com.acme.*"},
+	} {
+		if pair[0] != pair[1] {
+			t.Errorf("%s = %q, want %q", field, pair[0], pair[1])
+		}
+	}
+	if !reflect.DeepEqual(p.TeamIDs, []string{"ABCDE12345"}) || !reflect.DeepEqual(p.Platforms, []string{"iOS"}) {
+		t.Errorf("TeamIDs = %v, Platforms = %v", p.TeamIDs, p.Platforms)
+	}
+	if len(p.Devices) != 2 || p.ProvisionsAllDevices {
+		t.Errorf("Devices = %v, ProvisionsAllDevices = %t", p.Devices, p.ProvisionsAllDevices)
+	}
+	if !p.CreationDate.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !p.Expiration.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("CreationDate = %v, Expiration = %v", p.CreationDate, p.Expiration)
+	}
+	wantKeys := []string{"application-identifier", "aps-environment", "com.apple.developer.team-identifier", "get-task-allow", "keychain-access-groups"}
+	if !reflect.DeepEqual(p.EntitlementKeys(), wantKeys) {
+		t.Errorf("EntitlementKeys = %v", p.EntitlementKeys())
+	}
+	if len(p.Certificates) != 1 || !strings.HasPrefix(p.Certificates[0].Subject.CommonName, "Apple Development: ") {
+		t.Errorf("Certificates = %v", p.Certificates)
+	}
+	if p.Signer == nil || p.Signer.Subject.CommonName != "Test Provisioning Profile Signing" {
+		t.Errorf("Signer = %v", p.Signer)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	data, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, b := range map[string][]byte{
+		"empty":     nil,
+		"plist":     []byte("<plist><dict/></plist>"),
+		"truncated": data[:len(data)/2],
+	} {
+		if _, err := Parse(b); err == nil {
+			t.Errorf("%s: Parse succeeded", name)
+		}
+	}
+}
+
+func TestType(t *testing.T) {
+	for want, p := range map[string]*Profile{
+		TypeAppStore:    {Entitlements: map[string]any{}},
+		TypeAdHoc:       {Entitlements: map[string]any{}, Devices: []string{"udid"}},
+		TypeDevelopment: {Entitlements: map[string]any{"get-task-allow": true}, Devices: []string{"udid"}},
+		TypeEnterprise:  {Entitlements: map[string]any{}, ProvisionsAllDevices: true},
+	} {
+		if got := p.Type(); got != want {
+			t.Errorf("Type = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	p, err := ParseFile(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	req := Requirements{
+		BundleID:     "com.acme.app",
+		TeamID:       "ABCDE12345",
+		ExportMethod: TypeDevelopment,
+		Entitlements: map[string]any{
+			"aps-environment":        "development",
+			"keychain-access-groups": []any{"ABCDE12345.com.acme.shared"},
+			"get-task-allow":         true,
+		},
+	}
+	if err := p.Check(req, valid); err != nil {
+		t.Errorf("Check: %v", err)
+	}
+
+	req = Requirements{
+		BundleID:     "com.other.app",
+		TeamID:       "ZZZZZ99999",
+		ExportMethod: TypeAdHoc,
+		Entitlements: map[string]any{
+			"aps-environment":               "production",
+			"com.apple.developer.healthkit": true,
+		},
+	}
+	err = p.Check(req, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	if err == nil {
+		t.Fatal("Check succeeded")
+	}
+	for _, want := range []string{
+		"expired on 2025-01-01",
+		"belongs to team ABCDE12345, not ZZZZZ99999",
+		"app ID com.acme.* does not match bundle ID com.other.app",
+		"is a development profile, but the export method is ad-hoc",
+		"grants aps-environment = development, the app requests production",
+		"does not grant the com.apple.developer.healthkit entitlement",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	data, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(home, "Library", "MobileDevice", "Provisioning Profiles")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string][]byte{
+		"acme.mobileprovision":   data,
+		"broken.mobileprovision": []byte("not a profile"),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, query := range []string{"Acme Development", "0F0E0D0C-0B0A-0908-0706-050403020100"} {
+		p, err := Find(query)
+		if err != nil {
+			t.Errorf("Find(%q): %v", query, err)
+			continue
+		}
+		if p.Path != filepath.Join(dir, "acme.mobileprovision") {
+			t.Errorf("Find(%q) = %s", query, p.Path)
+		}
+	}
+	if _, err := Find("Missing"); err == nil {
+		t.Error("Find of a missing profile succeeded")
+	}
+}
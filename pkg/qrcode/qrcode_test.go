@@ -0,0 +1,249 @@
+package qrcode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// Byte mode capacities at level M from table 7 of ISO/IEC 18004
+func TestCapacity(t *testing.T) {
+	for _, tc := range []struct {
+		length, size int
+	}{
+		{1, 21},
+		{14, 21},
+		{15, 25},
+		{26, 25},
+		{27, 29},
+		{122, 45},
+		{123, 49},
+		{213, 57},
+	} {
+		c, err := Encode(strings.Repeat("a", tc.length))
+		if err != nil {
+			t.Errorf("%d bytes: %v", tc.length, err)
+			continue
+		}
+		if c.Size != tc.size {
+			t.Errorf("%d bytes: size %d, want %d", tc.length, c.Size, tc.size)
+		}
+	}
+	if _, err := Encode(strings.Repeat("a", 214)); err == nil {
+		t.Error("214 bytes fit")
+	}
+}
+
+// The generator polynomial of degree 10 from annex A, as exponents of alpha
+func TestGeneratorKnownVector(t *testing.T) {
+	exp := make([]byte, 255)
+	x := byte(1)
+	for i := range exp {
+		exp[i] = x
+		x = gfMultiply(x, 2)
+	}
+	for i, e := range []int{251, 67, 46, 61, 118, 70, 64, 94, 32, 45} {
+		if got := rsDivisor(10)[i]; got != exp[e] {
+			t.Errorf("coefficient %d = %d, want alpha^%d = %d", i, got, e, exp[e])
+		}
+	}
+}
+
+// The error correction of the 1-M code of "HELLO WORLD" in alphanumeric mode,
+// the worked example of many QR code tutorials
+func TestErrorCorrectionKnownVector(t *testing.T) {
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("error correction = %v, want %v", got, want)
+	}
+}
+
+// The format information of level M by mask, from table C.1
+func TestFormatBitsKnownVector(t *testing.T) {
+	want := []int{0x5412, 0x5125, 0x5E7C, 0x5B4B, 0x45F9, 0x40CE, 0x4F97, 0x4AA0}
+	for mask, bits := range want {
+		c := &Code{Size: 21, modules: grid(21), function: grid(21)}
+		c.drawFormatBits(mask)
+		if got := formatBits(c); got != bits {
+			t.Errorf("mask %d: format bits %015b, want %015b", mask, got, bits)
+		}
+	}
+}
+
+// The version information from table D.1, drawn for versions 7 and up
+func TestVersionBitsKnownVector(t *testing.T) {
+	for version, bits := range map[int]int{7: 0x07C94, 8: 0x085BC, 9: 0x09A99, 10: 0x0A4D3} {
+		c := &Code{Size: 17 + 4*version, modules: grid(17 + 4*version), function: grid(17 + 4*version)}
+		c.drawFunctionPatterns(version)
+		got := 0
+		for i := 0; i < 18; i++ {
+			a, b := c.Size-11+i%3, i/3
+			if c.Dark(a, b) != c.Dark(b, a) {
+				t.Errorf("version %d: copies of bit %d differ", version, i)
+			}
+			if c.Dark(a, b) {
+				got |= 1 << i
+			}
+		}
+		if got != bits {
+			t.Errorf("version %d: version bits %018b, want %018b", version, got, bits)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, text := range []string{
+		"http://192.168.1.20:3000",
+		"velo://dev?url=http%3A%2F%2F192.168.1.20%3A3000",
+		"Grüße ☕",
+		strings.Repeat("0123456789abcdef", 8),
+		strings.Repeat("x", 213),
+	} {
+		c, err := Encode(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := decode(t, c); got != text {
+			t.Errorf("decoded %q, want %q", got, text)
+		}
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	c, err := Encode("velo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(c.Terminal(), "\n"), "\n")
+	width := c.Size + 2*quietZone
+	if len(lines) != (width+1)/2 {
+		t.Errorf("%d lines, want %d", len(lines), (width+1)/2)
+	}
+	for i, line := range lines {
+		if n := utf8.RuneCountInString(line); n != width {
+			t.Errorf("line %d is %d wide, want %d", i, n, width)
+		}
+	}
+	// The quiet zone is light and so drawn as full blocks
+	if lines[0] != strings.Repeat("█", width) {
+		t.Errorf("first line is not quiet: %q", lines[0])
+	}
+}
+
+// formatBits reads the copy of the format information next to the bottom
+// left and top right finders
+func formatBits(c *Code) int {
+	bits := 0
+	for i := 0; i < 15; i++ {
+		x, y := c.Size-1-i, 8
+		if i >= 8 {
+			x, y = 8, c.Size-15+i
+		}
+		if c.Dark(x, y) {
+			bits |= 1 << i
+		}
+	}
+	return bits
+}
+
+// decode reads the text of a code the way a scanner does: it unmasks the data
+// modules, collects the codewords, checks the error correction of the blocks
+// and parses the byte mode segment
+func decode(t *testing.T, c *Code) string {
+	t.Helper()
+	version := (c.Size - 17) / 4
+	format := formatBits(c) ^ 0x5412
+	if format>>13 != 0 {
+		t.Fatalf("error correction level %02b, want M", format>>13)
+	}
+	mask := format >> 10 & 7
+	masked := map[int]func(x, y int) bool{
+		0: func(x, y int) bool { return (x+y)%2 == 0 },
+		1: func(x, y int) bool { return y%2 == 0 },
+		2: func(x, y int) bool { return x%3 == 0 },
+		3: func(x, y int) bool { return (x+y)%3 == 0 },
+		4: func(x, y int) bool { return (x/3+y/2)%2 == 0 },
+		5: func(x, y int) bool { return x*y%2+x*y%3 == 0 },
+		6: func(x, y int) bool { return (x*y%2+x*y%3)%2 == 0 },
+		7: func(x, y int) bool { return ((x+y)%2+x*y%3)%2 == 0 },
+	}[mask]
+
+	// Column pairs from the right, alternating up and down
+	var bits []bool
+	up := true
+	for right := c.Size - 1; right > 0; right -= 2 {
+		if right == 6 {
+			right--
+		}
+		for i := 0; i < c.Size; i++ {
+			y := i
+			if up {
+				y = c.Size - 1 - i
+			}
+			for _, x := range []int{right, right - 1} {
+				if !c.function[y][x] {
+					bits = append(bits, c.Dark(x, y) != masked(x, y))
+				}
+			}
+		}
+		up = !up
+	}
+	codewords := make([]byte, len(bits)/8)
+	for i := range codewords {
+		for _, bit := range bits[8*i : 8*i+8] {
+			codewords[i] <<= 1
+			if bit {
+				codewords[i] |= 1
+			}
+		}
+	}
+
+	// Undo the interleaving and check every block
+	layout := layouts[version]
+	sizes := make([]int, 0, layout.blocks1+layout.blocks2)
+	for i := 0; i < layout.blocks1; i++ {
+		sizes = append(sizes, layout.data1)
+	}
+	for i := 0; i < layout.blocks2; i++ {
+		sizes = append(sizes, layout.data2)
+	}
+	blocks := make([][]byte, len(sizes))
+	next := 0
+	for i := 0; i < max(layout.data1, layout.data2)+layout.ecPerBlock; i++ {
+		for b, size := range sizes {
+			if i < size || i >= max(layout.data1, layout.data2) {
+				blocks[b] = append(blocks[b], codewords[next])
+				next++
+			}
+		}
+	}
+	var data []byte
+	for b, block := range blocks {
+		n := sizes[b]
+		if ec := rsRemainder(block[:n], rsDivisor(layout.ecPerBlock)); !bytes.Equal(ec, block[n:]) {
+			t.Fatalf("block %d: error correction does not match", b)
+		}
+		data = append(data, block[:n]...)
+	}
+
+	// Mode indicator 0100, then the count and the bytes
+	if data[0]>>4 != 0b0100 {
+		t.Fatalf("mode %04b, want byte mode", data[0]>>4)
+	}
+	bit := 4
+	read := func(n int) int {
+		v := 0
+		for i := 0; i < n; i++ {
+			v = v<<1 | int(data[bit/8]>>(7-bit%8)&1)
+			bit++
+		}
+		return v
+	}
+	text := make([]byte, read(countBits(version)))
+	for i := range text {
+		text[i] = byte(read(8))
+	}
+	return string(text)
+}
@@ -0,0 +1,240 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ConsolePath is the WebSocket endpoint the console client sends to
+const ConsolePath = EndpointPrefix + "console"
+
+// ConsoleScript is injected into the pages of the dev proxy. It forwards
+// console calls, uncaught errors and unhandled promise rejections to
+// ConsolePath, queueing them while the socket is not connected. The
+// sourceURL names the script in stack traces, so its own frames are skipped
+// when looking for the caller.
+const ConsoleScript = `(function () {
+  if (window.__veloConsole) return;
+  window.__veloConsole = true;
+  var url = (location.protocol === "https:" ? "wss://" : "ws://") + location.host + "` + ConsolePath + `";
+  var socket = null, queue = [], retry = 500;
+
+  function connect() {
+    socket = new WebSocket(url);
+    socket.onopen = function () {
+      retry = 500;
+      while (queue.length) socket.send(queue.shift());
+    };
+    socket.onclose = function () {
+      socket = null;
+      setTimeout(connect, retry);
+      retry = Math.min(retry * 2, 10000);
+    };
+  }
+
+  function send(level, message, source) {
+    var data = JSON.stringify({ level: level, message: message, source: source || "", time: Date.now() });
+    if (socket && socket.readyState === 1) socket.send(data);
+    else if (queue.length < 500) queue.push(data);
+  }
+
+  function format(value) {
+    if (value instanceof Error) return value.stack || String(value);
+    if (typeof value === "string") return value;
+    try { return JSON.stringify(value); } catch (e) { return String(value); }
+  }
+
+  // caller returns the first url:line:column of a stack outside this script
+  function caller(stack) {
+    var lines = String(stack || "").split("\n");
+    for (var i = 0; i < lines.length; i++) {
+      if (lines[i].indexOf("velo-console.js") >= 0) continue;
+      var match = lines[i].match(/([a-z-]+:\/\/\/?[^\s()]+:\d+:\d+)/i);
+      if (match) return match[1];
+    }
+    return "";
+  }
+
+  ["log", "info", "warn", "error", "debug"].forEach(function (level) {
+    var original = console[level];
+    console[level] = function () {
+      send(level, Array.prototype.map.call(arguments, format).join(" "), caller(new Error().stack));
+      return original.apply(console, arguments);
+    };
+  });
+
+  window.addEventListener("error", function (event) {
+    var message = event.error && event.error.stack ? event.error.stack : event.message;
+    send("error", message, event.filename ? event.filename + ":" + event.lineno + ":" + event.colno : "");
+  });
+  window.addEventListener("unhandledrejection", function (event) {
+    var reason = event.reason;
+    send("error", "Unhandled rejection: " + format(reason), reason && reason.stack ? caller(reason.stack) : "");
+  });
+
+  connect();
+})();
+//# sourceURL=velo-console.js`
+
+// ConsoleEntry is a console message, uncaught error or unhandled rejection
+// of a page
+type ConsoleEntry struct {
+	// Device tags the device the page runs on, e.g. Pixel 7 or iPhone
+	Device string `json:"-"`
+	// Level is log, info, warn, error or debug
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	// Source is the url:line:column of the call
+	Source string `json:"source"`
+	// Time is the device clock in milliseconds since the epoch
+	Time int64 `json:"time"`
+}
+
+// ConsoleLog receives the entries of ConsoleScript over WebSockets and
+// prints them
+type ConsoleLog struct {
+	// Output receives the entries with colored levels, e.g. the terminal
+	Output io.Writer
+	// File mirrors the entries without colors when set
+	File io.Writer
+
+	mu sync.Mutex
+}
+
+var levelStyles = map[string]lipgloss.Style{
+	"error": lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Bold(true),
+	"warn":  lipgloss.NewStyle().Foreground(lipgloss.Color("3")),
+	"info":  lipgloss.NewStyle().Foreground(lipgloss.Color("6")),
+	"debug": lipgloss.NewStyle().Foreground(lipgloss.Color("8")),
+	"log":   lipgloss.NewStyle(),
+}
+
+// ServeHTTP accepts the WebSocket of a page and prints its entries until it
+// closes
+func (l *ConsoleLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	device := deviceTag(r.UserAgent())
+	for {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var entry ConsoleEntry
+		if json.Unmarshal(data, &entry) != nil {
+			continue
+		}
+		entry.Device = device
+		l.Print(entry)
+	}
+}
+
+// Print writes an entry to Output and File
+func (l *ConsoleLog) Print(e ConsoleEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Pages control the text of the entries, escape sequences in it would
+	// be run by the terminal
+	e.Device = stripControl(e.Device, "")
+	e.Level = stripControl(e.Level, "")
+	e.Message = stripControl(e.Message, "\n\t")
+	e.Source = stripControl(e.Source, "")
+
+	t := time.UnixMilli(e.Time)
+	if e.Time == 0 {
+		t = time.Now()
+	}
+	source := shortenSource(e.Source)
+	level := strings.ToUpper(e.Level)
+	style, ok := levelStyles[e.Level]
+	if !ok {
+		style = levelStyles["log"]
+	}
+	// Continuation lines, such as those of stack traces, are indented
+	message := strings.ReplaceAll(strings.TrimRight(e.Message, "\n"), "\n", "\n    ")
+
+	if source != "" {
+		message = source + " " + message
+	}
+
+	if l.Output != nil {
+		fmt.Fprintf(l.Output, "%s [%s] %s %s\n", t.Format("15:04:05.000"), e.Device,
+			style.Render(fmt.Sprintf("%-5s", level)), message)
+	}
+	if l.File != nil {
+		fmt.Fprintf(l.File, "%s [%s] %-5s %s\n", t.Format("2006-01-02T15:04:05.000Z07:00"), e.Device, level, message)
+	}
+}
+
+// escapeSequence matches the CSI and OSC escape sequences of terminals
+var escapeSequence = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)?`)
+
+// stripControl removes escape sequences and the control characters not in
+// keep from s
+func stripControl(s, keep string) string {
+	s = escapeSequence.ReplaceAllString(s, "")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && !strings.ContainsRune(keep, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// shortenSource strips the origin and query of a source location, e.g.
+// http://localhost:3000/src/App.tsx?t=171:12:5 becomes src/App.tsx:12:5
+func shortenSource(source string) string {
+	i := strings.LastIndex(source, ":")
+	if i < 0 {
+		return source
+	}
+	j := strings.LastIndex(source[:i], ":")
+	if j < 0 {
+		return source
+	}
+	u, err := url.Parse(source[:j])
+	if err != nil || u.Path == "" {
+		return source
+	}
+	return strings.TrimPrefix(u.Path, "/") + source[j:]
+}
+
+var (
+	androidModel = regexp.MustCompile(`Android [\d.]+; (?:[a-z]{2}[-_][a-zA-Z]{2}; )?([^;)]+?)(?: Build/[^;)]*)?[;)]`)
+	appleDevice  = regexp.MustCompile(`\((iPhone|iPad|iPod)`)
+)
+
+// deviceTag names the device of a user agent: the model of Android devices,
+// the kind of Apple devices and the browser otherwise
+func deviceTag(userAgent string) string {
+	if m := androidModel.FindStringSubmatch(userAgent); m != nil && m[1] != "K" {
+		return m[1]
+	}
+	if strings.Contains(userAgent, "Android") {
+		return "Android"
+	}
+	if m := appleDevice.FindStringSubmatch(userAgent); m != nil {
+		return m[1]
+	}
+	for _, browser := range []string{"Edg", "Firefox", "Chrome", "Safari"} {
+		if strings.Contains(userAgent, browser+"/") {
+			return browser
+		}
+	}
+	return "browser"
+}
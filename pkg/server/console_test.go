@@ -0,0 +1,96 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConsoleRejectsCrossOriginHandshakes(t *testing.T) {
+	ts := httptest.NewServer(&ConsoleLog{})
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	tests := []struct {
+		origin string
+		want   int
+	}{
+		{"", http.StatusSwitchingProtocols},
+		{"http://" + host, http.StatusSwitchingProtocols},
+		{"http://" + strings.ToUpper(host), http.StatusSwitchingProtocols},
+		{"https://evil.example", http.StatusForbidden},
+		{"http://" + host + ".evil.example", http.StatusForbidden},
+		{"http://localhost:1", http.StatusForbidden},
+		{"null", http.StatusForbidden},
+		{"file://", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+ConsolePath, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.want {
+			t.Errorf("Origin %q: status %d, want %d", test.origin, resp.StatusCode, test.want)
+		}
+	}
+}
+
+func TestConsolePrintStripsControlCharacters(t *testing.T) {
+	var output, file bytes.Buffer
+	l := &ConsoleLog{Output: &output, File: &file}
+	l.Print(ConsoleEntry{
+		Device:  "Pixel\x1b[2J 7",
+		Level:   "error\x1b[0m",
+		Message: "\x1b[31mred\x1b[0m\r\nline\a two\x1b]0;pwned\x07\tdone\u009b",
+		Source:  "http://localhost:3000/src/App.tsx\x1b[1A:12:5",
+		Time:    1,
+	})
+
+	for name, got := range map[string]string{"output": output.String(), "file": file.String()} {
+		if strings.ContainsAny(got, "\x1b\a\r\u009b") {
+			t.Errorf("%s has control characters: %q", name, got)
+		}
+		if !strings.Contains(got, "src/App.tsx:12:5 red\n    line two\tdone") {
+			t.Errorf("%s = %q", name, got)
+		}
+		if !strings.Contains(got, "[Pixel 7]") {
+			t.Errorf("%s lost the device: %q", name, got)
+		}
+	}
+	if !strings.Contains(file.String(), "ERROR") {
+		t.Errorf("file lost the level: %q", file.String())
+	}
+}
+
+func TestStripControl(t *testing.T) {
+	tests := []struct {
+		in, keep, want string
+	}{
+		{"plain text", "", "plain text"},
+		{"\x1b[1;32mgreen\x1b[m", "", "green"},
+		{"title\x1b]2;owned\x1b\\ end", "", "title end"},
+		{"a\nb\tc\rd", "\n\t", "a\nb\tcd"},
+		{"a\nb\tc", "", "abc"},
+		{"bell\a and \x00null\x7f", "", "bell and null"},
+		{"ünïcödé ✓", "", "ünïcödé ✓"},
+	}
+	for _, test := range tests {
+		if got := stripControl(test.in, test.keep); got != test.want {
+			t.Errorf("stripControl(%q, %q) = %q, want %q", test.in, test.keep, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EndpointPrefix is the path prefix of the endpoints served by the proxy
+// itself, they are never forwarded to the framework dev server
+const EndpointPrefix = "/__velo/"
+
+// DevProxy is the dev server the shells load in development mode. It forwards
+// HTTP and WebSocket (HMR) requests to the framework dev server, injects
+// scripts into HTML pages, adds headers and serves the endpoints of Velo.
+type DevProxy struct {
+	// Addr is the host:port the proxy listens on
+	Addr string
+	// ExtraAddrs are listened on as well, e.g. the LAN addresses of
+	// velo dev --lan
+	ExtraAddrs []string
+	// TLSConfig serves HTTPS when set, e.g. with velo dev --https. Without
+	// "h2" in NextProtos connections stay on HTTP/1.1, which WebSockets need.
+	TLSConfig *tls.Config
+	// Target is the URL of the framework dev server
+	Target *url.URL
+	// Headers are added to every response
+	Headers http.Header
+	// Log receives failed requests, and every request when Verbose is set.
+	// Nothing is logged when nil.
+	Log     io.Writer
+	Verbose bool
+
+	mu      sync.Mutex
+	scripts []string
+	mux     *http.ServeMux
+	proxy   *httputil.ReverseProxy
+	server  *http.Server
+}
+
+// NewDevProxy creates a proxy listening on addr and forwarding to target
+func NewDevProxy(addr, target string) (*DevProxy, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dev server URL %q: %w", target, err)
+	}
+	p := &DevProxy{
+		Addr:    addr,
+		Target:  u,
+		Headers: http.Header{},
+		mux:     http.NewServeMux(),
+	}
+	p.proxy = &httputil.ReverseProxy{
+		Rewrite:        p.rewrite,
+		ModifyResponse: p.modifyResponse,
+		ErrorHandler:   p.proxyError,
+	}
+	p.Handle(EndpointPrefix+"health", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	}))
+	return p, nil
+}
+
+// InjectScript adds JavaScript run by every HTML page before its own scripts
+func (p *DevProxy) InjectScript(js string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.scripts = append(p.scripts, js)
+}
+
+// Handle serves pattern with handler instead of forwarding it, pattern must
+// start with EndpointPrefix
+func (p *DevProxy) Handle(pattern string, handler http.Handler) {
+	if !strings.HasPrefix(pattern, EndpointPrefix) {
+		panic("server: proxy endpoint " + pattern + " outside " + EndpointPrefix)
+	}
+	p.mux.Handle(pattern, handler)
+}
+
+// Start listens on Addr and ExtraAddrs and serves in the background. It
+// fails right away when an address is in use.
+func (p *DevProxy) Start() error {
+	var listeners []net.Listener
+	for _, addr := range append([]string{p.Addr}, p.ExtraAddrs...) {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			for _, ln := range listeners {
+				ln.Close()
+			}
+			return fmt.Errorf("dev server cannot listen on %s: %w", addr, err)
+		}
+		if p.TLSConfig != nil {
+			ln = tls.NewListener(ln, p.TLSConfig)
+		}
+		listeners = append(listeners, ln)
+	}
+	p.server = &http.Server{Handler: p}
+	for _, ln := range listeners {
+		go p.server.Serve(ln)
+	}
+	return nil
+}
+
+// Shutdown stops the proxy, open connections such as HMR WebSockets are
+// closed
+func (p *DevProxy) Shutdown() error {
+	if p.server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	err := p.server.Shutdown(ctx)
+	// Hijacked WebSocket connections are not tracked by Shutdown
+	p.server.Close()
+	return err
+}
+
+// ServeHTTP implements http.Handler
+func (p *DevProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w}
+
+	if strings.HasPrefix(r.URL.Path, EndpointPrefix) {
+		for key, values := range p.Headers {
+			rec.Header()[key] = values
+		}
+		p.mux.ServeHTTP(rec, r)
+	} else {
+		p.proxy.ServeHTTP(rec, r)
+	}
+
+	if p.Log != nil && (p.Verbose || rec.status >= 400) {
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		fmt.Fprintf(p.Log, "%s %s %d %s\n", r.Method, r.URL.RequestURI(), status, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// rewrite points the request at the framework dev server. The Host header is
+// rewritten as well, dev servers such as Vite reject hosts they do not know.
+func (p *DevProxy) rewrite(r *httputil.ProxyRequest) {
+	r.SetURL(p.Target)
+	r.SetXForwarded()
+	r.Out.Host = p.Target.Host
+	// Responses are read uncompressed to inject scripts into HTML pages
+	r.Out.Header.Del("Accept-Encoding")
+}
+
+// modifyResponse adds the headers and injects the scripts into HTML pages
+func (p *DevProxy) modifyResponse(resp *http.Response) error {
+	for key, values := range p.Headers {
+		resp.Header[key] = values
+	}
+
+	p.mu.Lock()
+	scripts := append([]string{}, p.scripts...)
+	p.mu.Unlock()
+	if len(scripts) == 0 || resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Encoding") != "" ||
+		!strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	body = injectScripts(body, scripts)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return nil
+}
+
+// proxyError answers when the framework dev server cannot be reached
+func (p *DevProxy) proxyError(w http.ResponseWriter, r *http.Request, err error) {
+	if p.Log != nil {
+		fmt.Fprintf(p.Log, "%s %s: %v\n", r.Method, r.URL.RequestURI(), err)
+	}
+	http.Error(w, fmt.Sprintf("The framework dev server at %s is not reachable: %v", p.Target, err), http.StatusBadGateway)
+}
+
+// injectScripts inserts script elements at the start of head, or of the
+// document when it has none, so they run before the page's own scripts
+func injectScripts(html []byte, scripts []string) []byte {
+	var tags bytes.Buffer
+	for _, js := range scripts {
+		tags.WriteString("<script>")
+		// A script cannot contain its own end tag
+		tags.WriteString(strings.ReplaceAll(js, "</script", `<\/script`))
+		tags.WriteString("</script>")
+	}
+
+	at := 0
+	lower := bytes.ToLower(html)
+	for i := 0; ; {
+		j := bytes.Index(lower[i:], []byte("<head"))
+		if j < 0 {
+			break
+		}
+		i += j + len("<head")
+		// Skip <header>
+		if i < len(html) && (html[i] == '>' || html[i] == ' ' || html[i] == '\t' || html[i] == '\n' || html[i] == '\r') {
+			if end := bytes.IndexByte(html[i:], '>'); end >= 0 {
+				at = i + end + 1
+			}
+			break
+		}
+	}
+	out := make([]byte, 0, len(html)+tags.Len())
+	out = append(out, html[:at]...)
+	out = append(out, tags.Bytes()...)
+	return append(out, html[at:]...)
+}
+
+// statusRecorder records the status of a response. Unwrap lets
+// http.ResponseController reach the Flusher of the underlying writer for
+// streamed responses, Hijack records the upgrade of WebSockets.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Hijack takes over the connection of a WebSocket upgrade, the proxy writes
+// the 101 response itself
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.status = http.StatusSwitchingProtocols
+	return http.NewResponseController(r.ResponseWriter).Hijack()
+}
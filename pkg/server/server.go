@@ -1,17 +1,38 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 )
 
+// liveReloadScript is injected into the HTML pages of the preview server. It
+// reloads the page when the server sends a reload event after a rebuild.
+const liveReloadScript = `(function () {
+  var events = new EventSource("` + EndpointPrefix + `events");
+  events.addEventListener("reload", function () { location.reload(); });
+})();`
+
 // PreviewServer represents a local HTTP server for previewing assets
 type PreviewServer struct {
 	RootDir   string
 	AssetsDir string
 	Port      string
+	// TLSConfig serves HTTPS from Listen when set
+	TLSConfig *tls.Config
+
+	mu      sync.Mutex
+	clients map[chan struct{}]bool
+	server  *http.Server
 }
 
 // NewPreviewServer creates a new preview server
@@ -20,18 +41,25 @@ func NewPreviewServer(rootDir, port string) *PreviewServer {
 		RootDir:   rootDir,
 		AssetsDir: filepath.Join(rootDir, "mobile-shell", "assets"),
 		Port:      port,
+		clients:   map[chan struct{}]bool{},
 	}
 }
 
+// Handler serves the assets, injecting the live reload client into HTML
+// pages, and the reload events as Server-Sent Events
+func (s *PreviewServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(EndpointPrefix+"events", s.serveEvents)
+	mux.HandleFunc("/", s.serveAsset)
+	return mux
+}
+
 // Start starts the preview server
 func (s *PreviewServer) Start() error {
-	fs := http.FileServer(http.Dir(s.AssetsDir))
-	http.Handle("/", fs)
-
 	addr := fmt.Sprintf(":%s", s.Port)
 	fmt.Printf("Preview server running at http://localhost:%s\n", s.Port)
 
-	return http.ListenAndServe(addr, nil)
+	return http.ListenAndServe(addr, s.Handler())
 }
 
 // StartBackground starts the preview server in a background goroutine
@@ -42,3 +70,110 @@ func (s *PreviewServer) StartBackground() {
 		}
 	}()
 }
+
+// Listen binds the port and serves in the background. Unlike StartBackground
+// it fails right away when the port is taken.
+func (s *PreviewServer) Listen() error {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%s", s.Port))
+	if err != nil {
+		return fmt.Errorf("preview server cannot listen on port %s: %w", s.Port, err)
+	}
+	scheme := "http"
+	if s.TLSConfig != nil {
+		ln = tls.NewListener(ln, s.TLSConfig)
+		scheme = "https"
+	}
+	s.server = &http.Server{Handler: s.Handler()}
+	go s.server.Serve(ln)
+	fmt.Printf("Preview server running at %s://localhost:%s\n", scheme, s.Port)
+	return nil
+}
+
+// Shutdown stops a server started with Listen
+func (s *PreviewServer) Shutdown() error {
+	if s.server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	err := s.server.Shutdown(ctx)
+	// Event streams never end on their own
+	s.server.Close()
+	return err
+}
+
+// Reload makes every connected page reload
+func (s *PreviewServer) Reload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for client := range s.clients {
+		select {
+		case client <- struct{}{}:
+		default:
+			// A reload is already pending for this page
+		}
+	}
+}
+
+// serveEvents streams the reload events of a page
+func (s *PreviewServer) serveEvents(w http.ResponseWriter, r *http.Request) {
+	client := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.clients[client] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, client)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Pages loaded from file:// have a null origin
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	rc := http.NewResponseController(w)
+	fmt.Fprint(w, ": connected\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-client:
+			fmt.Fprint(w, "event: reload\ndata: {}\n\n")
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// serveAsset serves a file of the assets. HTML pages get the live reload
+// client, paths without an extension that match no file get index.html so
+// client side routes of single page apps load.
+func (s *PreviewServer) serveAsset(w http.ResponseWriter, r *http.Request) {
+	name := path.Clean("/" + r.URL.Path)
+	file := filepath.Join(s.AssetsDir, filepath.FromSlash(name))
+	info, err := os.Stat(file)
+	switch {
+	case err == nil && info.IsDir():
+		file = filepath.Join(file, "index.html")
+	case err != nil && path.Ext(name) == "":
+		file = filepath.Join(s.AssetsDir, "index.html")
+	}
+
+	if !strings.HasSuffix(file, ".html") {
+		http.ServeFile(w, r, file)
+		return
+	}
+	html, err := os.ReadFile(file)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(injectScripts(html, []string{liveReloadScript}))
+}
@@ -0,0 +1,288 @@
+package supervisor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/velogo-dev/velo/pkg/utils"
+)
+
+// proc is the state of a supervised process
+type proc struct {
+	Process
+	s      *Supervisor
+	prefix prefix
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	done    chan struct{} // closed when the current run exited
+	exitErr error         // result of the last run
+	started time.Time
+
+	ready     chan struct{} // closed once the health check passed
+	readyOnce sync.Once
+	dead      chan struct{} // closed when the process is not restarted again
+	deathErr  error
+	stop      chan struct{} // closed by shutdown
+	stopOnce  sync.Once
+	// startMu keeps shutdown from missing a run started by a restart
+	startMu sync.Mutex
+}
+
+// start runs the process once, with a health check for the run
+func (p *proc) start() error {
+	output := &lineWriter{s: p.s, prefix: p.prefix, raw: p.Output}
+	cmd, err := utils.StartCmd(p.Dir, p.Env, output, p.Command, p.Args...)
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	p.mu.Lock()
+	p.cmd = cmd
+	p.done = done
+	p.started = time.Now()
+	p.mu.Unlock()
+
+	go func() {
+		err := cmd.Wait()
+		output.flush()
+		p.mu.Lock()
+		p.exitErr = err
+		p.mu.Unlock()
+		close(done)
+	}()
+
+	if p.Health == nil {
+		p.markReady()
+	} else {
+		go p.checkHealth(cmd, done)
+	}
+	return nil
+}
+
+// supervise waits for the runs of the process to exit and restarts it with
+// backoff until it is stopped or gives up
+func (p *proc) supervise() {
+	restarts := 0
+	for {
+		p.mu.Lock()
+		done, started := p.done, p.started
+		p.mu.Unlock()
+
+		select {
+		case <-done:
+		case <-p.stop:
+			return
+		}
+		if p.s.isStopping() {
+			return
+		}
+
+		p.mu.Lock()
+		err := p.exitErr
+		p.mu.Unlock()
+		p.s.logf(p, "%v", exitError(err))
+
+		if !p.Restart {
+			p.die(exitError(err))
+			return
+		}
+		if !p.isReady() {
+			p.die(errNotReady)
+			return
+		}
+		if time.Since(started) > stableAfter {
+			restarts = 0
+		}
+		if restarts >= p.MaxRestarts {
+			p.die(fmt.Errorf("gave up after %d restarts, %w", restarts, exitError(err)))
+			return
+		}
+
+		delay := backoff(restarts)
+		restarts++
+		p.s.logf(p, "restarting in %s (%d/%d)", delay, restarts, p.MaxRestarts)
+		select {
+		case <-time.After(delay):
+		case <-p.stop:
+			return
+		}
+		p.startMu.Lock()
+		select {
+		case <-p.stop:
+			p.startMu.Unlock()
+			return
+		default:
+		}
+		err = p.start()
+		p.startMu.Unlock()
+		if err != nil {
+			p.die(fmt.Errorf("restart failed: %w", err))
+			return
+		}
+	}
+}
+
+// checkHealth polls the health check of a run, quickly until it passed and
+// every HealthInterval after. A run failing HealthFailures checks in a row is
+// stopped, supervise decides whether it restarts.
+func (p *proc) checkHealth(cmd *exec.Cmd, done chan struct{}) {
+	failures := 0
+	for {
+		interval := p.HealthInterval
+		if !p.isReady() {
+			interval = readyInterval
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), interval+time.Second)
+		err := p.Health(ctx)
+		cancel()
+
+		if err == nil {
+			failures = 0
+			p.markReady()
+		} else if p.isReady() {
+			failures++
+			if failures >= p.HealthFailures {
+				p.s.logf(p, "health check failed %d times: %v, stopping", failures, err)
+				utils.StopCmd(cmd, done, p.s.GracePeriod)
+				return
+			}
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-done:
+			return
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// shutdown stops the process and waits for it to exit
+func (p *proc) shutdown() {
+	p.startMu.Lock()
+	p.stopOnce.Do(func() { close(p.stop) })
+	p.startMu.Unlock()
+	p.mu.Lock()
+	cmd, done := p.cmd, p.done
+	p.mu.Unlock()
+	utils.StopCmd(cmd, done, p.s.GracePeriod)
+}
+
+func (p *proc) markReady() {
+	p.readyOnce.Do(func() { close(p.ready) })
+}
+
+func (p *proc) isReady() bool {
+	select {
+	case <-p.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// die marks the process as given up and fails the supervisor when it is
+// critical
+func (p *proc) die(err error) {
+	p.mu.Lock()
+	p.deathErr = err
+	p.mu.Unlock()
+	close(p.dead)
+	if p.Critical {
+		p.s.fail(fmt.Errorf("%s %w", p.Name, err))
+	}
+}
+
+func (p *proc) deathError() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return fmt.Errorf("%s %w", p.Name, p.deathErr)
+}
+
+// backoff returns the delay before the restart following n restarts in a row
+func backoff(n int) time.Duration {
+	delay := minBackoff
+	for i := 0; i < n && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
+
+// exitError describes how a run ended
+func exitError(err error) error {
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return fmt.Errorf("exited with code %d", exitErr.ExitCode())
+	}
+	if err != nil {
+		return fmt.Errorf("failed: %w", err)
+	}
+	return fmt.Errorf("exited")
+}
+
+// HTTPCheck returns a health check passing when url answers with any HTTP
+// response, dev servers often answer 404 on / until a page is requested
+func HTTPCheck(url string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+}
+
+// lineWriter splits output into prefixed lines
+type lineWriter struct {
+	s      *Supervisor
+	prefix prefix
+	// raw receives the output unchanged when set
+	raw io.Writer
+
+	mu      sync.Mutex
+	partial []byte
+}
+
+// Write implements io.Writer
+func (w *lineWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.raw != nil {
+		w.raw.Write(b)
+	}
+	data := append(w.partial, b...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		w.s.writeLine(w.prefix, string(bytes.TrimRight(data[:i], "\r")))
+		data = data[i+1:]
+	}
+	w.partial = append([]byte{}, data...)
+	return len(b), nil
+}
+
+// flush writes an unterminated last line
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.partial) > 0 {
+		w.s.writeLine(w.prefix, string(w.partial))
+		w.partial = nil
+	}
+}
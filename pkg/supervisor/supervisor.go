@@ -0,0 +1,258 @@
+// Package supervisor runs the child processes of velo dev, such as the
+// framework dev server. Every process gets a colored name prefix on its output
+// lines, an optional health check and restarts with backoff when it crashes.
+// The supervisor stops them in reverse start order and reports when a
+// critical process dies.
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Process describes a supervised child process
+type Process struct {
+	// Name prefixes the output lines of the process
+	Name    string
+	Dir     string
+	Command string
+	Args    []string
+	// Env holds extra KEY=value environment variables
+	Env []string
+	// Output receives the raw output of the process in addition to the
+	// supervisor's output, e.g. a utils.TailBuffer
+	Output io.Writer
+	// Critical processes fail the supervisor when they die for good, because
+	// they are not restarted or ran out of restarts
+	Critical bool
+	// Restart restarts the process when it exits, up to MaxRestarts times in a
+	// row. A process with a health check that never became healthy is not
+	// restarted, a crash on startup would only repeat.
+	Restart     bool
+	MaxRestarts int
+	// Health reports whether the process serves, see HTTPCheck. It is polled
+	// until it passes once, then every HealthInterval. After HealthFailures
+	// failures in a row the process is stopped and restarted.
+	Health         func(ctx context.Context) error
+	HealthInterval time.Duration
+	HealthFailures int
+}
+
+// Defaults of the Process and Supervisor settings
+const (
+	DefaultMaxRestarts    = 5
+	DefaultHealthInterval = 2 * time.Second
+	DefaultHealthFailures = 3
+	DefaultGracePeriod    = 5 * time.Second
+	// readyInterval is how often the health check is polled before it passed
+	readyInterval = 250 * time.Millisecond
+	// minBackoff and maxBackoff bound the delay before a restart, doubled for
+	// every restart in a row
+	minBackoff = 500 * time.Millisecond
+	maxBackoff = 30 * time.Second
+	// stableAfter is how long a process must run before its restarts in a row
+	// are reset
+	stableAfter = time.Minute
+)
+
+// colors are assigned to the processes in start order
+var colors = []lipgloss.Color{"6", "5", "3", "2", "4", "1"}
+
+// Supervisor runs and watches a set of processes
+type Supervisor struct {
+	// Output receives the prefixed output of every process, os.Stdout when
+	// nil
+	Output io.Writer
+	// GracePeriod is how long a process gets to exit on shutdown before it is
+	// killed
+	GracePeriod time.Duration
+
+	mu       sync.Mutex
+	outMu    sync.Mutex
+	procs    []*proc
+	prefixes int
+	width    int
+	failed   chan error
+	stopping bool
+}
+
+// prefix is the colored name starting the output lines of a process or
+// writer
+type prefix struct {
+	name  string
+	style lipgloss.Style
+}
+
+// New returns a Supervisor writing to os.Stdout
+func New() *Supervisor {
+	return &Supervisor{
+		Output:      os.Stdout,
+		GracePeriod: DefaultGracePeriod,
+		failed:      make(chan error, 1),
+	}
+}
+
+// Start starts p and supervises it until Stop. It fails when the process
+// cannot be started at all.
+func (s *Supervisor) Start(p Process) error {
+	if p.MaxRestarts == 0 {
+		p.MaxRestarts = DefaultMaxRestarts
+	}
+	if p.HealthInterval == 0 {
+		p.HealthInterval = DefaultHealthInterval
+	}
+	if p.HealthFailures == 0 {
+		p.HealthFailures = DefaultHealthFailures
+	}
+
+	s.mu.Lock()
+	if s.stopping {
+		s.mu.Unlock()
+		return fmt.Errorf("supervisor is stopping")
+	}
+	if s.process(p.Name) != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("process %s is already supervised", p.Name)
+	}
+	pr := &proc{
+		Process: p,
+		s:       s,
+		prefix:  s.newPrefix(p.Name),
+		ready:   make(chan struct{}),
+		dead:    make(chan struct{}),
+		stop:    make(chan struct{}),
+	}
+	s.procs = append(s.procs, pr)
+	s.mu.Unlock()
+
+	if err := pr.start(); err != nil {
+		s.mu.Lock()
+		s.procs = s.procs[:len(s.procs)-1]
+		s.mu.Unlock()
+		return fmt.Errorf("failed to start %s: %w", p.Name, err)
+	}
+	go pr.supervise()
+	return nil
+}
+
+// WaitReady waits until the health check of the named process passes. It
+// fails when the process dies, the timeout passes or ctx is cancelled.
+// Processes without a health check are ready once started.
+func (s *Supervisor) WaitReady(ctx context.Context, name string, timeout time.Duration) error {
+	s.mu.Lock()
+	p := s.process(name)
+	s.mu.Unlock()
+	if p == nil {
+		return fmt.Errorf("process %s is not supervised", name)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-p.ready:
+		return nil
+	case <-p.dead:
+		return p.deathError()
+	case <-timer.C:
+		return fmt.Errorf("%s did not become ready within %s", name, timeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wait blocks until ctx is cancelled, returning nil, or until a critical
+// process dies, returning why
+func (s *Supervisor) Wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-s.failed:
+		return err
+	}
+}
+
+// Stop stops every process, in reverse start order, and waits for them to
+// exit
+func (s *Supervisor) Stop() {
+	s.mu.Lock()
+	s.stopping = true
+	procs := append([]*proc{}, s.procs...)
+	s.mu.Unlock()
+
+	for i := len(procs) - 1; i >= 0; i-- {
+		procs[i].shutdown()
+	}
+}
+
+// Writer returns a writer prefixing its lines with name like the output of
+// the processes, for components such as a proxy that log next to them
+func (s *Supervisor) Writer(name string) io.Writer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &lineWriter{s: s, prefix: s.newPrefix(name)}
+}
+
+// newPrefix assigns the next color to name, s.mu must be held
+func (s *Supervisor) newPrefix(name string) prefix {
+	if len(name) > s.width {
+		s.width = len(name)
+	}
+	style := lipgloss.NewStyle().Foreground(colors[s.prefixes%len(colors)])
+	s.prefixes++
+	return prefix{name: name, style: style}
+}
+
+func (s *Supervisor) process(name string) *proc {
+	for _, p := range s.procs {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// fail reports the death of a critical process to Wait, only the first one
+// is kept
+func (s *Supervisor) fail(err error) {
+	select {
+	case s.failed <- err:
+	default:
+	}
+}
+
+func (s *Supervisor) isStopping() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopping
+}
+
+// writeLine writes an output line with its prefix
+func (s *Supervisor) writeLine(p prefix, line string) {
+	s.mu.Lock()
+	width := s.width
+	s.mu.Unlock()
+
+	prefix := p.style.Render(fmt.Sprintf("%-*s |", width, p.name))
+	out := s.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	s.outMu.Lock()
+	fmt.Fprintf(out, "%s %s\n", prefix, line)
+	s.outMu.Unlock()
+}
+
+// logf writes a message of the supervisor about the process
+func (s *Supervisor) logf(p *proc, format string, args ...any) {
+	s.writeLine(p.prefix, fmt.Sprintf(format, args...))
+}
+
+// errNotReady is the death of a process whose health check never passed
+var errNotReady = errors.New("exited before it became ready")
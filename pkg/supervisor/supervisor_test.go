@@ -0,0 +1,305 @@
+package supervisor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// The test binary doubles as the supervised process, run with
+// VELO_TEST_PROCESS set to what it should do
+func TestMain(m *testing.M) {
+	switch os.Getenv("VELO_TEST_PROCESS") {
+	case "":
+		os.Exit(m.Run())
+	case "print":
+		fmt.Print("hello\r\nworld\npartial")
+		os.Exit(0)
+	case "fail":
+		countRun()
+		os.Exit(3)
+	case "serve":
+		countRun()
+		time.Sleep(time.Minute)
+		os.Exit(0)
+	}
+	os.Exit(2)
+}
+
+// countRun appends a line to the file in VELO_TEST_RUNS
+func countRun() {
+	f, err := os.OpenFile(os.Getenv("VELO_TEST_RUNS"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		os.Exit(1)
+	}
+	f.WriteString("run\n")
+	f.Close()
+}
+
+// testProcess returns a process running the test binary in mode, and the file
+// counting its runs
+func testProcess(t *testing.T, name, mode string) (Process, string) {
+	t.Helper()
+	runs := filepath.Join(t.TempDir(), "runs")
+	return Process{
+		Name:    name,
+		Command: os.Args[0],
+		Env:     []string{"VELO_TEST_PROCESS=" + mode, "VELO_TEST_RUNS=" + runs},
+	}, runs
+}
+
+func runCount(t *testing.T, runs string) int {
+	t.Helper()
+	data, err := os.ReadFile(runs)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "run\n")
+}
+
+// syncBuffer is a bytes.Buffer safe to read while the supervisor writes
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func newTestSupervisor(t *testing.T) (*Supervisor, *syncBuffer) {
+	t.Helper()
+	out := &syncBuffer{}
+	s := New()
+	s.Output = out
+	s.GracePeriod = time.Second
+	t.Cleanup(s.Stop)
+	return s, out
+}
+
+// wait waits for the supervisor to fail
+func wait(t *testing.T, s *Supervisor, timeout time.Duration) error {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := s.Wait(ctx)
+	if err == nil {
+		t.Fatal("no critical process died")
+	}
+	return err
+}
+
+func TestOutputPrefixes(t *testing.T) {
+	s, out := newTestSupervisor(t)
+	p, _ := testProcess(t, "web", "print")
+	p.Critical = true
+	var raw bytes.Buffer
+	p.Output = &raw
+	if err := s.Start(p); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintln(s.Writer("proxy"), "listening")
+
+	err := wait(t, s, 10*time.Second)
+	if err.Error() != "web exited" {
+		t.Errorf("Wait = %v", err)
+	}
+	// Prefixes are padded to the longest name
+	for _, line := range []string{"web   | hello\n", "web   | world\n", "web   | partial\n", "proxy | listening\n"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("output does not contain %q:\n%s", line, out)
+		}
+	}
+	if raw.String() != "hello\r\nworld\npartial" {
+		t.Errorf("raw output %q", raw.String())
+	}
+}
+
+func TestRestartGivesUp(t *testing.T) {
+	s, out := newTestSupervisor(t)
+	p, runs := testProcess(t, "api", "fail")
+	p.Critical = true
+	p.Restart = true
+	p.MaxRestarts = 2
+	if err := s.Start(p); err != nil {
+		t.Fatal(err)
+	}
+
+	err := wait(t, s, 20*time.Second)
+	if err.Error() != "api gave up after 2 restarts, exited with code 3" {
+		t.Errorf("Wait = %v", err)
+	}
+	if n := runCount(t, runs); n != 3 {
+		t.Errorf("ran %d times, want 3", n)
+	}
+	for _, line := range []string{"restarting in 500ms (1/2)", "restarting in 1s (2/2)"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("output does not contain %q:\n%s", line, out)
+		}
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	s, out := newTestSupervisor(t)
+	p, runs := testProcess(t, "server", "serve")
+	p.Critical = true
+	p.Restart = true
+	p.MaxRestarts = 1
+	p.HealthInterval = 20 * time.Millisecond
+	p.HealthFailures = 2
+	// Passes on the third check, then fails for good
+	var checks atomic.Int32
+	p.Health = func(ctx context.Context) error {
+		if checks.Add(1) == 3 {
+			return nil
+		}
+		return errors.New("connection refused")
+	}
+	if err := s.Start(p); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WaitReady(context.Background(), "server", 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if n := checks.Load(); n < 3 {
+		t.Errorf("ready after %d checks", n)
+	}
+
+	// An unhealthy process is stopped and restarted, and given up on when
+	// it stays unhealthy
+	err := wait(t, s, 20*time.Second)
+	if !strings.HasPrefix(err.Error(), "server gave up after 1 restarts") {
+		t.Errorf("Wait = %v", err)
+	}
+	if n := runCount(t, runs); n != 2 {
+		t.Errorf("ran %d times, want 2", n)
+	}
+	if !strings.Contains(out.String(), "health check failed 2 times: connection refused, stopping") {
+		t.Errorf("output:\n%s", out)
+	}
+}
+
+func TestNotReady(t *testing.T) {
+	s, _ := newTestSupervisor(t)
+	p, runs := testProcess(t, "server", "fail")
+	p.Restart = true
+	p.Health = func(ctx context.Context) error { return errors.New("connection refused") }
+	if err := s.Start(p); err != nil {
+		t.Fatal(err)
+	}
+
+	// A process dying before it was healthy is not restarted
+	err := s.WaitReady(context.Background(), "server", 10*time.Second)
+	if err == nil || err.Error() != "server exited before it became ready" {
+		t.Errorf("WaitReady = %v", err)
+	}
+	if n := runCount(t, runs); n != 1 {
+		t.Errorf("ran %d times, want 1", n)
+	}
+	if err := s.WaitReady(context.Background(), "missing", time.Second); err == nil {
+		t.Error("WaitReady of an unknown process succeeded")
+	}
+}
+
+func TestStop(t *testing.T) {
+	s, _ := newTestSupervisor(t)
+	first, firstRuns := testProcess(t, "first", "serve")
+	second, secondRuns := testProcess(t, "second", "serve")
+	for _, p := range []Process{first, second} {
+		p.Restart = true
+		if err := s.Start(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Start(first); err == nil {
+		t.Error("a second process named first started")
+	}
+	// Both are running once they counted their run
+	deadline := time.Now().Add(10 * time.Second)
+	for runCount(t, firstRuns) == 0 || runCount(t, secondRuns) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("processes did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	start := time.Now()
+	s.Stop()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Stop took %s", elapsed)
+	}
+	for _, p := range s.procs {
+		select {
+		case <-p.done:
+		default:
+			t.Errorf("%s still runs", p.Name)
+		}
+	}
+	// Stopped processes are not restarted
+	time.Sleep(700 * time.Millisecond)
+	if runCount(t, firstRuns) != 1 || runCount(t, secondRuns) != 1 {
+		t.Error("a process restarted after Stop")
+	}
+	if err := s.Start(first); err == nil {
+		t.Error("Start after Stop succeeded")
+	}
+}
+
+func TestStartFails(t *testing.T) {
+	s, _ := newTestSupervisor(t)
+	err := s.Start(Process{Name: "missing", Command: filepath.Join(t.TempDir(), "missing")})
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to start missing: ") {
+		t.Errorf("Start = %v", err)
+	}
+	// The name is free again
+	p, _ := testProcess(t, "missing", "serve")
+	if err := s.Start(p); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for n, want := range map[int]time.Duration{
+		0:  500 * time.Millisecond,
+		1:  time.Second,
+		3:  4 * time.Second,
+		5:  16 * time.Second,
+		6:  maxBackoff,
+		50: maxBackoff,
+	} {
+		if got := backoff(n); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestHTTPCheck(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	check := HTTPCheck(server.URL)
+	// Any response passes, dev servers answer 404 until a page exists
+	if err := check(context.Background()); err != nil {
+		t.Errorf("check = %v", err)
+	}
+	server.Close()
+	if err := check(context.Background()); err == nil {
+		t.Error("check of a closed server passed")
+	}
+}
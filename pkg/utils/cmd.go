@@ -1,10 +1,28 @@
 package utils
 
 import (
+	"io"
 	"os"
 	"os/exec"
+	"slices"
+	"strings"
+	"time"
 )
 
+// Runner runs external commands. Builders take a Runner so the commands they
+// construct can be checked with a fake on machines without the tools.
+type Runner interface {
+	Run(dir, name string, args ...string) error
+}
+
+// CmdRunner is the Runner executing commands with RunCmdWithDir
+type CmdRunner struct{}
+
+// Run executes the command in dir, connected to stdout/stderr
+func (CmdRunner) Run(dir, name string, args ...string) error {
+	return RunCmdWithDir(dir, name, args...)
+}
+
 // RunCmd executes a shell command and connects it to stdout/stderr
 func RunCmd(name string, args ...string) error {
 	cmd := exec.Command(name, args...)
@@ -41,3 +59,65 @@ func RunCmdWait(dir, name string, args ...string) error {
 	cmd.Stdin = os.Stdin // Add stdin for interactive prompts
 	return cmd.Run()
 }
+
+// RunCmdWithEnv executes a shell command in the specified directory with
+// additional environment variables
+func RunCmdWithEnv(dir string, env []string, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// RunCmdWithoutEnv executes a shell command in the specified directory with
+// the environment variables named in unset removed from its environment
+func RunCmdWithoutEnv(dir string, unset []string, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Env = []string{}
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if !slices.Contains(unset, key) {
+			cmd.Env = append(cmd.Env, kv)
+		}
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// StartCmd starts a long running command in dir with additional environment
+// variables, writing its output to output. The command runs in a process group
+// of its own and is stopped with StopCmd.
+func StartCmd(dir string, env []string, output io.Writer, name string, args ...string) (*exec.Cmd, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// StopCmd stops a command started with StartCmd and the processes it
+// spawned. They get the grace period to exit before they are killed. done
+// must be closed once cmd.Wait has returned.
+func StopCmd(cmd *exec.Cmd, done <-chan struct{}, grace time.Duration) {
+	select {
+	case <-done:
+		return
+	default:
+	}
+	terminate(cmd)
+	select {
+	case <-done:
+	case <-time.After(grace):
+		kill(cmd)
+		<-done
+	}
+}
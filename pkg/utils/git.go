@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"io"
 	"os/exec"
 	"strings"
 )
@@ -70,3 +71,35 @@ func GitPull() error {
 	cmd := exec.Command("git", "pull")
 	return cmd.Run()
 }
+
+// GitIsIgnored reports whether path is ignored by the git repository in dir
+func GitIsIgnored(dir, path string) bool {
+	cmd := exec.Command("git", "check-ignore", "-q", path)
+	cmd.Dir = dir
+	return cmd.Run() == nil
+}
+
+// GitIsTracked reports whether path is tracked by the git repository of dir
+func GitIsTracked(dir, path string) bool {
+	cmd := exec.Command("git", "ls-files", "--error-unmatch", "--", path)
+	cmd.Dir = dir
+	cmd.Stdout = io.Discard
+	cmd.Stderr = io.Discard
+	return cmd.Run() == nil
+}
+
+// GitAddFiles stages the given paths, removed files included
+func GitAddFiles(paths ...string) error {
+	// Without paths git add -A would stage the whole tree
+	if len(paths) == 0 {
+		return nil
+	}
+	cmd := exec.Command("git", append([]string{"add", "-A", "--"}, paths...)...)
+	return cmd.Run()
+}
+
+// GitTag creates an annotated tag on the current commit
+func GitTag(name, message string) error {
+	cmd := exec.Command("git", "tag", "-a", name, "-m", message)
+	return cmd.Run()
+}
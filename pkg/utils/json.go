@@ -0,0 +1,210 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SetJSONValue returns the JSON document data with value set at the given
+// object path, e.g. SetJSONValue(data, "1.2.0", "expo", "version"). Only the
+// bytes of the value are replaced, so the formatting, key order and
+// whitespace of the rest of the document are kept. Missing objects are
+// created, following the indentation of the document.
+func SetJSONValue(data []byte, value any, path ...string) ([]byte, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		data = []byte("{}\n")
+	}
+	unit := indentUnit(data)
+
+	// The span of the value the path leads to so far
+	start := len(data) - len(bytes.TrimLeft(data, " \t\r\n"))
+	end := len(bytes.TrimRight(data, " \t\r\n"))
+	for i, key := range path {
+		object, err := parseObject(data[start:end], start)
+		if err != nil {
+			if i > 0 {
+				return nil, fmt.Errorf("%s: %w", strings.Join(path[:i], "."), err)
+			}
+			return nil, err
+		}
+		if member, ok := object.member(key); ok {
+			start, end = member.valueStart, member.valueEnd
+			continue
+		}
+		// The rest of the path is created as nested objects
+		var nested any = value
+		for j := len(path) - 1; j > i; j-- {
+			nested = map[string]any{path[j]: nested}
+		}
+		return object.insert(data, key, nested, unit)
+	}
+
+	encoded, err := marshalIndentJSON(value, lineIndent(data, start), unit)
+	if err != nil {
+		return nil, err
+	}
+	return splice(data, start, end, encoded), nil
+}
+
+// jsonObject is an object of a document, with the offsets of its members
+type jsonObject struct {
+	// start is the offset of the opening brace, end follows the closing one
+	start, end int
+	members    []jsonMember
+}
+
+type jsonMember struct {
+	key                  string
+	keyStart, keyEnd     int
+	valueStart, valueEnd int
+}
+
+// parseObject returns the members of the object in data, which starts at
+// offset of the document
+func parseObject(data []byte, offset int) (*jsonObject, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+
+	object := &jsonObject{start: offset}
+	for decoder.More() {
+		// The key starts after the comma or brace preceding it
+		keyStart := int(decoder.InputOffset())
+		for keyStart < len(data) && strings.IndexByte(" \t\r\n,", data[keyStart]) >= 0 {
+			keyStart++
+		}
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := token.(string)
+		keyEnd := int(decoder.InputOffset())
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		valueEnd := int(decoder.InputOffset())
+		object.members = append(object.members, jsonMember{
+			key:        key,
+			keyStart:   offset + keyStart,
+			keyEnd:     offset + keyEnd,
+			valueStart: offset + valueEnd - len(value),
+			valueEnd:   offset + valueEnd,
+		})
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	object.end = offset + int(decoder.InputOffset())
+	return object, nil
+}
+
+// member returns the member named key, the last one when the key is repeated
+// as that is the value decoders keep
+func (o *jsonObject) member(key string) (jsonMember, bool) {
+	for i := len(o.members) - 1; i >= 0; i-- {
+		if o.members[i].key == key {
+			return o.members[i], true
+		}
+	}
+	return jsonMember{}, false
+}
+
+// insert returns data with the member key added at the end of the object,
+// laid out like the members before it
+func (o *jsonObject) insert(data []byte, key string, value any, unit string) ([]byte, error) {
+	name, err := marshalJSON(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(o.members) == 0 {
+		outer := lineIndent(data, o.start)
+		indent := outer + unit
+		encoded, err := marshalIndentJSON(value, indent, unit)
+		if err != nil {
+			return nil, err
+		}
+		member := "\n" + indent + string(name) + ": " + string(encoded) + "\n" + outer
+		return splice(data, o.start+1, o.end-1, []byte(member)), nil
+	}
+
+	first, last := o.members[0], o.members[len(o.members)-1]
+	separator := ": "
+	if strings.TrimSpace(string(data[first.keyEnd:first.valueStart])) == string(data[first.keyEnd:first.valueStart]) {
+		separator = ":"
+	}
+	// Members on a single line get the new one on the same line
+	if !bytes.ContainsRune(data[o.start:first.keyStart], '\n') {
+		encoded, err := marshalJSON(value)
+		if err != nil {
+			return nil, err
+		}
+		member := ", " + string(name) + separator + string(encoded)
+		return splice(data, last.valueEnd, last.valueEnd, []byte(member)), nil
+	}
+	indent := lineIndent(data, first.keyStart)
+	encoded, err := marshalIndentJSON(value, indent, unit)
+	if err != nil {
+		return nil, err
+	}
+	member := ",\n" + indent + string(name) + separator + string(encoded)
+	return splice(data, last.valueEnd, last.valueEnd, []byte(member)), nil
+}
+
+// indentUnit returns the indentation of the first indented line, two spaces
+// when no line is indented
+func indentUnit(data []byte) string {
+	for _, line := range bytes.Split(data, []byte("\n"))[1:] {
+		trimmed := bytes.TrimLeft(line, " \t")
+		if len(trimmed) > 0 && len(trimmed) < len(line) {
+			return string(line[:len(line)-len(trimmed)])
+		}
+	}
+	return "  "
+}
+
+// lineIndent returns the leading whitespace of the line holding offset
+func lineIndent(data []byte, offset int) string {
+	lineStart := bytes.LastIndexByte(data[:offset], '\n') + 1
+	line := data[lineStart:offset]
+	return string(line[:len(line)-len(bytes.TrimLeft(line, " \t"))])
+}
+
+func splice(data []byte, start, end int, insert []byte) []byte {
+	out := make([]byte, 0, len(data)-(end-start)+len(insert))
+	out = append(out, data[:start]...)
+	out = append(out, insert...)
+	return append(out, data[end:]...)
+}
+
+// marshalIndentJSON encodes v without escaping <, > and &, indenting nested
+// lines by prefix and unit. Scalars are written as they are.
+func marshalIndentJSON(v any, prefix, unit string) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent(prefix, unit)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// marshalJSON encodes v without escaping <, > and &
+func marshalJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return []byte(strings.TrimSuffix(buf.String(), "\n")), nil
+}
@@ -0,0 +1,104 @@
+package utils
+
+import "testing"
+
+func TestSetJSONValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		value any
+		path  []string
+		want  string
+	}{
+		{
+			name:  "replaces only the value",
+			data:  "{\n    \"name\":   \"app\",\n    \"version\": \"1.0.0\",\n    \"scripts\": {\"dev\": \"vite\"}\n}\n",
+			value: "1.1.0",
+			path:  []string{"version"},
+			want:  "{\n    \"name\":   \"app\",\n    \"version\": \"1.1.0\",\n    \"scripts\": {\"dev\": \"vite\"}\n}\n",
+		},
+		{
+			name:  "keeps tabs and nested layout",
+			data:  "{\n\t\"expo\": {\n\t\t\"ios\": { \"buildNumber\": \"4\" },\n\t\t\"version\": \"1.0.0\"\n\t}\n}",
+			value: "5",
+			path:  []string{"expo", "ios", "buildNumber"},
+			want:  "{\n\t\"expo\": {\n\t\t\"ios\": { \"buildNumber\": \"5\" },\n\t\t\"version\": \"1.0.0\"\n\t}\n}",
+		},
+		{
+			name:  "adds a missing key after the last member",
+			data:  "{\n  \"name\": \"app\"\n}\n",
+			value: "1.0.0",
+			path:  []string{"version"},
+			want:  "{\n  \"name\": \"app\",\n  \"version\": \"1.0.0\"\n}\n",
+		},
+		{
+			name:  "creates missing objects with the document indentation",
+			data:  "{\n    \"expo\": {\n        \"name\": \"app\"\n    }\n}\n",
+			value: 7,
+			path:  []string{"expo", "android", "versionCode"},
+			want:  "{\n    \"expo\": {\n        \"name\": \"app\",\n        \"android\": {\n            \"versionCode\": 7\n        }\n    }\n}\n",
+		},
+		{
+			name:  "fills an empty object",
+			data:  "{\n  \"app\": {}\n}\n",
+			value: 3,
+			path:  []string{"app", "build"},
+			want:  "{\n  \"app\": {\n    \"build\": 3\n  }\n}\n",
+		},
+		{
+			name:  "single line objects stay on one line",
+			data:  `{"a":1,"b":2}`,
+			value: true,
+			path:  []string{"c"},
+			want:  `{"a":1,"b":2, "c":true}`,
+		},
+		{
+			name:  "empty document",
+			data:  "",
+			value: "1.0.0",
+			path:  []string{"app", "version"},
+			want:  "{\n  \"app\": {\n    \"version\": \"1.0.0\"\n  }\n}\n",
+		},
+		{
+			name:  "indents a replaced array like its line",
+			data:  "{\n  \"name\": \"app\",\n  \"icons\": []\n}\n",
+			value: []map[string]string{{"src": "a.png"}},
+			path:  []string{"icons"},
+			want:  "{\n  \"name\": \"app\",\n  \"icons\": [\n    {\n      \"src\": \"a.png\"\n    }\n  ]\n}\n",
+		},
+		{
+			name:  "repeated keys update the last one",
+			data:  `{"v": 1, "v": 2}`,
+			value: 3,
+			path:  []string{"v"},
+			want:  `{"v": 1, "v": 3}`,
+		},
+		{
+			name:  "does not escape HTML characters",
+			data:  `{"name": "x"}`,
+			value: "a & <b>",
+			path:  []string{"name"},
+			want:  `{"name": "a & <b>"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SetJSONValue([]byte(tt.data), tt.value, tt.path...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetJSONValueRejectsNonObjects(t *testing.T) {
+	if _, err := SetJSONValue([]byte(`{"expo": "x"}`), 1, "expo", "version"); err == nil {
+		t.Fatal("set a key inside a string")
+	}
+	if _, err := SetJSONValue([]byte(`[1]`), 1, "version"); err == nil {
+		t.Fatal("set a key of an array")
+	}
+}
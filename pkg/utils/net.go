@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// virtualInterfaces are name prefixes of the interfaces of containers, VMs
+// and VPNs, which other devices on the LAN cannot reach
+var virtualInterfaces = []string{
+	"docker", "br-", "veth", "virbr", "vmnet", "vboxnet", "cni", "flannel",
+	"tun", "tap", "utun", "wg", "tailscale", "zt", "awdl", "llw", "bridge",
+}
+
+// LANAddresses returns the private IPv4 addresses of the network interfaces
+// that are up, leaving out loopback and virtual interfaces. Networks using
+// public addresses get their other IPv4 addresses when none is private.
+func LANAddresses() ([]net.IP, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+	var private, public []net.IP
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || isVirtualInterface(iface.Name) {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			ip := ipNet.IP.To4()
+			switch {
+			case ip == nil || !ip.IsGlobalUnicast():
+			case ip.IsPrivate():
+				private = append(private, ip)
+			default:
+				public = append(public, ip)
+			}
+		}
+	}
+	if len(private) == 0 {
+		return public, nil
+	}
+	return private, nil
+}
+
+func isVirtualInterface(name string) bool {
+	name = strings.ToLower(name)
+	for _, prefix := range virtualInterfaces {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
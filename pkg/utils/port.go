@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// PortFree reports whether no server uses the TCP port on any address. A
+// listener on every interface conflicts with the servers of other addresses
+// on Linux, but not with those bound to loopback on macOS and Windows, so
+// the loopback addresses are dialed too.
+func PortFree(port int) bool {
+	ln, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return false
+	}
+	ln.Close()
+	for _, host := range []string{"127.0.0.1", "::1"} {
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), 200*time.Millisecond)
+		if err == nil {
+			conn.Close()
+			return false
+		}
+	}
+	return true
+}
+
+// FreePort returns the first free port of the count ports from start,
+// leaving out the excluded ones
+func FreePort(start, count int, exclude ...int) (int, error) {
+	for port := start; port < start+count && port <= 65535; port++ {
+		if !slices.Contains(exclude, port) && PortFree(port) {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("ports %d to %d are in use", start, min(start+count-1, 65535))
+}
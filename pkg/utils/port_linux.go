@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tcpListen is the state of listening sockets in /proc/net/tcp
+const tcpListen = "0A"
+
+// PortOwner returns the PID and command line of the process listening on
+// the TCP port. The sockets are looked up in /proc/net/tcp and tcp6, and
+// their owner among the file descriptors of the processes, so processes of
+// other users are only found when running as root.
+func PortOwner(port int) (int, string, error) {
+	inodes := map[string]bool{}
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		if err := listeningInodes(table, port, inodes); err != nil && !os.IsNotExist(err) {
+			return 0, "", err
+		}
+	}
+	if len(inodes) == 0 {
+		return 0, "", fmt.Errorf("no socket listens on port %d", port)
+	}
+
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, "", err
+	}
+	for _, proc := range procs {
+		pid, err := strconv.Atoi(proc.Name())
+		if err != nil {
+			continue
+		}
+		dir := filepath.Join("/proc", proc.Name(), "fd")
+		fds, err := os.ReadDir(dir)
+		if err != nil {
+			// Processes of other users
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(dir, fd.Name()))
+			if err != nil {
+				continue
+			}
+			if inode, ok := strings.CutPrefix(link, "socket:["); ok && inodes[strings.TrimSuffix(inode, "]")] {
+				return pid, processCommand(pid), nil
+			}
+		}
+	}
+	return 0, "", fmt.Errorf("the process listening on port %d is not visible to this user", port)
+}
+
+// listeningInodes adds the inodes of the sockets of a /proc/net/tcp table
+// listening on port. The lines hold the slot, local and remote address as
+// hex ip:port, the state and, in the tenth field, the inode.
+func listeningInodes(table string, port int, inodes map[string]bool) error {
+	f, err := os.Open(table)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	// The header
+	scanner.Scan()
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpListen {
+			continue
+		}
+		i := strings.LastIndexByte(fields[1], ':')
+		local, err := strconv.ParseInt(fields[1][i+1:], 16, 32)
+		if err == nil && int(local) == port {
+			inodes[fields[9]] = true
+		}
+	}
+	return scanner.Err()
+}
+
+// processCommand returns the command line of a process, its name when the
+// command line is empty
+func processCommand(pid int) string {
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+	if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil && len(cmdline) > 0 {
+		args := strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00")
+		args[0] = filepath.Base(args[0])
+		return strings.Join(args, " ")
+	}
+	comm, _ := os.ReadFile(filepath.Join(dir, "comm"))
+	return strings.TrimSpace(string(comm))
+}
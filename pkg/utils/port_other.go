@@ -0,0 +1,10 @@
+//go:build !linux
+
+package utils
+
+import "errors"
+
+// PortOwner is only implemented on Linux, through /proc
+func PortOwner(port int) (int, string, error) {
+	return 0, "", errors.New("finding the process of a port is only supported on Linux")
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package utils
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminate stops cmd and the processes it spawned. Windows has no SIGTERM,
+// taskkill /T walks the process tree instead.
+func terminate(cmd *exec.Cmd) error {
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+}
+
+func kill(cmd *exec.Cmd) error {
+	return terminate(cmd)
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"bytes"
+	"sync"
+)
+
+// TailBuffer is an io.Writer keeping the last lines written to it, e.g. to
+// show the end of a command's output when it fails
+type TailBuffer struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial []byte
+}
+
+// NewTailBuffer returns a TailBuffer keeping up to max lines
+func NewTailBuffer(max int) *TailBuffer {
+	return &TailBuffer{max: max}
+}
+
+// Write implements io.Writer
+func (t *TailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data := append(t.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		t.lines = append(t.lines, string(bytes.TrimRight(data[:i], "\r")))
+		data = data[i+1:]
+	}
+	t.partial = append([]byte{}, data...)
+	if len(t.lines) > t.max {
+		t.lines = append([]string{}, t.lines[len(t.lines)-t.max:]...)
+	}
+	return len(p), nil
+}
+
+// Lines returns the kept lines, including an unterminated last line
+func (t *TailBuffer) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := append([]string{}, t.lines...)
+	if len(t.partial) > 0 {
+		lines = append(lines, string(t.partial))
+	}
+	if len(lines) > t.max {
+		lines = lines[len(lines)-t.max:]
+	}
+	return lines
+}
@@ -0,0 +1,195 @@
+package watcher
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// inotifyMask selects the changes watched on every directory
+const inotifyMask = syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_ATTRIB |
+	syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO |
+	syscall.IN_DELETE_SELF | syscall.IN_ONLYDIR
+
+// inotify watches every directory below the roots with an inotify watch, and
+// the directories of watched files
+type inotify struct {
+	w *Watcher
+	// fd is used for the watches, calling file.Fd would make reads blocking
+	fd   int
+	file *os.File
+
+	mu   sync.Mutex
+	dirs map[int32]string
+}
+
+func newInotify(w *Watcher) (*inotify, error) {
+	// A non-blocking descriptor lets os.File use the runtime poller, so Close
+	// interrupts a pending read
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	in := &inotify{
+		w:    w,
+		fd:   fd,
+		file: os.NewFile(uintptr(fd), "inotify"),
+		dirs: map[int32]string{},
+	}
+	for _, r := range w.roots {
+		dir := r.path
+		if r.file {
+			dir = filepath.Dir(r.path)
+		}
+		if err := in.addTree(dir, r.file, false); err != nil {
+			in.file.Close()
+			return nil, err
+		}
+	}
+	go in.run()
+	return in, nil
+}
+
+func (in *inotify) close() {
+	in.file.Close()
+}
+
+// addTree watches dir and, unless only is set, the directories below it.
+// With report set the files found are reported as created, they may have been
+// created before the watch was added.
+func (in *inotify) addTree(dir string, only, report bool) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Removed while walking
+			return nil
+		}
+		if path != dir && in.w.ignored(path, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			if report {
+				in.w.send(path, Create)
+			}
+			return nil
+		}
+		if err := in.add(path); err != nil {
+			return err
+		}
+		if only {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+func (in *inotify) add(dir string) error {
+	wd, err := syscall.InotifyAddWatch(in.fd, dir, inotifyMask)
+	if errors.Is(err, syscall.ENOENT) {
+		return nil
+	}
+	if err != nil {
+		// ENOSPC means fs.inotify.max_user_watches is reached, New falls
+		// back to polling
+		return &os.PathError{Op: "inotify_add_watch", Path: dir, Err: err}
+	}
+	in.mu.Lock()
+	in.dirs[int32(wd)] = dir
+	in.mu.Unlock()
+	return nil
+}
+
+// removeTree removes the watches of dir and the directories below it
+func (in *inotify) removeTree(dir string) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	prefix := dir + string(filepath.Separator)
+	for wd, path := range in.dirs {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			syscall.InotifyRmWatch(in.fd, uint32(wd))
+			delete(in.dirs, wd)
+		}
+	}
+}
+
+// run reads the events until the descriptor is closed
+func (in *inotify) run() {
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := in.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				in.w.fail(err)
+			}
+			return
+		}
+		in.handle(buf[:n])
+	}
+}
+
+// handle parses the inotify_event structs in buf: wd, mask, cookie and
+// length of the NUL padded name that follows
+func (in *inotify) handle(buf []byte) {
+	for len(buf) >= syscall.SizeofInotifyEvent {
+		wd := int32(binary.NativeEndian.Uint32(buf[0:]))
+		mask := binary.NativeEndian.Uint32(buf[4:])
+		size := int(binary.NativeEndian.Uint32(buf[12:]))
+		end := syscall.SizeofInotifyEvent + size
+		if end > len(buf) {
+			return
+		}
+		name := string(buf[syscall.SizeofInotifyEvent:end])
+		for len(name) > 0 && name[len(name)-1] == 0 {
+			name = name[:len(name)-1]
+		}
+		buf = buf[end:]
+
+		if mask&syscall.IN_Q_OVERFLOW != 0 {
+			in.w.fail(ErrOverflow)
+			continue
+		}
+		in.mu.Lock()
+		dir, ok := in.dirs[wd]
+		if mask&syscall.IN_IGNORED != 0 {
+			delete(in.dirs, wd)
+		}
+		in.mu.Unlock()
+		if !ok || name == "" {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		isDir := mask&syscall.IN_ISDIR != 0
+		if in.w.ignored(path, isDir) {
+			continue
+		}
+		switch {
+		case isDir && mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+			if err := in.addTree(path, false, true); err != nil {
+				in.w.fail(err)
+			}
+		case isDir && mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0:
+			// The files of a deleted directory are reported by its own
+			// watch, those of a moved one only through the directory. The
+			// watches of a moved directory would keep reporting the old
+			// paths.
+			if mask&syscall.IN_MOVED_FROM != 0 {
+				in.removeTree(path)
+			}
+			in.w.send(path, Remove)
+		case mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+			in.w.send(path, Create)
+		case mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0:
+			in.w.send(path, Remove)
+		case mask&(syscall.IN_MODIFY|syscall.IN_ATTRIB) != 0:
+			in.w.send(path, Write)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package watcher
+
+import "errors"
+
+// inotify is only available on Linux, other systems poll
+type inotify struct{}
+
+func newInotify(w *Watcher) (*inotify, error) {
+	return nil, errors.New("watcher: inotify is not supported on this system")
+}
+
+func (in *inotify) close() {}
@@ -0,0 +1,86 @@
+package watcher
+
+import (
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// poller scans the roots every Interval and compares the sizes and
+// modification times of the files
+type poller struct {
+	w    *Watcher
+	done chan struct{}
+}
+
+// file is the state of a file compared between scans
+type file struct {
+	size    int64
+	modTime time.Time
+}
+
+func newPoller(w *Watcher) *poller {
+	p := &poller{w: w, done: make(chan struct{})}
+	go p.run(p.scan())
+	return p
+}
+
+func (p *poller) close() {
+	close(p.done)
+}
+
+func (p *poller) run(files map[string]file) {
+	ticker := time.NewTicker(p.w.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		next := p.scan()
+		for path, f := range next {
+			if old, ok := files[path]; !ok {
+				p.w.send(path, Create)
+			} else if old != f {
+				p.w.send(path, Write)
+			}
+		}
+		for path := range files {
+			if _, ok := next[path]; !ok {
+				p.w.send(path, Remove)
+			}
+		}
+		files = next
+	}
+}
+
+// scan returns the files below the roots. Files vanishing during the walk
+// are skipped.
+func (p *poller) scan() map[string]file {
+	files := map[string]file{}
+	for _, r := range p.w.roots {
+		filepath.WalkDir(r.path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if path != r.path && p.w.ignored(path, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			files[path] = file{size: info.Size(), modTime: info.ModTime()}
+			return nil
+		})
+	}
+	return files
+}
@@ -0,0 +1,267 @@
+// Package watcher reports files changing below a set of directories, for
+// the rebuilds of velo dev. It uses inotify on Linux and scans the
+// directories elsewhere, or when inotify is not available. Changes are
+// coalesced and sent in batches once the files stay unchanged for a debounce
+// window.
+package watcher
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Op is a bit set of the changes made to a file
+type Op uint8
+
+// Changes reported for a file
+const (
+	Create Op = 1 << iota
+	Write
+	Remove
+)
+
+// String returns the changes, e.g. create|write
+func (op Op) String() string {
+	var names []string
+	for _, bit := range []struct {
+		op   Op
+		name string
+	}{{Create, "create"}, {Write, "write"}, {Remove, "remove"}} {
+		if op&bit.op != 0 {
+			names = append(names, bit.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// Event is a change of a file
+type Event struct {
+	Path string
+	Op   Op
+}
+
+func (e Event) String() string {
+	return fmt.Sprintf("%s %s", e.Op, e.Path)
+}
+
+// Options configures a Watcher
+type Options struct {
+	// Ignore holds gitignore-style patterns, matched against paths relative
+	// to each watched directory, e.g. node_modules/, *.log or /build.
+	// ReadIgnoreFile reads them from a .gitignore.
+	Ignore []string
+	// Debounce is how long the files must stay unchanged before the
+	// coalesced changes are sent, so a save touching several files or an
+	// editor writing a file in steps is reported once
+	Debounce time.Duration
+	// MaxDelay bounds how long changes are held back while files keep
+	// changing
+	MaxDelay time.Duration
+	// Poll scans the directories every Interval instead of using inotify
+	Poll     bool
+	Interval time.Duration
+}
+
+// Defaults of the Options
+const (
+	DefaultDebounce = 300 * time.Millisecond
+	DefaultMaxDelay = 2 * time.Second
+	DefaultInterval = 500 * time.Millisecond
+)
+
+// ErrOverflow is sent on Errors when the kernel dropped events, changes may
+// have been missed
+var ErrOverflow = errors.New("watcher: event queue overflowed, changes may have been missed")
+
+// Watcher watches files and directories, directories recursively
+type Watcher struct {
+	// Events receives the changes of a debounce window, sorted by path. It
+	// is closed by Close.
+	Events chan []Event
+	// Errors receives errors of the watcher, which keeps running. Errors are
+	// dropped when nobody receives them.
+	Errors chan error
+
+	roots   []root
+	opts    Options
+	raw     chan Event
+	done    chan struct{}
+	backend backend
+	polling bool
+}
+
+// root is a watched path. Files are watched through their directory.
+type root struct {
+	path   string
+	file   bool
+	ignore rules
+}
+
+// backend reports changes of the roots on raw until closed
+type backend interface {
+	close()
+}
+
+// New starts watching paths, files or directories. Ignore patterns apply
+// below directories.
+func New(paths []string, opts Options) (*Watcher, error) {
+	if opts.Debounce == 0 {
+		opts.Debounce = DefaultDebounce
+	}
+	if opts.MaxDelay == 0 {
+		opts.MaxDelay = DefaultMaxDelay
+	}
+	if opts.Interval == 0 {
+		opts.Interval = DefaultInterval
+	}
+	ignore, err := parseRules(opts.Ignore)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		Events: make(chan []Event),
+		Errors: make(chan error, 1),
+		opts:   opts,
+		raw:    make(chan Event, 256),
+		done:   make(chan struct{}),
+	}
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, err
+		}
+		w.roots = append(w.roots, root{path: abs, file: !info.IsDir(), ignore: ignore})
+	}
+
+	if !opts.Poll {
+		w.backend, err = newInotify(w)
+	}
+	if opts.Poll || err != nil {
+		w.polling = true
+		w.backend = newPoller(w)
+	}
+	go w.run()
+	return w, nil
+}
+
+// Polling reports whether the directories are scanned, because inotify was
+// not requested or not available
+func (w *Watcher) Polling() bool {
+	return w.polling
+}
+
+// Close stops the watcher and closes Events
+func (w *Watcher) Close() {
+	select {
+	case <-w.done:
+		return
+	default:
+	}
+	close(w.done)
+	w.backend.close()
+}
+
+// ignored reports whether path, below one of the roots, is ignored. Paths
+// outside the roots, such as siblings of a watched file, are ignored.
+func (w *Watcher) ignored(path string, isDir bool) bool {
+	for _, r := range w.roots {
+		if r.file {
+			if path == r.path {
+				return false
+			}
+			continue
+		}
+		rel, err := filepath.Rel(r.path, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if rel == "." {
+			return false
+		}
+		return r.ignore.ignored(filepath.ToSlash(rel), isDir)
+	}
+	return true
+}
+
+// send passes an event of a backend to the coalescing loop
+func (w *Watcher) send(path string, op Op) {
+	select {
+	case w.raw <- Event{Path: path, Op: op}:
+	case <-w.done:
+	}
+}
+
+// fail reports an error without blocking the backend
+func (w *Watcher) fail(err error) {
+	select {
+	case w.Errors <- err:
+	default:
+	}
+}
+
+// run coalesces the events of the backend and sends them once no more
+// arrive for the debounce window, or MaxDelay after the first one
+func (w *Watcher) run() {
+	defer close(w.Events)
+
+	pending := map[string]Op{}
+	var quiet, deadline <-chan time.Time
+	for {
+		select {
+		case <-w.done:
+			return
+		case e := <-w.raw:
+			if len(pending) == 0 {
+				deadline = time.After(w.opts.MaxDelay)
+			}
+			coalesce(pending, e)
+			quiet = time.After(w.opts.Debounce)
+			continue
+		case <-quiet:
+		case <-deadline:
+		}
+
+		quiet, deadline = nil, nil
+		if len(pending) == 0 {
+			continue
+		}
+		events := make([]Event, 0, len(pending))
+		for path, op := range pending {
+			events = append(events, Event{Path: path, Op: op})
+		}
+		sort.Slice(events, func(i, j int) bool { return events[i].Path < events[j].Path })
+		pending = map[string]Op{}
+
+		select {
+		case w.Events <- events:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// coalesce merges e into the pending changes. A file created and removed
+// within a window is dropped, one removed and created again was rewritten.
+func coalesce(pending map[string]Op, e Event) {
+	op, ok := pending[e.Path]
+	switch {
+	case e.Op == Remove && op&Create != 0:
+		delete(pending, e.Path)
+	case e.Op == Remove:
+		pending[e.Path] = Remove
+	case e.Op == Create && ok && op&Remove != 0:
+		pending[e.Path] = Write
+	default:
+		pending[e.Path] = op | e.Op
+	}
+}
@@ -0,0 +1,233 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOpString(t *testing.T) {
+	for op, want := range map[Op]string{
+		Create:         "create",
+		Create | Write: "create|write",
+		Remove:         "remove",
+		0:              "",
+	} {
+		if got := op.String(); got != want {
+			t.Errorf("%d: %q, want %q", op, got, want)
+		}
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		ops  []Op
+		want Op
+	}{
+		{"written twice", []Op{Write, Write}, Write},
+		{"created and written", []Op{Create, Write}, Create | Write},
+		{"created and removed", []Op{Create, Write, Remove}, 0},
+		{"written and removed", []Op{Write, Remove}, Remove},
+		{"replaced", []Op{Remove, Create}, Write},
+	} {
+		pending := map[string]Op{}
+		for _, op := range tc.ops {
+			coalesce(pending, Event{Path: "a", Op: op})
+		}
+		if got := pending["a"]; got != tc.want {
+			t.Errorf("%s: %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestIgnoreRules(t *testing.T) {
+	rs, err := parseRules([]string{
+		"# comment",
+		"",
+		"node_modules/",
+		"*.log",
+		"!keep.log",
+		"/build",
+		"docs/**/*.tmp",
+		"cache?.bin",
+		"[ab].txt",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"node_modules", true, true},
+		{"web/node_modules/react/index.js", false, true},
+		{"node_modules", false, false},
+		{"debug.log", false, true},
+		{"logs/server.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build/app.js", false, true},
+		{"web/build/app.js", false, false},
+		{"docs/a.tmp", false, true},
+		{"docs/a/b/c.tmp", false, true},
+		{"src/a.tmp", false, false},
+		{"cache1.bin", false, true},
+		{"cache10.bin", false, false},
+		{"a.txt", false, true},
+		{"c.txt", false, false},
+		{"main.go", false, false},
+	} {
+		if got := rs.ignored(tc.path, tc.isDir); got != tc.want {
+			t.Errorf("%s (dir %t): ignored %t, want %t", tc.path, tc.isDir, got, tc.want)
+		}
+	}
+}
+
+func TestReadIgnoreFile(t *testing.T) {
+	dir := t.TempDir()
+	patterns, err := ReadIgnoreFile(filepath.Join(dir, ".gitignore"))
+	if err != nil || patterns != nil {
+		t.Errorf("missing file: %v, %v", patterns, err)
+	}
+	write(t, filepath.Join(dir, ".gitignore"), "dist/\n*.log\n")
+	patterns, err = ReadIgnoreFile(filepath.Join(dir, ".gitignore"))
+	if err != nil || !reflect.DeepEqual(patterns, []string{"dist/", "*.log"}) {
+		t.Errorf("patterns %v, %v", patterns, err)
+	}
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// next returns the next batch of events, failing after a timeout
+func next(t *testing.T, w *Watcher) []Event {
+	t.Helper()
+	select {
+	case events := <-w.Events:
+		return events
+	case err := <-w.Errors:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no events")
+	}
+	return nil
+}
+
+// quiet fails when events arrive within a while
+func quiet(t *testing.T, w *Watcher) {
+	t.Helper()
+	select {
+	case events := <-w.Events:
+		t.Errorf("unexpected events %v", events)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestWatch(t *testing.T) {
+	for _, poll := range []bool{false, true} {
+		name := "inotify"
+		if poll {
+			name = "poll"
+		}
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			write(t, filepath.Join(dir, "main.go"), "package main")
+			w, err := New([]string{dir}, Options{
+				Ignore:   []string{"*.log", "node_modules/"},
+				Debounce: 100 * time.Millisecond,
+				Poll:     poll,
+				Interval: 20 * time.Millisecond,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+			if !poll && w.Polling() {
+				t.Skip("inotify is not available")
+			}
+			// Let the poller take its first scan
+			time.Sleep(50 * time.Millisecond)
+
+			// Several changes within the debounce window arrive as one batch
+			write(t, filepath.Join(dir, "main.go"), "package main\n\nfunc main() {}")
+			write(t, filepath.Join(dir, "app.go"), "package main")
+			write(t, filepath.Join(dir, "debug.log"), "ignored")
+			want := []Event{
+				{Path: filepath.Join(dir, "app.go"), Op: Create},
+				{Path: filepath.Join(dir, "main.go"), Op: Write},
+			}
+			events := next(t, w)
+			// inotify reports the write of a new file too
+			for i := range events {
+				if events[i].Op&Create != 0 {
+					events[i].Op = Create
+				}
+			}
+			if !reflect.DeepEqual(events, want) {
+				t.Errorf("events %v, want %v", events, want)
+			}
+
+			// New directories are watched, ignored ones are not. Directories
+			// themselves are not reported.
+			sub := filepath.Join(dir, "src")
+			if err := os.Mkdir(sub, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Mkdir(filepath.Join(dir, "node_modules"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			write(t, filepath.Join(sub, "util.go"), "package main")
+			write(t, filepath.Join(dir, "node_modules", "index.js"), "ignored")
+			found := false
+			for _, e := range next(t, w) {
+				if e.Path == filepath.Join(dir, "node_modules", "index.js") {
+					t.Errorf("ignored file reported: %v", e)
+				}
+				found = found || e.Path == filepath.Join(sub, "util.go")
+			}
+			if !found {
+				t.Error("file in a new directory not reported")
+			}
+
+			if err := os.Remove(filepath.Join(dir, "app.go")); err != nil {
+				t.Fatal(err)
+			}
+			if events := next(t, w); !reflect.DeepEqual(events, []Event{{Path: filepath.Join(dir, "app.go"), Op: Remove}}) {
+				t.Errorf("events %v after removing app.go", events)
+			}
+			quiet(t, w)
+
+			w.Close()
+			if _, ok := <-w.Events; ok {
+				t.Error("Events not closed")
+			}
+		})
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "velo.json")
+	write(t, config, "{}")
+	w, err := New([]string{config}, Options{Debounce: 50 * time.Millisecond, Poll: true, Interval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	// Siblings of a watched file are not reported
+	write(t, filepath.Join(dir, "other.json"), "{}")
+	write(t, config, `{"name": "acme"}`)
+	if events := next(t, w); !reflect.DeepEqual(events, []Event{{Path: config, Op: Write}}) {
+		t.Errorf("events %v", events)
+	}
+}
@@ -0,0 +1,158 @@
+package xcode
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// object is an entry of the objects dictionary of a project.pbxproj
+type object struct {
+	id      string
+	comment string
+	isa     string
+	fields  []field
+	// inline objects are written on a single line, as Xcode does for build
+	// files and file references
+	inline bool
+}
+
+// field is a key-value pair of an object or dictionary. Values are strings,
+// refs, []ref, []string or dict.
+type field struct {
+	key   string
+	value any
+}
+
+// dict is a nested dictionary written in field order
+type dict []field
+
+// ref points at another object, with the comment Xcode writes next to it
+type ref struct {
+	id      string
+	comment string
+}
+
+// objectID derives a stable 24 character identifier from a key, so that
+// regenerating the project produces the same file
+func objectID(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return strings.ToUpper(fmt.Sprintf("%x", sum[:12]))
+}
+
+// unquoted matches the strings that can be written without quotes
+var unquoted = regexp.MustCompile(`^[A-Za-z0-9_$/.]+$`)
+
+// quote renders a string in the OpenStep plist syntax
+func quote(s string) string {
+	if unquoted.MatchString(s) {
+		return s
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+	return `"` + replacer.Replace(s) + `"`
+}
+
+// writer renders objects in the layout Xcode uses for project.pbxproj
+type writer struct {
+	b strings.Builder
+}
+
+func (w *writer) ref(r ref) string {
+	if r.comment == "" {
+		return r.id
+	}
+	return r.id + " /* " + r.comment + " */"
+}
+
+// value renders a field value. Multi-line values are indented by depth tabs.
+func (w *writer) value(v any, depth int, inline bool) string {
+	indent := strings.Repeat("\t", depth)
+	switch v := v.(type) {
+	case string:
+		return quote(v)
+	case ref:
+		return w.ref(v)
+	case []ref:
+		items := make([]string, len(v))
+		for i, r := range v {
+			items[i] = w.ref(r)
+		}
+		return w.list(items, depth, inline)
+	case []string:
+		items := make([]string, len(v))
+		for i, s := range v {
+			items[i] = quote(s)
+		}
+		return w.list(items, depth, inline)
+	case dict:
+		if inline {
+			var b strings.Builder
+			b.WriteString("{")
+			for _, f := range v {
+				fmt.Fprintf(&b, "%s = %s; ", quote(f.key), w.value(f.value, depth+1, true))
+			}
+			b.WriteString("}")
+			return b.String()
+		}
+		var b strings.Builder
+		b.WriteString("{\n")
+		for _, f := range v {
+			fmt.Fprintf(&b, "%s\t%s = %s;\n", indent, quote(f.key), w.value(f.value, depth+1, false))
+		}
+		b.WriteString(indent + "}")
+		return b.String()
+	}
+	panic(fmt.Sprintf("xcode: unsupported value type %T", v))
+}
+
+// list renders a parenthesized list with a trailing comma after each item
+func (w *writer) list(items []string, depth int, inline bool) string {
+	if inline {
+		return "(" + strings.Join(items, ", ") + ", )"
+	}
+	indent := strings.Repeat("\t", depth)
+	var b strings.Builder
+	b.WriteString("(\n")
+	for _, item := range items {
+		fmt.Fprintf(&b, "%s\t%s,\n", indent, item)
+	}
+	b.WriteString(indent + ")")
+	return b.String()
+}
+
+// encode renders the project file with objects grouped in sections by isa and
+// sorted by identifier, like Xcode
+func encode(objects []object, rootObject ref) []byte {
+	sections := map[string][]object{}
+	for _, o := range objects {
+		sections[o.isa] = append(sections[o.isa], o)
+	}
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := &writer{}
+	w.b.WriteString("// !$*UTF8*$!\n{\n")
+	w.b.WriteString("\tarchiveVersion = 1;\n\tclasses = {\n\t};\n\tobjectVersion = 56;\n\tobjects = {\n")
+	for _, name := range names {
+		section := sections[name]
+		sort.Slice(section, func(i, j int) bool { return section[i].id < section[j].id })
+
+		fmt.Fprintf(&w.b, "\n/* Begin %s section */\n", name)
+		for _, o := range section {
+			// Keys follow isa in alphabetical order
+			fields := append(dict{}, o.fields...)
+			sort.SliceStable(fields, func(i, j int) bool { return fields[i].key < fields[j].key })
+			fields = append(dict{{"isa", o.isa}}, fields...)
+			fmt.Fprintf(&w.b, "\t\t%s = %s;\n", w.ref(ref{o.id, o.comment}), w.value(fields, 2, o.inline))
+		}
+		fmt.Fprintf(&w.b, "/* End %s section */\n", name)
+	}
+	w.b.WriteString("\t};\n")
+	fmt.Fprintf(&w.b, "\trootObject = %s;\n}\n", w.ref(rootObject))
+	return []byte(w.b.String())
+}
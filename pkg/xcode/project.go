@@ -0,0 +1,335 @@
+// Package xcode generates the Xcode project of the iOS shell.
+//
+// The project.pbxproj and the shared scheme are rendered from a Project
+// description. Object identifiers are derived from the project contents, so
+// the output is deterministic and can be regenerated without producing a diff.
+package xcode
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Project describes the single application target of the iOS shell
+type Project struct {
+	// Name is the target, product and scheme name
+	Name             string
+	BundleID         string
+	DeploymentTarget string
+	// InfoPlist is the path of the Info.plist relative to the project directory
+	InfoPlist string
+	// DebugInfoPlist replaces InfoPlist in the Debug configuration when set
+	DebugInfoPlist string
+	// Entitlements is the path of the .entitlements file, if the app has any
+	Entitlements string
+	// BaseConfiguration is the path of an .xcconfig file the target
+	// configurations are based on. Settings of the target override it.
+	BaseConfiguration string
+	// Sources are the Swift files compiled into the app
+	Sources []string
+	// Resources are files copied into the app bundle. Asset catalogs and
+	// storyboards are compiled by Xcode.
+	Resources []string
+	// Folders are copied into the bundle as folder references, keeping their
+	// directory structure, e.g. the web assets
+	Folders []string
+	// AppIcon is the name of the app icon set in the asset catalog of the
+	// resources, if the catalog has one
+	AppIcon string
+	// BuildSettings are extra target build settings applied to every
+	// configuration, overriding the defaults
+	BuildSettings map[string]string
+}
+
+// Configurations built for the target
+var Configurations = []string{"Debug", "Release"}
+
+// FileName returns the name of the .xcodeproj bundle
+func (p *Project) FileName() string {
+	return p.Name + ".xcodeproj"
+}
+
+// fileType returns the lastKnownFileType of a file reference
+func fileType(name string) string {
+	switch path.Ext(name) {
+	case ".swift":
+		return "sourcecode.swift"
+	case ".plist":
+		return "text.plist.xml"
+	case ".entitlements":
+		return "text.plist.entitlements"
+	case ".xcconfig":
+		return "text.xcconfig"
+	case ".storyboard":
+		return "file.storyboard"
+	case ".xcassets":
+		return "folder.assetcatalog"
+	case ".json":
+		return "text.json"
+	case ".png":
+		return "image.png"
+	}
+	return "file"
+}
+
+// Generate renders the project.pbxproj
+func (p *Project) Generate() []byte {
+	var (
+		objects  []object
+		children []ref
+	)
+	id := func(kind, name string) string {
+		return objectID(p.Name + "/" + kind + "/" + name)
+	}
+
+	fileRef := func(name string, fields ...field) ref {
+		r := ref{id("file", name), path.Base(name)}
+		if path.Base(name) != name {
+			fields = append(fields, field{"name", path.Base(name)})
+		}
+		fields = append(fields, field{"path", name}, field{"sourceTree", "<group>"})
+		objects = append(objects, object{id: r.id, comment: r.comment, isa: "PBXFileReference", fields: fields, inline: true})
+		children = append(children, r)
+		return r
+	}
+	buildFile := func(file ref, phase string) ref {
+		r := ref{id("build/"+phase, file.comment+"/"+file.id), file.comment + " in " + phase}
+		objects = append(objects, object{id: r.id, comment: r.comment, isa: "PBXBuildFile",
+			fields: []field{{"fileRef", file}}, inline: true})
+		return r
+	}
+
+	sources := sortedCopy(p.Sources)
+	resources := sortedCopy(p.Resources)
+	folders := sortedCopy(p.Folders)
+
+	var sourceFiles, resourceFiles []ref
+	for _, name := range sources {
+		sourceFiles = append(sourceFiles, buildFile(fileRef(name, field{"lastKnownFileType", fileType(name)}), "Sources"))
+	}
+	if p.InfoPlist != "" {
+		fileRef(p.InfoPlist, field{"lastKnownFileType", fileType(p.InfoPlist)})
+	}
+	if p.DebugInfoPlist != "" {
+		fileRef(p.DebugInfoPlist, field{"lastKnownFileType", fileType(p.DebugInfoPlist)})
+	}
+	if p.Entitlements != "" {
+		fileRef(p.Entitlements, field{"lastKnownFileType", fileType(p.Entitlements)})
+	}
+	var baseConfiguration *ref
+	if p.BaseConfiguration != "" {
+		r := fileRef(p.BaseConfiguration, field{"lastKnownFileType", fileType(p.BaseConfiguration)})
+		baseConfiguration = &r
+	}
+	for _, name := range resources {
+		resourceFiles = append(resourceFiles, buildFile(fileRef(name, field{"lastKnownFileType", fileType(name)}), "Resources"))
+	}
+	for _, name := range folders {
+		resourceFiles = append(resourceFiles, buildFile(fileRef(name, field{"lastKnownFileType", "folder"}), "Resources"))
+	}
+
+	// Product and groups
+	appName := p.Name + ".app"
+	product := ref{id("product", appName), appName}
+	objects = append(objects, object{id: product.id, comment: product.comment, isa: "PBXFileReference", inline: true, fields: []field{
+		{"explicitFileType", "wrapper.application"},
+		{"includeInIndex", "0"},
+		{"path", appName},
+		{"sourceTree", "BUILT_PRODUCTS_DIR"},
+	}})
+
+	appGroup := ref{id("group", p.Name), p.Name}
+	productsGroup := ref{id("group", "Products"), "Products"}
+	mainGroup := ref{id("group", ""), ""}
+	objects = append(objects,
+		object{id: appGroup.id, comment: appGroup.comment, isa: "PBXGroup", fields: []field{
+			{"children", children},
+			{"name", p.Name},
+			{"sourceTree", "<group>"},
+		}},
+		object{id: productsGroup.id, comment: productsGroup.comment, isa: "PBXGroup", fields: []field{
+			{"children", []ref{product}},
+			{"name", "Products"},
+			{"sourceTree", "<group>"},
+		}},
+		object{id: mainGroup.id, isa: "PBXGroup", fields: []field{
+			{"children", []ref{appGroup, productsGroup}},
+			{"sourceTree", "<group>"},
+		}},
+	)
+
+	// Build phases
+	phase := func(isa, name string, files []ref) ref {
+		r := ref{id("phase", name), name}
+		objects = append(objects, object{id: r.id, comment: r.comment, isa: isa, fields: []field{
+			{"buildActionMask", "2147483647"},
+			{"files", append([]ref{}, files...)},
+			{"runOnlyForDeploymentPostprocessing", "0"},
+		}})
+		return r
+	}
+	phases := []ref{
+		phase("PBXSourcesBuildPhase", "Sources", sourceFiles),
+		phase("PBXFrameworksBuildPhase", "Frameworks", nil),
+		phase("PBXResourcesBuildPhase", "Resources", resourceFiles),
+	}
+
+	// Build configurations
+	configList := func(owner, kind string, base *ref, settings func(config string) dict) ref {
+		var configs []ref
+		for _, config := range Configurations {
+			r := ref{id("config/"+kind, config), config}
+			var fields []field
+			if base != nil {
+				fields = append(fields, field{"baseConfigurationReference", *base})
+			}
+			fields = append(fields, field{"buildSettings", settings(config)}, field{"name", config})
+			objects = append(objects, object{id: r.id, comment: r.comment, isa: "XCBuildConfiguration", fields: fields})
+			configs = append(configs, r)
+		}
+		r := ref{id("configlist", kind), "Build configuration list for " + owner}
+		objects = append(objects, object{id: r.id, comment: r.comment, isa: "XCConfigurationList", fields: []field{
+			{"buildConfigurations", configs},
+			{"defaultConfigurationIsVisible", "0"},
+			{"defaultConfigurationName", "Release"},
+		}})
+		return r
+	}
+	projectConfigs := configList(`PBXProject "`+p.Name+`"`, "project", nil, p.projectSettings)
+	targetConfigs := configList(`PBXNativeTarget "`+p.Name+`"`, "target", baseConfiguration, p.targetSettings)
+
+	target := ref{id("target", p.Name), p.Name}
+	objects = append(objects, object{id: target.id, comment: target.comment, isa: "PBXNativeTarget", fields: []field{
+		{"buildConfigurationList", targetConfigs},
+		{"buildPhases", phases},
+		{"buildRules", []ref{}},
+		{"dependencies", []ref{}},
+		{"name", p.Name},
+		{"productName", p.Name},
+		{"productReference", product},
+		{"productType", "com.apple.product-type.application"},
+	}})
+
+	root := ref{id("project", p.Name), "Project object"}
+	objects = append(objects, object{id: root.id, comment: root.comment, isa: "PBXProject", fields: []field{
+		{"attributes", dict{
+			{"BuildIndependentTargetsInParallel", "1"},
+			{"LastSwiftUpdateCheck", "1500"},
+			{"LastUpgradeCheck", "1500"},
+			{"TargetAttributes", dict{
+				{target.id, dict{{"CreatedOnToolsVersion", "15.0"}}},
+			}},
+		}},
+		{"buildConfigurationList", projectConfigs},
+		{"compatibilityVersion", "Xcode 14.0"},
+		{"developmentRegion", "en"},
+		{"hasScannedForEncodings", "0"},
+		{"knownRegions", []string{"en", "Base"}},
+		{"mainGroup", mainGroup},
+		{"productRefGroup", productsGroup},
+		{"projectDirPath", ""},
+		{"projectRoot", ""},
+		{"targets", []ref{target}},
+	}})
+
+	return encode(objects, root)
+}
+
+// projectSettings returns the project level build settings
+func (p *Project) projectSettings(config string) dict {
+	settings := map[string]any{
+		"ALWAYS_SEARCH_USER_PATHS":             "NO",
+		"CLANG_ENABLE_MODULES":                 "YES",
+		"CLANG_ENABLE_OBJC_ARC":                "YES",
+		"COPY_PHASE_STRIP":                     "NO",
+		"ENABLE_STRICT_OBJC_MSGSEND":           "YES",
+		"ENABLE_USER_SCRIPT_SANDBOXING":        "YES",
+		"GCC_C_LANGUAGE_STANDARD":              "gnu17",
+		"GCC_NO_COMMON_BLOCKS":                 "YES",
+		"IPHONEOS_DEPLOYMENT_TARGET":           p.DeploymentTarget,
+		"LOCALIZATION_PREFERS_STRING_CATALOGS": "YES",
+		"SDKROOT":                              "iphoneos",
+	}
+	if config == "Debug" {
+		settings["DEBUG_INFORMATION_FORMAT"] = "dwarf"
+		settings["ENABLE_TESTABILITY"] = "YES"
+		settings["GCC_DYNAMIC_NO_PIC"] = "NO"
+		settings["GCC_OPTIMIZATION_LEVEL"] = "0"
+		settings["GCC_PREPROCESSOR_DEFINITIONS"] = []string{"DEBUG=1", "$(inherited)"}
+		settings["MTL_ENABLE_DEBUG_INFO"] = "INCLUDE_SOURCE"
+		settings["ONLY_ACTIVE_ARCH"] = "YES"
+		settings["SWIFT_ACTIVE_COMPILATION_CONDITIONS"] = "DEBUG"
+		settings["SWIFT_OPTIMIZATION_LEVEL"] = "-Onone"
+	} else {
+		settings["DEBUG_INFORMATION_FORMAT"] = "dwarf-with-dsym"
+		settings["ENABLE_NS_ASSERTIONS"] = "NO"
+		settings["MTL_ENABLE_DEBUG_INFO"] = "NO"
+		settings["SWIFT_COMPILATION_MODE"] = "wholemodule"
+		settings["SWIFT_OPTIMIZATION_LEVEL"] = "-O"
+		settings["VALIDATE_PRODUCT"] = "YES"
+	}
+	return sortedSettings(settings)
+}
+
+// targetSettings returns the build settings of the app target
+func (p *Project) targetSettings(config string) dict {
+	settings := map[string]any{
+		"CODE_SIGN_STYLE":            "Automatic",
+		"CURRENT_PROJECT_VERSION":    "1",
+		"GENERATE_INFOPLIST_FILE":    "NO",
+		"INFOPLIST_FILE":             p.InfoPlist,
+		"IPHONEOS_DEPLOYMENT_TARGET": p.DeploymentTarget,
+		"LD_RUNPATH_SEARCH_PATHS":    []string{"$(inherited)", "@executable_path/Frameworks"},
+		"PRODUCT_BUNDLE_IDENTIFIER":  p.BundleID,
+		"PRODUCT_NAME":               "$(TARGET_NAME)",
+		"SWIFT_EMIT_LOC_STRINGS":     "YES",
+		"SWIFT_VERSION":              "5.0",
+		"TARGETED_DEVICE_FAMILY":     "1,2",
+	}
+	if config == "Debug" && p.DebugInfoPlist != "" {
+		settings["INFOPLIST_FILE"] = p.DebugInfoPlist
+	}
+	if p.Entitlements != "" {
+		settings["CODE_SIGN_ENTITLEMENTS"] = p.Entitlements
+	}
+	// Xcode fails the build when the named set is not in the catalog
+	if p.AppIcon != "" && p.hasResource(".xcassets") {
+		settings["ASSETCATALOG_COMPILER_APPICON_NAME"] = p.AppIcon
+	}
+	for key, value := range p.BuildSettings {
+		settings[key] = value
+	}
+	return sortedSettings(settings)
+}
+
+// hasResource reports whether a resource with the extension is bundled
+func (p *Project) hasResource(ext string) bool {
+	for _, name := range p.Resources {
+		if strings.EqualFold(path.Ext(name), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedSettings orders build settings by name, as Xcode writes them
+func sortedSettings(settings map[string]any) dict {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := make(dict, len(keys))
+	for i, key := range keys {
+		out[i] = field{key, settings[key]}
+	}
+	return out
+}
+
+func sortedCopy(values []string) []string {
+	out := append([]string{}, values...)
+	sort.Strings(out)
+	return out
+}
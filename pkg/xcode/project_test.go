@@ -0,0 +1,107 @@
+package xcode
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata")
+
+// testProjects are the projects compared to testdata/<name>.pbxproj
+var testProjects = map[string]func() *Project{
+	"minimal": func() *Project {
+		return &Project{
+			Name:             "App",
+			BundleID:         "com.acme.app",
+			DeploymentTarget: "15.0",
+			InfoPlist:        "Info.plist",
+			Sources:          []string{"AppDelegate.swift"},
+		}
+	},
+	"velo": func() *Project {
+		return &Project{
+			Name:              "App",
+			BundleID:          "com.acme.app$(VELO_APP_ID_SUFFIX)",
+			DeploymentTarget:  "15.0",
+			InfoPlist:         "Info.plist",
+			DebugInfoPlist:    "Info-Debug.plist",
+			Entitlements:      "App.entitlements",
+			BaseConfiguration: "Velo.xcconfig",
+			Sources:           []string{"AppDelegate.swift", "SceneDelegate.swift", "ViewController.swift"},
+			Resources:         []string{"Environment.plist", "Assets.xcassets", "LaunchScreen.storyboard"},
+			Folders:           []string{"../assets"},
+			AppIcon:           "AppIcon",
+			BuildSettings: map[string]string{
+				"CODE_SIGN_STYLE":  "Manual",
+				"DEVELOPMENT_TEAM": "ABCDE12345",
+			},
+		}
+	},
+}
+
+func TestGenerateGolden(t *testing.T) {
+	for name, project := range testProjects {
+		t.Run(name, func(t *testing.T) {
+			first := project().Generate()
+			// A second project of the same description must not change a
+			// single object ID
+			if second := project().Generate(); !bytes.Equal(first, second) {
+				t.Fatal("generating the project twice gave different output")
+			}
+			checkGolden(t, name+".pbxproj", first)
+		})
+	}
+}
+
+func TestSchemeGolden(t *testing.T) {
+	p := testProjects["velo"]()
+	if !bytes.Equal(p.Scheme(), testProjects["velo"]().Scheme()) {
+		t.Fatal("generating the scheme twice gave different output")
+	}
+	checkGolden(t, "velo.xcscheme", p.Scheme())
+}
+
+func TestObjectIDsDependOnContent(t *testing.T) {
+	p := testProjects["minimal"]()
+	p.Sources = append(p.Sources, "Extra.swift")
+	if bytes.Equal(p.Generate(), testProjects["minimal"]().Generate()) {
+		t.Fatal("adding a source did not change the project")
+	}
+	if objectID("a") == objectID("b") || len(objectID("a")) != 24 {
+		t.Fatalf("object IDs must be 24 hex digits derived from the key, got %q", objectID("a"))
+	}
+}
+
+func TestAppIconNeedsIconSet(t *testing.T) {
+	const setting = "ASSETCATALOG_COMPILER_APPICON_NAME"
+	p := testProjects["velo"]()
+	if !bytes.Contains(p.Generate(), []byte(setting+" = AppIcon;")) {
+		t.Errorf("project with an app icon set lacks %s", setting)
+	}
+	// A catalog holding only the splash screen
+	p.AppIcon = ""
+	if bytes.Contains(p.Generate(), []byte(setting)) {
+		t.Errorf("project without an app icon set has %s", setting)
+	}
+}
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run go test -update to create it", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file, run go test -update after checking the change", name)
+	}
+}
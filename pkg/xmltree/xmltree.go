@@ -0,0 +1,295 @@
+// Package xmltree reads and writes XML documents as a tree of elements.
+//
+// Namespace prefixes, attribute order, text and comments are kept, so a file
+// that is partly generated can be updated without losing what was added by
+// hand. Only the formatting is normalized: documents are written back with
+// four space indentation, the style of Android resource files.
+package xmltree
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const header = `<?xml version="1.0" encoding="utf-8"?>` + "\n"
+
+// Node is an *Element, a Comment or a Text
+type Node any
+
+// Comment is an XML comment
+type Comment string
+
+// Text is character data. Whitespace between elements is not kept.
+type Text string
+
+// Attr is an attribute with its prefixed name, e.g. android:name
+type Attr struct {
+	Name  string
+	Value string
+}
+
+// Element is an XML element with its prefixed name
+type Element struct {
+	Name     string
+	Attrs    []Attr
+	Children []Node
+}
+
+// Document is a parsed XML file
+type Document struct {
+	// Prolog holds the comments before the root element
+	Prolog []Node
+	Root   *Element
+}
+
+// NewElement creates an element with attributes given as name, value pairs
+func NewElement(name string, attrs ...string) *Element {
+	e := &Element{Name: name}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		e.SetAttr(attrs[i], attrs[i+1])
+	}
+	return e
+}
+
+// Parse reads an XML document
+func Parse(data []byte) (*Document, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	doc := &Document{}
+	var stack []*Element
+
+	for {
+		// RawToken keeps prefixes as written instead of resolving namespaces
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("xml: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			e := &Element{Name: qualified(t.Name)}
+			for _, attr := range t.Attr {
+				e.Attrs = append(e.Attrs, Attr{Name: qualified(attr.Name), Value: attr.Value})
+			}
+			if len(stack) == 0 {
+				if doc.Root != nil {
+					return nil, fmt.Errorf("xml: more than one root element")
+				}
+				doc.Root = e
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, e)
+			}
+			stack = append(stack, e)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("xml: unexpected end element %s", qualified(t.Name))
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 && strings.TrimSpace(string(t)) != "" {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, Text(t))
+			}
+		case xml.Comment:
+			if len(stack) == 0 {
+				if doc.Root == nil {
+					doc.Prolog = append(doc.Prolog, Comment(t))
+				}
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, Comment(t))
+			}
+		}
+	}
+	if doc.Root == nil {
+		return nil, fmt.Errorf("xml: no root element")
+	}
+	if len(stack) != 0 {
+		return nil, fmt.Errorf("xml: unclosed element %s", stack[len(stack)-1].Name)
+	}
+	return doc, nil
+}
+
+func qualified(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// Attr returns the value of the named attribute
+func (e *Element) Attr(name string) (string, bool) {
+	for _, attr := range e.Attrs {
+		if attr.Name == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// SetAttr sets an attribute, keeping its position when it already exists
+func (e *Element) SetAttr(name, value string) {
+	for i, attr := range e.Attrs {
+		if attr.Name == name {
+			e.Attrs[i].Value = value
+			return
+		}
+	}
+	e.Attrs = append(e.Attrs, Attr{Name: name, Value: value})
+}
+
+// RemoveAttr removes the named attribute if present
+func (e *Element) RemoveAttr(name string) {
+	for i, attr := range e.Attrs {
+		if attr.Name == name {
+			e.Attrs = append(e.Attrs[:i], e.Attrs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Elements returns the child elements with the given name
+func (e *Element) Elements(name string) []*Element {
+	var out []*Element
+	for _, child := range e.Children {
+		if c, ok := child.(*Element); ok && c.Name == name {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Find returns the first child element with the given name and attribute
+// value, or nil
+func (e *Element) Find(name, attr, value string) *Element {
+	for _, c := range e.Elements(name) {
+		if v, ok := c.Attr(attr); ok && v == value {
+			return c
+		}
+	}
+	return nil
+}
+
+// Text returns the character data directly inside the element
+func (e *Element) Text() string {
+	var b strings.Builder
+	for _, child := range e.Children {
+		if t, ok := child.(Text); ok {
+			b.WriteString(string(t))
+		}
+	}
+	return b.String()
+}
+
+// SetText replaces the character data of the element
+func (e *Element) SetText(text string) {
+	children := e.Children[:0]
+	for _, child := range e.Children {
+		if _, ok := child.(Text); !ok {
+			children = append(children, child)
+		}
+	}
+	e.Children = append(children, Text(text))
+}
+
+// Append adds child elements at the end
+func (e *Element) Append(children ...*Element) {
+	for _, child := range children {
+		e.Children = append(e.Children, child)
+	}
+}
+
+// InsertAfter adds child directly after the last child element named after,
+// or first when there is none
+func (e *Element) InsertAfter(after string, child *Element) {
+	index := 0
+	for i, c := range e.Children {
+		if el, ok := c.(*Element); ok && el.Name == after {
+			index = i + 1
+		}
+	}
+	e.Children = append(e.Children, nil)
+	copy(e.Children[index+1:], e.Children[index:])
+	e.Children[index] = child
+}
+
+// Remove removes the child elements for which drop returns true
+func (e *Element) Remove(drop func(*Element) bool) {
+	children := e.Children[:0]
+	for _, child := range e.Children {
+		if c, ok := child.(*Element); ok && drop(c) {
+			continue
+		}
+		children = append(children, child)
+	}
+	e.Children = children
+}
+
+// Encode writes the document with an XML declaration and four space
+// indentation. Elements with several attributes get one attribute per line.
+func (d *Document) Encode() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	for _, node := range d.Prolog {
+		writeNode(&buf, node, 0)
+	}
+	writeNode(&buf, d.Root, 0)
+	return buf.Bytes()
+}
+
+func writeNode(buf *bytes.Buffer, node Node, depth int) {
+	indent := strings.Repeat("    ", depth)
+	switch n := node.(type) {
+	case Comment:
+		fmt.Fprintf(buf, "%s<!--%s-->\n", indent, string(n))
+	case Text:
+		fmt.Fprintf(buf, "%s%s\n", indent, escapeText(strings.TrimSpace(string(n))))
+	case *Element:
+		buf.WriteString(indent + "<" + n.Name)
+		if len(n.Attrs) == 1 {
+			fmt.Fprintf(buf, " %s=\"%s\"", n.Attrs[0].Name, escapeAttr(n.Attrs[0].Value))
+		} else {
+			for _, attr := range n.Attrs {
+				fmt.Fprintf(buf, "\n%s    %s=\"%s\"", indent, attr.Name, escapeAttr(attr.Value))
+			}
+		}
+
+		switch {
+		case len(n.Children) == 0:
+			buf.WriteString(" />\n")
+		case len(n.Children) == 1 && isText(n.Children[0]):
+			// Resource values stay on one line: <string name="a">b</string>
+			fmt.Fprintf(buf, ">%s</%s>\n", escapeText(string(n.Children[0].(Text))), n.Name)
+		default:
+			buf.WriteString(">\n")
+			for _, child := range n.Children {
+				writeNode(buf, child, depth+1)
+			}
+			fmt.Fprintf(buf, "%s</%s>\n", indent, n.Name)
+		}
+	}
+}
+
+func isText(node Node) bool {
+	_, ok := node.(Text)
+	return ok
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func escapeAttr(s string) string {
+	return attrEscaper.Replace(s)
+}
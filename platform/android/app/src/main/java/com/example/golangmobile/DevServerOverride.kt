@@ -0,0 +1,18 @@
+package com.example.golangmobile
+
+// A dev server handed over by a dev link or the dev settings, stored with the
+// dev server the app was built with and the time it was handed over
+data class DevServerOverride(val url: String, val builtWith: String, val savedAt: Long) {
+    // An override only holds for the build it was stored by. velo dev builds
+    // again with another dev server, e.g. on a new port or with --bundled, and
+    // installs the app again, after which the override points to a dead server.
+    fun appliesTo(buildUrl: String, installedAt: Long): Boolean =
+        builtWith == buildUrl && savedAt >= installedAt
+
+    companion object {
+        // The dev server a debug build loads: the override when it still
+        // applies, else the one set by velo dev at build time
+        fun resolve(override: DevServerOverride?, buildUrl: String, installedAt: Long): String =
+            if (override != null && override.appliesTo(buildUrl, installedAt)) override.url else buildUrl
+    }
+}
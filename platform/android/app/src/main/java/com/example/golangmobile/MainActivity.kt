@@ -3,21 +3,97 @@ package com.example.golangmobile
 import android.annotation.SuppressLint
 import android.os.Build
 import android.os.Bundle
+import android.os.Handler
+import android.os.Looper
 import android.webkit.*
 import androidx.appcompat.app.AppCompatActivity
+import androidx.core.splashscreen.SplashScreen.Companion.installSplashScreen
 import android.content.Context
 import android.content.Intent
 import android.net.Uri
+import android.text.InputType
+import android.widget.EditText
+import androidx.appcompat.app.AlertDialog
 import android.webkit.WebView
 import android.webkit.WebViewClient
 import android.widget.Toast
+import java.io.File
 
 class MainActivity : AppCompatActivity() {
     private lateinit var webView: WebView
 
+    // Set when the web app calls AndroidBridge.ready() or the splash timeout passes
+    @Volatile
+    private var webAppReady = false
+
+    // Holds the dev server handed over by a dev link or the dev settings
+    private val devPrefs by lazy { getSharedPreferences("velo_dev", Context.MODE_PRIVATE) }
+
+    // When the app was last installed
+    private val installedAt: Long
+        get() = packageManager.getPackageInfo(packageName, 0).lastUpdateTime
+
+    // The dev server loaded by debug builds: the one handed over while it
+    // applies to this build, else the one set by velo dev at build time.
+    // Always empty in release builds.
+    private val devServerUrl: String
+        get() {
+            if (!BuildConfig.DEBUG) return ""
+            val override = devServerOverride
+            if (override != null && !override.appliesTo(BuildConfig.DEV_SERVER_URL, installedAt)) {
+                // Left by an earlier build, drop it so the dev settings show
+                // the current dev server too
+                clearDevServer()
+            }
+            return DevServerOverride.resolve(devServerOverride, BuildConfig.DEV_SERVER_URL, installedAt)
+        }
+
+    private val devServerOverride: DevServerOverride?
+        get() {
+            val url = devPrefs.getString(DEV_SERVER_KEY, null) ?: return null
+            return DevServerOverride(
+                url,
+                devPrefs.getString(DEV_SERVER_BUILT_WITH_KEY, null) ?: "",
+                devPrefs.getLong(DEV_SERVER_SAVED_AT_KEY, 0)
+            )
+        }
+
+    private fun storeDevServer(url: String) {
+        devPrefs.edit()
+            .putString(DEV_SERVER_KEY, url)
+            .putString(DEV_SERVER_BUILT_WITH_KEY, BuildConfig.DEV_SERVER_URL)
+            .putLong(DEV_SERVER_SAVED_AT_KEY, System.currentTimeMillis())
+            .apply()
+    }
+
+    private fun clearDevServer() {
+        devPrefs.edit()
+            .remove(DEV_SERVER_KEY)
+            .remove(DEV_SERVER_BUILT_WITH_KEY)
+            .remove(DEV_SERVER_SAVED_AT_KEY)
+            .apply()
+    }
+
+    // The web app pushed by velo dev --bundled, loaded by debug builds instead
+    // of the bundled one until the app is installed again
+    private val pushedIndex: File?
+        get() {
+            if (!BuildConfig.DEBUG) return null
+            val index = File(filesDir, "$PUSHED_ASSETS_DIR/index.html")
+            return if (index.exists() && index.lastModified() > installedAt) index else null
+        }
+
     @SuppressLint("SetJavaScriptEnabled")
     override fun onCreate(savedInstanceState: Bundle?) {
+        // Keep the splash screen up until the web app is ready
+        val splashScreen = installSplashScreen()
         super.onCreate(savedInstanceState)
+        splashScreen.setKeepOnScreenCondition { !webAppReady }
+        Handler(Looper.getMainLooper()).postDelayed(
+            { webAppReady = true },
+            resources.getInteger(R.integer.splash_timeout).toLong()
+        )
+
         setContentView(R.layout.activity_main)
 
         webView = findViewById(R.id.webview)
@@ -35,34 +111,110 @@ class MainActivity : AppCompatActivity() {
         // Set up app-specific WebViewClient
         webView.webViewClient = object : WebViewClient() {
             override fun shouldOverrideUrlLoading(view: WebView?, request: WebResourceRequest?): Boolean {
-                val url = request?.url.toString()
-                // Handle external links if needed
-                if (url.startsWith("http://") || url.startsWith("https://") && !url.contains("localhost")) {
+                val url = request?.url?.toString() ?: return false
+                // Pages of the web app stay in the WebView
+                val devServer = devServerUrl
+                val pushed = Uri.fromFile(File(filesDir, PUSHED_ASSETS_DIR)).toString()
+                if (url.startsWith(BUNDLED_URL_PREFIX) || url.startsWith(pushed) ||
+                    (devServer.isNotEmpty() && url.startsWith(devServer))) {
+                    return false
+                }
+                // Open other links in the browser
+                if (url.startsWith("http://") || url.startsWith("https://")) {
                     val intent = Intent(Intent.ACTION_VIEW, Uri.parse(url))
                     startActivity(intent)
                     return true
                 }
                 return false
             }
+
+            // Debug builds offer the dev settings when the dev server cannot
+            // be reached, e.g. localhost from a device on Wi-Fi
+            override fun onReceivedError(view: WebView?, request: WebResourceRequest?, error: WebResourceError?) {
+                if (request?.isForMainFrame == true && devServerUrl.isNotEmpty()) {
+                    webAppReady = true
+                    showDevSettings()
+                }
+            }
         }
 
         // Add JavaScript interface for communication
-        webView.addJavascriptInterface(WebAppInterface(this), "AndroidBridge")
+        webView.addJavascriptInterface(WebAppInterface(this) { webAppReady = true }, "AndroidBridge")
 
         // Load the web app
+        handleDevLink(intent)
         loadWebApp()
     }
 
+    // The app is already running when a dev link is opened
+    override fun onNewIntent(intent: Intent) {
+        super.onNewIntent(intent)
+        if (handleDevLink(intent)) {
+            loadWebApp()
+        }
+    }
+
+    // Stores the dev server of a dev link, velo-<app id>://dev?url=<dev server>,
+    // which velo dev --lan prints as a QR code. Only debug builds register the
+    // scheme. A link without url opens the dev settings. velo dev --bundled
+    // opens velo-<app id>://reload after pushing new assets.
+    private fun handleDevLink(intent: Intent?): Boolean {
+        val data = intent?.data ?: return false
+        if (!BuildConfig.DEBUG || data.scheme?.startsWith("velo-") != true) {
+            return false
+        }
+        if (data.host == "reload") {
+            return true
+        }
+        if (data.host != "dev") {
+            return false
+        }
+        val url = data.getQueryParameter("url")
+        if (url.isNullOrEmpty()) {
+            showDevSettings()
+            return false
+        }
+        storeDevServer(url)
+        Toast.makeText(this, "Dev server: $url", Toast.LENGTH_SHORT).show()
+        return true
+    }
+
+    // Lets the dev server be entered by hand, or reset to the one the app was
+    // built with
+    private fun showDevSettings() {
+        if (!BuildConfig.DEBUG || isFinishing) return
+        val input = EditText(this).apply {
+            setText(devServerUrl)
+            inputType = InputType.TYPE_CLASS_TEXT or InputType.TYPE_TEXT_VARIATION_URI
+        }
+        AlertDialog.Builder(this)
+            .setTitle("Dev server")
+            .setView(input)
+            .setPositiveButton("Connect") { _, _ ->
+                storeDevServer(input.text.toString().trim())
+                loadWebApp()
+            }
+            .setNeutralButton("Reset") { _, _ ->
+                clearDevServer()
+                loadWebApp()
+            }
+            .setNegativeButton("Cancel", null)
+            .show()
+    }
+
     private fun loadWebApp() {
-        // Check for development mode (could be set via build config)
-        val isDevelopment = true // Change based on build type
-        
-        if (isDevelopment) {
-            // For development, load from development server
-            webView.loadUrl("http://localhost:3000") // Android emulator localhost equivalent
+        // Velo sets the dev server of debug builds started by velo dev, it is
+        // always empty in release builds
+        val devServer = devServerUrl
+        if (devServer.isNotEmpty()) {
+            // Reached through adb reverse, which forwards the port to the
+            // host, or over the LAN with velo dev --lan
+            webView.loadUrl(devServer)
         } else {
-            // For production, load from assets (the bundled web app)
-            webView.loadUrl("file:///android_asset/index.html")
+            // The bundled web app copied from mobile-shell/assets, or the one
+            // pushed since by velo dev --bundled
+            val pushed = pushedIndex
+            webView.loadUrl(if (pushed != null) Uri.fromFile(pushed).toString() else BUNDLED_URL_PREFIX + "index.html")
         }
     }
 
@@ -75,8 +227,16 @@ class MainActivity : AppCompatActivity() {
         }
     }
 
+    companion object {
+        private const val BUNDLED_URL_PREFIX = "file:///android_asset/"
+        private const val DEV_SERVER_KEY = "devServerUrl"
+        private const val DEV_SERVER_BUILT_WITH_KEY = "devServerBuiltWith"
+        private const val DEV_SERVER_SAVED_AT_KEY = "devServerSavedAt"
+        private const val PUSHED_ASSETS_DIR = "velo-assets"
+    }
+
     // JavaScript interface for communication between JS and Android
-    private class WebAppInterface(private val context: Context) {
+    private class WebAppInterface(private val context: Context, private val onReady: () -> Unit) {
         @JavascriptInterface
         fun getPlatformInfo(): String {
             return "Android ${Build.VERSION.RELEASE} (SDK ${Build.VERSION.SDK_INT})"
@@ -86,5 +246,11 @@ class MainActivity : AppCompatActivity() {
         fun showToast(message: String) {
             Toast.makeText(context, message, Toast.LENGTH_SHORT).show()
         }
+
+        // Hides the splash screen once the web app has rendered
+        @JavascriptInterface
+        fun ready() {
+            onReady()
+        }
     }
 } 
\ No newline at end of file
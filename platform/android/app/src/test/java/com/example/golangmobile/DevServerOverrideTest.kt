@@ -0,0 +1,39 @@
+package com.example.golangmobile
+
+import org.junit.Assert.assertEquals
+import org.junit.Test
+
+class DevServerOverrideTest {
+    private val installedAt = 1_000L
+
+    @Test
+    fun overrideWinsForTheBuildThatStoredIt() {
+        val override = DevServerOverride("http://192.168.1.20:3000", "http://localhost:3000", 2_000L)
+        assertEquals("http://192.168.1.20:3000", DevServerOverride.resolve(override, "http://localhost:3000", installedAt))
+    }
+
+    @Test
+    fun portChangeDropsTheOverride() {
+        // velo dev moved to another port because 3000 was taken
+        val override = DevServerOverride("http://192.168.1.20:3000", "http://localhost:3000", 2_000L)
+        assertEquals("http://localhost:3001", DevServerOverride.resolve(override, "http://localhost:3001", installedAt))
+    }
+
+    @Test
+    fun bundledBuildDropsTheOverride() {
+        // velo dev --bundled builds without a dev server
+        val override = DevServerOverride("http://192.168.1.20:3000", "http://localhost:3000", 2_000L)
+        assertEquals("", DevServerOverride.resolve(override, "", installedAt))
+    }
+
+    @Test
+    fun reinstallDropsTheOverride() {
+        val override = DevServerOverride("http://192.168.1.20:3000", "http://localhost:3000", 500L)
+        assertEquals("http://localhost:3000", DevServerOverride.resolve(override, "http://localhost:3000", installedAt))
+    }
+
+    @Test
+    fun noOverride() {
+        assertEquals("http://localhost:3000", DevServerOverride.resolve(null, "http://localhost:3000", installedAt))
+    }
+}
@@ -0,0 +1,26 @@
+import Foundation
+
+// Values of the build environment written by Velo to Environment.plist:
+// VELO_ENV, the <NAME>_URL of every configured URL and FEATURE_<NAME> flags
+enum BuildEnvironment {
+    static let values: [String: Any] = {
+        guard let url = Bundle.main.url(forResource: "Environment", withExtension: "plist"),
+              let data = try? Data(contentsOf: url),
+              let values = try? PropertyListSerialization.propertyList(from: data, format: nil) as? [String: Any] else {
+            return [:]
+        }
+        return values
+    }()
+
+    static var name: String {
+        return values["VELO_ENV"] as? String ?? "prod"
+    }
+
+    static func url(_ name: String) -> String? {
+        return values[name] as? String
+    }
+
+    static func isEnabled(_ feature: String) -> Bool {
+        return values[feature] as? Bool ?? false
+    }
+}
@@ -0,0 +1,32 @@
+import UIKit
+
+class SceneDelegate: UIResponder, UIWindowSceneDelegate {
+    var window: UIWindow?
+
+    // The shell has no main storyboard, the window and its view controller
+    // are created here
+    func scene(_ scene: UIScene, willConnectTo session: UISceneSession, options connectionOptions: UIScene.ConnectionOptions) {
+        guard let windowScene = scene as? UIWindowScene else { return }
+        let window = UIWindow(windowScene: windowScene)
+        window.rootViewController = ViewController()
+        self.window = window
+        window.makeKeyAndVisible()
+
+        if let url = connectionOptions.urlContexts.first?.url {
+            openDevLink(url)
+        }
+    }
+
+    // The app is already running when a link is opened
+    func scene(_ scene: UIScene, openURLContexts URLContexts: Set<UIOpenURLContext>) {
+        if let url = URLContexts.first?.url {
+            openDevLink(url)
+        }
+    }
+
+    // Hands the dev links of velo dev --lan to the view controller
+    private func openDevLink(_ url: URL) {
+        guard url.scheme?.hasPrefix("velo-") == true else { return }
+        (window?.rootViewController as? ViewController)?.open(devLink: url)
+    }
+}
@@ -3,6 +3,8 @@ import WebKit
 
 class ViewController: UIViewController, WKNavigationDelegate, WKScriptMessageHandler {
     private var webView: WKWebView!
+    // The launch screen kept on top of the web view until the web app is ready
+    private var splashView: UIView?
     
     override func viewDidLoad() {
         super.viewDidLoad()
@@ -27,28 +29,202 @@ class ViewController: UIViewController, WKNavigationDelegate, WKScriptMessageHan
         webView.navigationDelegate = self
         webView.autoresizingMask = [.flexibleWidth, .flexibleHeight]
         view.addSubview(webView)
+        showSplash()
         
         // Load web app
         loadWebApp()
     }
     
-    private func loadWebApp() {
-        // Check for development mode
+    private func showSplash() {
+        guard let splash = UIStoryboard(name: "LaunchScreen", bundle: nil).instantiateInitialViewController()?.view else { return }
+        splash.frame = view.bounds
+        splash.autoresizingMask = [.flexibleWidth, .flexibleHeight]
+        view.addSubview(splash)
+        splashView = splash
+        
+        // Show the web app anyway if it never signals that it is ready
+        let timeout = Bundle.main.object(forInfoDictionaryKey: "VeloSplashTimeout") as? Int ?? 5000
+        DispatchQueue.main.asyncAfter(deadline: .now() + .milliseconds(timeout)) { [weak self] in
+            self?.hideSplash()
+        }
+    }
+    
+    private func hideSplash() {
+        guard let splash = splashView else { return }
+        splashView = nil
+        UIView.animate(withDuration: 0.2, animations: { splash.alpha = 0 }) { _ in
+            splash.removeFromSuperview()
+        }
+    }
+    
+    // Hold the dev server handed over by a dev link or the dev settings, the
+    // dev server the app was built with then and when it was handed over
+    private static let devServerKey = "VeloDevServerURL"
+    private static let devServerBuiltWithKey = "VeloDevServerBuiltWith"
+    private static let devServerSavedAtKey = "VeloDevServerSavedAt"
+    
+    // Opens the dev settings once the view is on screen
+    private var showsDevSettingsOnAppear = false
+    
+    // The dev server of debug builds: the one handed over while it applies to
+    // this build, else the one set by velo dev through Velo.xcconfig, which is
+    // always empty in Release builds
+    private var devServerURL: URL? {
+        let buildValue = Bundle.main.object(forInfoDictionaryKey: "VeloDevServerURL") as? String ?? ""
         #if DEBUG
-        // For development, load from dev server
-        if let url = URL(string: "http://localhost:3001") {
-            let request = URLRequest(url: url)
-            webView.load(request)
-        }
-        #else
-        // For production, load from bundled files
-        if let htmlPath = Bundle.main.path(forResource: "index", ofType: "html", inDirectory: "assets") {
+        if let value = UserDefaults.standard.string(forKey: Self.devServerKey) {
+            if devServerOverrideApplies(to: buildValue), let url = URL(string: value) {
+                return url
+            }
+            // Left by an earlier build, drop it so the dev settings show the
+            // current dev server too
+            clearDevServer()
+        }
+        #endif
+        guard !buildValue.isEmpty else {
+            return nil
+        }
+        return URL(string: buildValue)
+    }
+    
+    #if DEBUG
+    // An override only holds for the build it was stored by. velo dev builds
+    // again with another dev server, e.g. on a new port or with --bundled, and
+    // installs the app again, after which the override points to a dead server.
+    private func devServerOverrideApplies(to buildValue: String) -> Bool {
+        let defaults = UserDefaults.standard
+        guard defaults.string(forKey: Self.devServerBuiltWithKey) == buildValue,
+              let savedAt = defaults.object(forKey: Self.devServerSavedAtKey) as? Date else {
+            return false
+        }
+        // Installing the app replaces its bundle
+        let installedAt = (try? FileManager.default.attributesOfItem(atPath: Bundle.main.bundlePath))?[.modificationDate] as? Date
+        return installedAt.map { savedAt >= $0 } ?? true
+    }
+    
+    private func storeDevServer(_ value: String) {
+        let defaults = UserDefaults.standard
+        defaults.set(value, forKey: Self.devServerKey)
+        defaults.set(Bundle.main.object(forInfoDictionaryKey: "VeloDevServerURL") as? String ?? "", forKey: Self.devServerBuiltWithKey)
+        defaults.set(Date(), forKey: Self.devServerSavedAtKey)
+    }
+    
+    private func clearDevServer() {
+        let defaults = UserDefaults.standard
+        defaults.removeObject(forKey: Self.devServerKey)
+        defaults.removeObject(forKey: Self.devServerBuiltWithKey)
+        defaults.removeObject(forKey: Self.devServerSavedAtKey)
+    }
+    #endif
+    
+    // The web app pushed by velo dev --bundled, loaded by debug builds instead
+    // of the bundled one until the app is installed again
+    private var pushedIndexURL: URL? {
+        #if DEBUG
+        let fileManager = FileManager.default
+        guard let library = fileManager.urls(for: .libraryDirectory, in: .userDomainMask).first,
+              let bundled = Bundle.main.path(forResource: "index", ofType: "html", inDirectory: "assets") else {
+            return nil
+        }
+        let index = library.appendingPathComponent("VeloAssets/index.html")
+        let pushedDate = (try? fileManager.attributesOfItem(atPath: index.path))?[.modificationDate] as? Date
+        let bundledDate = (try? fileManager.attributesOfItem(atPath: bundled))?[.modificationDate] as? Date
+        if let pushedDate = pushedDate, let bundledDate = bundledDate, pushedDate > bundledDate {
+            return index
+        }
+        #endif
+        return nil
+    }
+    
+    private func loadWebApp() {
+        if let url = devServerURL {
+            webView.load(URLRequest(url: url))
+        } else if let pushedURL = pushedIndexURL {
+            webView.loadFileURL(pushedURL, allowingReadAccessTo: pushedURL.deletingLastPathComponent())
+        } else if let htmlPath = Bundle.main.path(forResource: "index", ofType: "html", inDirectory: "assets") {
+            // The bundled web app copied from mobile-shell/assets
             let htmlUrl = URL(fileURLWithPath: htmlPath)
             webView.loadFileURL(htmlUrl, allowingReadAccessTo: htmlUrl.deletingLastPathComponent())
         }
+    }
+    
+    override func viewDidAppear(_ animated: Bool) {
+        super.viewDidAppear(animated)
+        if showsDevSettingsOnAppear {
+            showsDevSettingsOnAppear = false
+            showDevSettings()
+        }
+    }
+    
+    // MARK: - Dev settings
+    
+    // Stores the dev server of a dev link, velo-<bundle id>://dev?url=<dev server>,
+    // which velo dev --lan prints as a QR code. A link without url opens the
+    // dev settings. velo dev --bundled opens velo-<bundle id>://reload after
+    // pushing new assets. Release builds ignore dev links.
+    func open(devLink: URL) {
+        #if DEBUG
+        if devLink.host == "reload" {
+            if isViewLoaded {
+                loadWebApp()
+            }
+            return
+        }
+        guard devLink.host == "dev" else { return }
+        let value = URLComponents(url: devLink, resolvingAgainstBaseURL: false)?
+            .queryItems?.first(where: { $0.name == "url" })?.value
+        guard let value = value, !value.isEmpty else {
+            if viewIfLoaded?.window != nil {
+                showDevSettings()
+            } else {
+                showsDevSettingsOnAppear = true
+            }
+            return
+        }
+        storeDevServer(value)
+        // Opening the app with the link loads it in viewDidLoad
+        if isViewLoaded {
+            loadWebApp()
+        }
+        #endif
+    }
+    
+    // Lets the dev server be entered by hand, or reset to the one the app was
+    // built with
+    private func showDevSettings() {
+        #if DEBUG
+        guard presentedViewController == nil else { return }
+        let alert = UIAlertController(title: "Dev server", message: nil, preferredStyle: .alert)
+        alert.addTextField { [weak self] field in
+            field.text = self?.devServerURL?.absoluteString
+            field.keyboardType = .URL
+            field.autocapitalizationType = .none
+            field.autocorrectionType = .no
+        }
+        alert.addAction(UIAlertAction(title: "Connect", style: .default) { [weak self, weak alert] _ in
+            guard let value = alert?.textFields?.first?.text?.trimmingCharacters(in: .whitespaces), !value.isEmpty else { return }
+            self?.storeDevServer(value)
+            self?.loadWebApp()
+        })
+        alert.addAction(UIAlertAction(title: "Reset", style: .destructive) { [weak self] _ in
+            self?.clearDevServer()
+            self?.loadWebApp()
+        })
+        alert.addAction(UIAlertAction(title: "Cancel", style: .cancel))
+        present(alert, animated: true)
         #endif
     }
     
+    #if DEBUG
+    // Shaking the device opens the dev settings
+    override func motionEnded(_ motion: UIEvent.EventSubtype, with event: UIEvent?) {
+        if motion == .motionShake {
+            showDevSettings()
+        }
+        super.motionEnded(motion, with: event)
+    }
+    #endif
+    
     // MARK: - WKScriptMessageHandler
     
     func userContentController(_ userContentController: WKUserContentController, didReceive message: WKScriptMessage) {
@@ -59,16 +235,34 @@ class ViewController: UIViewController, WKNavigationDelegate, WKScriptMessageHan
                 let deviceInfo = "iOS \(UIDevice.current.systemVersion)"
                 // Call JavaScript function to set platform info
                 webView.evaluateJavaScript("setPlatformInfo('\(deviceInfo)')", completionHandler: nil)
+            } else if action == "ready" {
+                hideSplash()
             }
         }
     }
     
     // MARK: - WKNavigationDelegate
     
+    // Debug builds offer the dev settings when the dev server cannot be
+    // reached, e.g. from a device that is not on the same network
+    func webView(_ webView: WKWebView, didFailProvisionalNavigation navigation: WKNavigation!, withError error: Error) {
+        #if DEBUG
+        if devServerURL != nil {
+            hideSplash()
+            showDevSettings()
+        }
+        #endif
+    }
+    
     func webView(_ webView: WKWebView, decidePolicyFor navigationAction: WKNavigationAction, decisionHandler: @escaping (WKNavigationActionPolicy) -> Void) {
-        if navigationAction.navigationType == .linkActivated {
-            // Handle external links
-            if let url = navigationAction.request.url, UIApplication.shared.canOpenURL(url) {
+        if navigationAction.navigationType == .linkActivated, let url = navigationAction.request.url {
+            // Pages of the web app stay in the web view
+            if url.isFileURL || (url.host == devServerURL?.host && url.port == devServerURL?.port) {
+                decisionHandler(.allow)
+                return
+            }
+            // Open other links in Safari
+            if UIApplication.shared.canOpenURL(url) {
                 UIApplication.shared.open(url)
                 decisionHandler(.cancel)
                 return